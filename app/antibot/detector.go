@@ -0,0 +1,241 @@
+package antibot
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+var (
+	ErrFriction  = errors.New("swiping too fast, slow down")
+	ErrThrottled = errors.New("swiping is paused because of unusual activity")
+)
+
+// RetryError holds a user back from swiping until RetryAfter has passed.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+type Detector interface {
+	// Allow returns a RetryError when userID is held back from swiping at now.
+	Allow(userID uuid.UUID, now time.Time) error
+	// Observe feeds a recorded swipe to the detector, persists the signals of the
+	// thresholds it crossed and returns where the user stands after it.
+	Observe(swipe *models.Swipe, now time.Time) (models.BotLevel, error)
+}
+
+type event struct {
+	at   time.Time
+	like bool
+}
+
+type userState struct {
+	// events are the latest swipes, oldest first
+	events         []event
+	strikes        []time.Time
+	throttledUntil time.Time
+	flagged        bool
+}
+
+// velocityDetector keeps the recent swipes of each user in memory, so every instance of
+// the service watches the swipes it serves. The signals it persists are what lasts.
+type velocityDetector struct {
+	signalRepo repository.BotSignalRepository
+	cfg        config.AntiBotConfig
+	mu         sync.Mutex
+	users      map[uuid.UUID]*userState
+	lastPrune  time.Time
+}
+
+func NewDetector(signalRepo repository.BotSignalRepository, cfg config.AntiBotConfig) Detector {
+	return &velocityDetector{signalRepo: signalRepo, cfg: cfg, users: make(map[uuid.UUID]*userState)}
+}
+
+func (d *velocityDetector) Allow(userID uuid.UUID, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.users[userID]
+	if !ok {
+		return nil
+	}
+
+	if now.Before(state.throttledUntil) {
+		return &RetryError{Err: ErrThrottled, RetryAfter: state.throttledUntil.Sub(now)}
+	}
+
+	if d.level(state, now) != models.BotLevelNone && len(state.events) > 0 {
+		next := state.events[len(state.events)-1].at.Add(d.cfg.FrictionInterval)
+		if now.Before(next) {
+			return &RetryError{Err: ErrFriction, RetryAfter: next.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+func (d *velocityDetector) Observe(swipe *models.Swipe, now time.Time) (models.BotLevel, error) {
+	d.mu.Lock()
+
+	d.prune(now)
+	state, ok := d.users[swipe.UserID]
+	if !ok {
+		state = &userState{}
+		d.users[swipe.UserID] = state
+	}
+
+	state.events = append(state.events, event{at: now, like: swipe.Type.IsLike()})
+	if keep := d.eventsKept(); len(state.events) > keep {
+		state.events = append(state.events[:0], state.events[len(state.events)-keep:]...)
+	}
+
+	signals := d.evaluate(swipe.UserID, state.events, now)
+	if len(signals) > 0 {
+		state.strikes = append(state.strikes, now)
+		if reached(len(state.strikes), d.cfg.FlagStrikes) {
+			state.flagged = true
+		}
+	}
+
+	level := d.level(state, now)
+	if len(signals) > 0 && (level == models.BotLevelThrottle || level == models.BotLevelFlag) {
+		state.throttledUntil = now.Add(d.cfg.ThrottleDuration)
+	}
+	for i := range signals {
+		signals[i].Level = level
+	}
+
+	d.mu.Unlock()
+
+	return level, d.signalRepo.CreateSignals(signals)
+}
+
+// evaluate checks the user's recent swipes against each threshold, returning a signal for
+// every threshold crossed.
+func (d *velocityDetector) evaluate(userID uuid.UUID, events []event, now time.Time) []models.BotSignal {
+	var signals []models.BotSignal
+	signal := func(name string, value, threshold float64) {
+		signals = append(signals, models.BotSignal{UserID: userID, Signal: name, Value: value, Threshold: threshold})
+	}
+
+	inWindow := 0
+	for i := len(events) - 1; i >= 0 && now.Sub(events[i].at) < d.cfg.Window; i-- {
+		inWindow++
+	}
+	if d.cfg.MaxSwipes > 0 && inWindow > d.cfg.MaxSwipes {
+		signal(models.BotSignalSwipeRate, float64(inWindow), float64(d.cfg.MaxSwipes))
+	}
+
+	if d.cfg.LikeRatioSample > 0 && len(events) >= d.cfg.LikeRatioSample {
+		likes := 0
+		for _, e := range events[len(events)-d.cfg.LikeRatioSample:] {
+			if e.like {
+				likes++
+			}
+		}
+		ratio := float64(likes) / float64(d.cfg.LikeRatioSample)
+		if ratio > d.cfg.MaxLikeRatio {
+			signal(models.BotSignalLikeRatio, ratio, d.cfg.MaxLikeRatio)
+		}
+	}
+
+	if d.cfg.TimingSample > 1 && len(events) >= d.cfg.TimingSample {
+		mean, stddev := intervalStats(events[len(events)-d.cfg.TimingSample:])
+		if mean < d.cfg.MinInterval.Seconds() {
+			signal(models.BotSignalInterval, mean, d.cfg.MinInterval.Seconds())
+		}
+		if mean > 0 && stddev/mean < d.cfg.MinJitter {
+			signal(models.BotSignalRegularity, stddev/mean, d.cfg.MinJitter)
+		}
+	}
+
+	return signals
+}
+
+// level drops the strikes that left the strike window and returns how hard the user is
+// held back. A flag stays until the user has been quiet long enough to be pruned.
+func (d *velocityDetector) level(state *userState, now time.Time) models.BotLevel {
+	cutoff := now.Add(-d.cfg.StrikeWindow)
+	expired := 0
+	for expired < len(state.strikes) && !state.strikes[expired].After(cutoff) {
+		expired++
+	}
+	state.strikes = state.strikes[expired:]
+
+	switch {
+	case state.flagged:
+		return models.BotLevelFlag
+	case reached(len(state.strikes), d.cfg.ThrottleStrikes):
+		return models.BotLevelThrottle
+	case reached(len(state.strikes), d.cfg.FrictionStrikes):
+		return models.BotLevelFriction
+	default:
+		return models.BotLevelNone
+	}
+}
+
+// prune forgets the users who have neither swiped nor been throttled for a whole strike
+// window, at most once per window.
+func (d *velocityDetector) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.cfg.StrikeWindow {
+		return
+	}
+	d.lastPrune = now
+
+	cutoff := now.Add(-d.cfg.StrikeWindow)
+	for userID, state := range d.users {
+		last := state.events[len(state.events)-1].at
+		if last.Before(cutoff) && state.throttledUntil.Before(now) {
+			delete(d.users, userID)
+		}
+	}
+}
+
+// eventsKept is how many swipes per user the thresholds need to look back on.
+func (d *velocityDetector) eventsKept() int {
+	keep := d.cfg.MaxSwipes + 1
+	if d.cfg.LikeRatioSample > keep {
+		keep = d.cfg.LikeRatioSample
+	}
+	if d.cfg.TimingSample > keep {
+		keep = d.cfg.TimingSample
+	}
+	return keep
+}
+
+// intervalStats returns the mean and standard deviation of the seconds between events.
+func intervalStats(events []event) (float64, float64) {
+	intervals := make([]float64, len(events)-1)
+	var sum float64
+	for i := 1; i < len(events); i++ {
+		intervals[i-1] = events[i].at.Sub(events[i-1].at).Seconds()
+		sum += intervals[i-1]
+	}
+	mean := sum / float64(len(intervals))
+
+	var variance float64
+	for _, interval := range intervals {
+		variance += (interval - mean) * (interval - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(intervals)))
+}
+
+// reached reports whether count meets a strike threshold, a threshold of zero is disabled.
+func reached(count, threshold int) bool {
+	return threshold > 0 && count >= threshold
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type AdminHandler struct {
+	analyticsUseCase usecase.AnalyticsUseCase
+}
+
+func NewAdminHandler(analyticsUseCase usecase.AnalyticsUseCase) *AdminHandler {
+	return &AdminHandler{analyticsUseCase: analyticsUseCase}
+}
+
+// UnmatchReasons reports why users unmatched over the period between the optional from and
+// to query parameters.
+func (h *AdminHandler) UnmatchReasons(c *gin.Context) {
+	var from, to time.Time
+	var err error
+	if c.Query("from") != "" {
+		if from, err = time.Parse(time.RFC3339Nano, c.Query("from")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected an RFC 3339 time"})
+			return
+		}
+	}
+	if c.Query("to") != "" {
+		if to, err = time.Parse(time.RFC3339Nano, c.Query("to")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected an RFC 3339 time"})
+			return
+		}
+	}
+
+	report, err := h.analyticsUseCase.UnmatchReasons(from, to)
+	if errors.Is(err, usecase.ErrInvalidReportPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
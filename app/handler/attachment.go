@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type AttachmentHandler struct {
+	attachmentUseCase usecase.AttachmentUseCase
+}
+
+func NewAttachmentHandler(attachmentUseCase usecase.AttachmentUseCase) *AttachmentHandler {
+	return &AttachmentHandler{attachmentUseCase: attachmentUseCase}
+}
+
+// CreateUploadSlot reserves an image or voice note for the caller to upload to.
+func (h *AttachmentHandler) CreateUploadSlot(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	var request struct {
+		Kind string `json:"kind" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slot, err := h.attachmentUseCase.CreateUploadSlot(matchRoomID, userID.(uuid.UUID), request.Kind)
+	if err != nil {
+		attachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+// Upload takes the file of an upload slot as the raw request body.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	userID, matchRoomID, attachmentID, ok := attachmentParams(c)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.attachmentUseCase.Upload(matchRoomID, attachmentID, userID, c.Request.Body)
+	if err != nil {
+		attachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// GetURLs returns signed links to an attachment, to be fetched without authentication
+// before they expire.
+func (h *AttachmentHandler) GetURLs(c *gin.Context) {
+	userID, matchRoomID, attachmentID, ok := attachmentParams(c)
+	if !ok {
+		return
+	}
+
+	urls, err := h.attachmentUseCase.GetAttachmentURLs(matchRoomID, attachmentID, userID)
+	if err != nil {
+		attachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, urls)
+}
+
+// Serve sends the file a signed link points to.
+func (h *AttachmentHandler) Serve(c *gin.Context) {
+	h.serve(c, usecase.AttachmentOriginal)
+}
+
+// ServeThumbnail sends the thumbnail of an image a signed link points to.
+func (h *AttachmentHandler) ServeThumbnail(c *gin.Context) {
+	h.serve(c, usecase.AttachmentThumbnail)
+}
+
+func (h *AttachmentHandler) serve(c *gin.Context, variant string) {
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	file, contentType, err := h.attachmentUseCase.OpenAttachment(attachmentID, variant, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		attachmentError(c, err)
+		return
+	}
+	defer file.Close()
+
+	// Links expire, so nothing shared may keep the file
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, file)
+}
+
+// attachmentParams reads the caller and the match room and attachment of the path, answering
+// the request itself when one of them is missing or invalid.
+func attachmentParams(c *gin.Context) (userID, matchRoomID, attachmentID uuid.UUID, ok bool) {
+	caller, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+	attachmentID, err = uuid.Parse(c.Param("attachment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+	return caller.(uuid.UUID), matchRoomID, attachmentID, true
+}
+
+func attachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrMatchRoomNotFound), errors.Is(err, usecase.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidAttachmentLink):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrUnsupportedAttachment):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrUploadSlotExpired), errors.Is(err, usecase.ErrAttachmentUploaded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidAttachmentKind), errors.Is(err, usecase.ErrVoiceNoteTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type BoostHandler struct {
+	boostUseCase usecase.BoostUseCase
+}
+
+func NewBoostHandler(boostUseCase usecase.BoostUseCase) *BoostHandler {
+	return &BoostHandler{boostUseCase: boostUseCase}
+}
+
+func (h *BoostHandler) StartBoost(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	boost, err := h.boostUseCase.StartBoost(userID.(uuid.UUID))
+	if errors.Is(err, usecase.ErrBoostActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, boost)
+}
+
+func (h *BoostHandler) GetSummary(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	boostID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid boost ID"})
+		return
+	}
+
+	summary, err := h.boostUseCase.GetSummary(userID.(uuid.UUID), boostID)
+	if errors.Is(err, usecase.ErrBoostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
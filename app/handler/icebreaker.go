@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type IcebreakerHandler struct {
+	icebreakerUseCase usecase.IcebreakerUseCase
+}
+
+func NewIcebreakerHandler(icebreakerUseCase usecase.IcebreakerUseCase) *IcebreakerHandler {
+	return &IcebreakerHandler{icebreakerUseCase}
+}
+
+// GetIcebreakers suggests openers for the match room. Sending one passes its id as the
+// icebreaker_id of the message.
+func (h *IcebreakerHandler) GetIcebreakers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	icebreakers, err := h.icebreakerUseCase.GetIcebreakers(matchRoomID, userID.(uuid.UUID))
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, icebreakers)
+}
+
+// GetTemplates lists the icebreaker templates with how often they were suggested, sent and
+// answered.
+func (h *IcebreakerHandler) GetTemplates(c *gin.Context) {
+	templates, err := h.icebreakerUseCase.GetTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+type templateRequest struct {
+	Source models.IcebreakerSource `json:"source" binding:"required"`
+	Text   string                  `json:"text" binding:"required"`
+	Active *bool                   `json:"active"`
+}
+
+func (r templateRequest) template() *models.IcebreakerTemplate {
+	template := &models.IcebreakerTemplate{Source: r.Source, Text: r.Text, Active: true}
+	if r.Active != nil {
+		template.Active = *r.Active
+	}
+	return template
+}
+
+func (h *IcebreakerHandler) CreateTemplate(c *gin.Context) {
+	var request templateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := request.template()
+	if err := h.icebreakerUseCase.CreateTemplate(template); err != nil {
+		templateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateTemplate replaces the source, text and, when given, whether the template is active.
+func (h *IcebreakerHandler) UpdateTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var request templateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := request.template()
+	template.ID = id
+	if err := h.icebreakerUseCase.UpdateTemplate(template); err != nil {
+		templateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *IcebreakerHandler) DeleteTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := h.icebreakerUseCase.DeleteTemplate(id); err != nil {
+		templateError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// templateError answers with the status of an error of the icebreaker templates.
+func templateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,22 +1,25 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
 	"github.com/mdzakyabd/dating-app/app/usecase"
-	"github.com/pusher/pusher-http-go"
+	"github.com/mdzakyabd/dating-app/app/utils"
 )
 
 type MatchHandler struct {
 	matchUsecase usecase.MatchUsecase
-	pusherClient *pusher.Client
+	publisher    realtime.RealtimePublisher
 }
 
-func NewMatchHandler(matchUsecase usecase.MatchUsecase, pusherClient *pusher.Client) *MatchHandler {
-	return &MatchHandler{matchUsecase, pusherClient}
+func NewMatchHandler(matchUsecase usecase.MatchUsecase, publisher realtime.RealtimePublisher) *MatchHandler {
+	return &MatchHandler{matchUsecase, publisher}
 }
 
 func (h *MatchHandler) GetMatchRooms(c *gin.Context) {
@@ -33,6 +36,136 @@ func (h *MatchHandler) GetMatchRooms(c *gin.Context) {
 	c.JSON(http.StatusOK, matchRooms)
 }
 
+// MarkRead records that the caller read the room up to message_id, or up to the latest
+// message without one, and lets the other participant know.
+func (h *MatchHandler) MarkRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	var request struct {
+		MessageID uuid.UUID `json:"message_id"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	receipt, err := h.matchUsecase.MarkRead(matchRoomID, userID.(uuid.UUID), request.MessageID)
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) || errors.Is(err, usecase.ErrMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if receipt == nil {
+		// Already read as far
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	// Real-time update
+	publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventRead, receipt)
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// Typing tells the other participant the caller started or stopped typing. It is for
+// clients that cannot send it over a WebSocket connection, nothing is stored.
+func (h *MatchHandler) Typing(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	var request struct {
+		Typing *bool `json:"typing" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.matchUsecase.CheckMember(matchRoomID, userID.(uuid.UUID))
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := realtime.EventTypingStopped
+	if *request.Typing {
+		event = realtime.EventTypingStarted
+	}
+	publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), event, realtime.TypingData{MatchRoomID: matchRoomID, UserID: userID.(uuid.UUID)})
+
+	c.Status(http.StatusNoContent)
+}
+
+// ExtendMatch gives the first message of the match more time, once per match and for
+// premium users only, and lets the other participant know.
+func (h *MatchHandler) ExtendMatch(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	expiresAt, err := h.matchUsecase.ExtendMatch(matchRoomID, userID.(uuid.UUID))
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrPremiumRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrCannotExtend) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	extension := models.MatchExpiry{MatchRoomID: matchRoomID, ExpiresAt: *expiresAt}
+
+	// Real-time update
+	publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventMatchExtended, extension)
+
+	c.JSON(http.StatusOK, extension)
+}
+
 func (h *MatchHandler) DeleteMatchRoom(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -44,19 +177,53 @@ func (h *MatchHandler) DeleteMatchRoom(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
 		return
 	}
-	err = h.matchUsecase.DeleteMatchRoom(id, userID.(uuid.UUID))
+
+	var request struct {
+		Reason models.UnmatchReason `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	_, err = h.matchUsecase.Unmatch(id, userID.(uuid.UUID), request.Reason)
+	if errors.Is(err, usecase.ErrInvalidUnmatchReason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	// Real-time update
+	publish(h.publisher, realtime.MatchRoomChannel(id), realtime.EventUnmatch, realtime.MatchData{MatchRoomID: id, UserID: userID.(uuid.UUID)})
+
 	c.Status(http.StatusNoContent)
 }
 
+// CreateMessage sends a message from the caller, who must take part in the open match room.
+// A message held for review is accepted without being delivered.
 func (h *MatchHandler) CreateMessage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
 	var request struct {
 		MatchRoomID string `json:"match_room_id" binding:"required"`
-		UserID      string `json:"user_id" binding:"required"`
-		Content     string `json:"content" binding:"required"`
+		Content     string `json:"content"`
+		// AttachmentID is an uploaded attachment, the content then being its caption
+		AttachmentID *uuid.UUID `json:"attachment_id"`
+		// IcebreakerID is the icebreaker suggestion the message was sent from
+		IcebreakerID *uuid.UUID `json:"icebreaker_id"`
 	}
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -69,36 +236,113 @@ func (h *MatchHandler) CreateMessage(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(request.UserID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
-		return
+	message := &models.Message{MatchRoomID: matchRoomID, SenderID: userID.(uuid.UUID), Content: request.Content, IcebreakerID: request.IcebreakerID}
+	if request.AttachmentID != nil {
+		message.Attachment = &models.Attachment{ID: *request.AttachmentID}
 	}
 
-	err = h.matchUsecase.CreateMessage(&models.Message{MatchRoomID: matchRoomID, SenderID: userID, Content: request.Content})
+	err = h.matchUsecase.CreateMessage(message)
+	if errors.Is(err, usecase.ErrInvalidMessage) || errors.Is(err, usecase.ErrAttachmentNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrNotYourTurn) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if message.HeldAt != nil {
+		// The recipient gets it once a moderator released it
+		c.JSON(http.StatusAccepted, gin.H{"id": message.ID, "held": true})
+		return
+	}
 
-	// Pusher real-time update
-	data := map[string]string{"user_id": request.UserID, "content": request.Content}
+	// Real-time update
+	data := map[string]interface{}{"user_id": userID, "content": request.Content}
+	if message.Attachment != nil {
+		data["attachment"] = message.Attachment
+	}
 
-	h.pusherClient.Trigger("chat_room_"+request.MatchRoomID, "new_message", data)
+	publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventNewMessage, data)
 
-	c.Status(http.StatusCreated)
+	// A nudge asks the sender whether they meant to send it, they can still unsend it
+	c.JSON(http.StatusCreated, gin.H{"id": message.ID, "nudge": message.Screening.Verdict == models.ScreeningNudge})
 }
 
+// GetMessages lists the messages of a match room, the latest first. ?before= pages back
+// through older messages, ?after= forward through newer ones, oldest first.
 func (h *MatchHandler) GetMessages(c *gin.Context) {
-	matchRoomID, err := uuid.Parse(c.Param("match_room_id"))
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+
+	query := models.MessageQuery{Before: c.Query("before"), After: c.Query("after")}
+	if c.Query("limit") != "" {
+		if query.Limit, err = strconv.Atoi(c.Query("limit")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	page, err := h.matchUsecase.GetMessages(matchRoomID, userID.(uuid.UUID), query)
+	if errors.Is(err, usecase.ErrInvalidMessageQuery) || errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetMessageUpdates returns what changed in a match room since ?since=, the next_since of the
+// previous sync or, for a first sync, an RFC 3339 time.
+func (h *MatchHandler) GetMessageUpdates(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
 		return
 	}
-	messages, err := h.matchUsecase.GetMessages(matchRoomID)
+	updates, err := h.matchUsecase.GetMessageUpdates(matchRoomID, userID.(uuid.UUID), c.Query("since"))
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected next_since or an RFC 3339 time"})
+		return
+	}
+	if errors.Is(err, usecase.ErrMatchRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, messages)
+	c.JSON(http.StatusOK, updates)
 }
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+func (h *MatchHandler) EditMessage(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.matchUsecase.EditMessage(matchRoomID, messageID, userID, request.Content)
+	if err != nil {
+		messageError(c, err)
+		return
+	}
+
+	// Real-time update, the recipient knows nothing of a message held for review
+	if message.HeldAt == nil {
+		publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventMessageEdited, message)
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// UnsendMessage removes a message for both participants, leaving a tombstone in its place.
+func (h *MatchHandler) UnsendMessage(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.matchUsecase.UnsendMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		messageError(c, err)
+		return
+	}
+
+	// Real-time update
+	if message.HeldAt == nil {
+		publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventMessageUnsent, message)
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *MatchHandler) GetMessageEdits(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	edits, err := h.matchUsecase.GetMessageEdits(matchRoomID, messageID, userID)
+	if err != nil {
+		messageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, edits)
+}
+
+func (h *MatchHandler) React(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Emoji string `json:"emoji" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reaction, err := h.matchUsecase.React(matchRoomID, messageID, userID, request.Emoji)
+	if err != nil {
+		messageError(c, err)
+		return
+	}
+
+	// Real-time update
+	publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventReactionAdded, reaction)
+
+	c.JSON(http.StatusOK, reaction)
+}
+
+// MarkUnwanted lets the recipient of a message say they did not want it.
+func (h *MatchHandler) MarkUnwanted(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.matchUsecase.MarkUnwanted(matchRoomID, messageID, userID); err != nil {
+		messageError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *MatchHandler) Unreact(c *gin.Context) {
+	userID, matchRoomID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	removed, err := h.matchUsecase.Unreact(matchRoomID, messageID, userID)
+	if err != nil {
+		messageError(c, err)
+		return
+	}
+
+	// Real-time update
+	if removed {
+		data := models.MessageReaction{MessageID: messageID, UserID: userID}
+		publish(h.publisher, realtime.MatchRoomChannel(matchRoomID), realtime.EventReactionRemoved, data)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// messageParams reads the caller and the match room and message of the path, answering
+// the request itself when one of them is missing or invalid.
+func messageParams(c *gin.Context) (userID, matchRoomID, messageID uuid.UUID, ok bool) {
+	caller, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	matchRoomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match room ID"})
+		return
+	}
+	messageID, err = uuid.Parse(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	return caller.(uuid.UUID), matchRoomID, messageID, true
+}
+
+func messageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrMatchRoomNotFound), errors.Is(err, usecase.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrNotMessageSender), errors.Is(err, usecase.ErrOwnMessage):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrEditWindowClosed), errors.Is(err, usecase.ErrMessageUnsent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidMessage), errors.Is(err, usecase.ErrInvalidReaction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrEditHeld):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
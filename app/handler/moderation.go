@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+)
+
+type ModerationHandler struct {
+	moderationUseCase usecase.ModerationUseCase
+	publisher         realtime.RealtimePublisher
+}
+
+func NewModerationHandler(moderationUseCase usecase.ModerationUseCase, publisher realtime.RealtimePublisher) *ModerationHandler {
+	return &ModerationHandler{moderationUseCase, publisher}
+}
+
+// GetCases lists the moderation queue, the soonest due first. It is filtered by the
+// optional status, source and assignee query parameters, assignee being a moderator ID,
+// "me" or "none", and overdue=true only keeps the cases past their due time.
+func (h *ModerationHandler) GetCases(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	filter := models.CaseFilter{Status: models.CaseStatus(c.Query("status")), Source: models.CaseSource(c.Query("source"))}
+	if filter.Status != "" && !filter.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	if filter.Source != "" && !filter.Source.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source"})
+		return
+	}
+
+	switch assignee := c.Query("assignee"); assignee {
+	case "":
+	case "none":
+		filter.Unassigned = true
+	case "me":
+		id := userID.(uuid.UUID)
+		filter.AssigneeID = &id
+	default:
+		id, err := uuid.Parse(assignee)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee"})
+			return
+		}
+		filter.AssigneeID = &id
+	}
+
+	if c.Query("overdue") != "" {
+		overdue, err := strconv.ParseBool(c.Query("overdue"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overdue"})
+			return
+		}
+		if overdue {
+			filter.OverdueAt = time.Now()
+		}
+	}
+
+	page, err := h.moderationUseCase.GetCases(filter, c.Query("cursor"))
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// OpenCase flags a user for review by hand, for a photo or anything the automated checks
+// missed.
+func (h *ModerationHandler) OpenCase(c *gin.Context) {
+	var request struct {
+		UserID  uuid.UUID         `json:"user_id" binding:"required"`
+		Source  models.CaseSource `json:"source" binding:"required"`
+		Summary string            `json:"summary" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moderationCase, err := h.moderationUseCase.OpenCase(request.UserID, request.Source, request.Summary)
+	if errors.Is(err, usecase.ErrInvalidCaseSource) || errors.Is(err, usecase.ErrInvalidNote) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, moderationCase)
+}
+
+// GetCase returns a case with its reports and notes.
+func (h *ModerationHandler) GetCase(c *gin.Context) {
+	caseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	moderationCase, err := h.moderationUseCase.GetCase(caseID)
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, moderationCase)
+}
+
+// Assign puts a case in review with assignee_id, the caller when omitted.
+func (h *ModerationHandler) Assign(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	caseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var request struct {
+		AssigneeID uuid.UUID `json:"assignee_id"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if request.AssigneeID == uuid.Nil {
+		request.AssigneeID = userID.(uuid.UUID)
+	}
+
+	moderationCase, err := h.moderationUseCase.Assign(caseID, userID.(uuid.UUID), request.AssigneeID)
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, moderationCase)
+}
+
+// Release puts a case in review back in the queue.
+func (h *ModerationHandler) Release(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	caseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	moderationCase, err := h.moderationUseCase.Release(caseID, userID.(uuid.UUID))
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, moderationCase)
+}
+
+func (h *ModerationHandler) AddNote(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	caseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var request struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := h.moderationUseCase.AddNote(caseID, userID.(uuid.UUID), request.Body)
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
+
+// Decide resolves a case, suspend_hours being how long a suspension lasts, and lets the
+// user know of the action taken.
+func (h *ModerationHandler) Decide(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	caseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
+		return
+	}
+
+	var request struct {
+		Decision     models.CaseDecision `json:"decision" binding:"required"`
+		Note         string              `json:"note"`
+		SuspendHours int                 `json:"suspend_hours"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moderationCase, err := h.moderationUseCase.Decide(caseID, userID.(uuid.UUID), usecase.DecisionRequest{
+		Decision:   request.Decision,
+		Note:       request.Note,
+		SuspendFor: time.Duration(request.SuspendHours) * time.Hour,
+	})
+	if err != nil {
+		caseError(c, err)
+		return
+	}
+
+	// Real-time update
+	if *moderationCase.Decision != models.DecisionDismiss {
+		publish(h.publisher, realtime.UserChannel(moderationCase.UserID), realtime.EventModerationAction, realtime.ModerationData{Decision: *moderationCase.Decision, SuspendedUntil: moderationCase.SuspendUntil})
+	}
+
+	c.JSON(http.StatusOK, moderationCase)
+}
+
+// GetMetrics sums up the queue and how fast it was worked through over the last week.
+func (h *ModerationHandler) GetMetrics(c *gin.Context) {
+	metrics, err := h.moderationUseCase.GetMetrics(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// ReleaseMessage delivers a message held for review to its recipient.
+func (h *ModerationHandler) ReleaseMessage(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	message, err := h.moderationUseCase.ReleaseMessage(messageID)
+	if errors.Is(err, usecase.ErrMessageNotHeld) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Real-time update
+	data := map[string]interface{}{"user_id": message.SenderID, "content": message.Content}
+	if message.Attachment != nil {
+		data["attachment"] = message.Attachment
+	}
+	publish(h.publisher, realtime.MatchRoomChannel(message.MatchRoomID), realtime.EventNewMessage, data)
+
+	c.Status(http.StatusNoContent)
+}
+
+// caseError answers with the status matching an error of the moderation use case.
+func caseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidAssignee), errors.Is(err, usecase.ErrInvalidNote), errors.Is(err, usecase.ErrInvalidDecision):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCaseResolved), errors.Is(err, usecase.ErrCaseNotInReview), errors.Is(err, usecase.ErrCaseChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
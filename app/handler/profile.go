@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
 	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
 )
 
 type ProfileHandler struct {
@@ -17,12 +19,28 @@ func NewProfileHandler(profileUseCase usecase.ProfileUseCase) *ProfileHandler {
 	return &ProfileHandler{profileUseCase: profileUseCase}
 }
 
+// profileRequest is a profile as its owner sends it, the location included, which is never
+// sent back out.
+type profileRequest struct {
+	models.Profile
+	Latitude  float64
+	Longitude float64
+}
+
+func (r *profileRequest) profile() models.Profile {
+	profile := r.Profile
+	profile.Latitude = r.Latitude
+	profile.Longitude = r.Longitude
+	return profile
+}
+
 func (h *ProfileHandler) CreateProfile(c *gin.Context) {
-	var profile models.Profile
-	if err := c.BindJSON(&profile); err != nil {
+	var request profileRequest
+	if err := c.BindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	profile := request.profile()
 
 	if err := h.profileUseCase.CreateProfile(&profile); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
@@ -57,12 +75,13 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	var profile models.Profile
-	if err := c.BindJSON(&profile); err != nil {
+	var request profileRequest
+	if err := c.BindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	profile := request.profile()
 	profile.ID = id
 	if err := h.profileUseCase.UpdateProfile(&profile); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -79,11 +98,68 @@ func (h *ProfileHandler) ViewProfiles(c *gin.Context) {
 		return
 	}
 
-	profiles, err := h.profileUseCase.ViewProfiles(userID.(uuid.UUID))
+	page, err := h.profileUseCase.ViewProfiles(userID.(uuid.UUID), c.Query("cursor"))
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, profiles)
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *ProfileHandler) GetPreference(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	preference, err := h.profileUseCase.GetPreference(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preference not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+func (h *ProfileHandler) UpdatePreference(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	var request struct {
+		Gender        string  `json:"gender"`
+		MinAge        int     `json:"min_age" binding:"gte=0"`
+		MaxAge        int     `json:"max_age" binding:"gte=0"`
+		MaxDistanceKm float64 `json:"max_distance_km" binding:"gte=0"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preference := &models.Preference{
+		UserID:        userID.(uuid.UUID),
+		Gender:        request.Gender,
+		MinAge:        request.MinAge,
+		MaxAge:        request.MaxAge,
+		MaxDistanceKm: request.MaxDistanceKm,
+	}
+	if err := h.profileUseCase.UpdatePreference(preference); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
 }
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type QuotaHandler struct {
+	quotaUseCase usecase.QuotaUseCase
+}
+
+func NewQuotaHandler(quotaUseCase usecase.QuotaUseCase) *QuotaHandler {
+	return &QuotaHandler{quotaUseCase: quotaUseCase}
+}
+
+func (h *QuotaHandler) GetQuotas(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	quotas, err := h.quotaUseCase.GetQuotas(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quotas)
+}
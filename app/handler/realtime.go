@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/middleware"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+)
+
+type RealtimeHandler struct {
+	hub         *realtime.Hub
+	userUseCase usecase.UserUseCase
+	jwtSecret   string
+}
+
+func NewRealtimeHandler(hub *realtime.Hub, userUseCase usecase.UserUseCase, jwtSecret string) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub, userUseCase: userUseCase, jwtSecret: jwtSecret}
+}
+
+// Connect opens a WebSocket connection. Browsers cannot set headers on WebSocket requests,
+// so the token may be given as ?token= instead of the Authorization header. middleware.Logger
+// keeps it out of the access log.
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		tokenString = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+
+	userID, err := utils.ParseJWT(tokenString, h.jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	if !middleware.CheckActiveAccount(c, h.userUseCase, userID) {
+		return
+	}
+
+	if err := h.hub.Connect(c.Writer, c.Request, userID); err != nil && !c.Writer.Written() {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// streamHeartbeat keeps idle event streams from being closed by proxies along the way
+const streamHeartbeat = 25 * time.Second
+
+// Stream serves the events of the caller as server-sent events. A client reconnecting with
+// Last-Event-ID gets the events it missed, or a reset event when they are no longer kept.
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	stream, err := h.hub.OpenStream(userID.(uuid.UUID), c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if stream.Reset {
+		fmt.Fprint(c.Writer, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range stream.Replay {
+		writeStreamEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event := <-stream.Events():
+			writeStreamEvent(c, event)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+			h.hub.Seen(userID.(uuid.UUID))
+		case <-stream.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamEvent(c *gin.Context, event realtime.StreamEvent) {
+	if event.ID != 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", event.ID)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Event, event.Payload)
+}
+
+// publish sends a realtime event. The request it belongs to has already succeeded, so a
+// failure is only logged.
+func publish(publisher realtime.RealtimePublisher, channel, event string, data interface{}) {
+	if err := publisher.Publish(channel, event, data); err != nil {
+		log.Printf("realtime: publishing %s on %s failed: %v", event, channel, err)
+	}
+}
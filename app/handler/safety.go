@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+type SafetyHandler struct {
+	safetyUseCase usecase.SafetyUseCase
+	publisher     realtime.RealtimePublisher
+}
+
+func NewSafetyHandler(safetyUseCase usecase.SafetyUseCase, publisher realtime.RealtimePublisher) *SafetyHandler {
+	return &SafetyHandler{safetyUseCase, publisher}
+}
+
+func (h *SafetyHandler) Block(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	blockedUserID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	unmatch, err := h.safetyUseCase.Block(userID.(uuid.UUID), blockedUserID)
+	if errors.Is(err, usecase.ErrSelfBlock) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Real-time update
+	if unmatch != nil {
+		publish(h.publisher, realtime.MatchRoomChannel(unmatch.MatchRoomID), realtime.EventUnmatch, realtime.MatchData{MatchRoomID: unmatch.MatchRoomID, UserID: unmatch.UserID})
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SafetyHandler) Report(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+	reportedUserID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request struct {
+		Category models.ReportCategory `json:"category" binding:"required"`
+		Details  string                `json:"details"`
+		// MessageIDs are messages of the reported user the report is about
+		MessageIDs []uuid.UUID `json:"message_ids"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.safetyUseCase.Report(userID.(uuid.UUID), reportedUserID, usecase.ReportRequest{
+		Category:   request.Category,
+		Details:    request.Details,
+		MessageIDs: request.MessageIDs,
+	})
+	if errors.Is(err, usecase.ErrSelfBlock) || errors.Is(err, usecase.ErrInvalidReport) || errors.Is(err, usecase.ErrReportMessages) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
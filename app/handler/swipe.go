@@ -1,27 +1,37 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/antibot"
 	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
 	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
 )
 
 type SwipeHandler struct {
 	swipeUseCase usecase.SwipeUseCase
+	publisher    realtime.RealtimePublisher
 }
 
-func NewSwipeHandler(swipeUseCase usecase.SwipeUseCase) *SwipeHandler {
-	return &SwipeHandler{swipeUseCase: swipeUseCase}
+func NewSwipeHandler(swipeUseCase usecase.SwipeUseCase, publisher realtime.RealtimePublisher) *SwipeHandler {
+	return &SwipeHandler{swipeUseCase: swipeUseCase, publisher: publisher}
 }
 
 func (h *SwipeHandler) Swipe(c *gin.Context) {
 	var request struct {
 		UserID       string `json:"user_id" binding:"required"`
 		TargetUserID string `json:"target_user_id" binding:"required"`
-		Liked        bool   `json:"liked"`
+		Type         string `json:"type"`
+		// Liked is the request format from before swipe types, used when type is empty
+		Liked bool `json:"liked"`
 	}
 
 	if err := c.BindJSON(&request); err != nil {
@@ -41,17 +51,144 @@ func (h *SwipeHandler) Swipe(c *gin.Context) {
 		return
 	}
 
+	swipeType := models.SwipeType(request.Type)
+	if swipeType == "" {
+		swipeType = models.SwipePass
+		if request.Liked {
+			swipeType = models.SwipeLike
+		}
+	}
+
 	swipe := &models.Swipe{
-		UserID:       userID,
-		TargetUserID: targetUserID,
-		Liked:        request.Liked,
+		UserID:         userID,
+		TargetUserID:   targetUserID,
+		Type:           swipeType,
+		IdempotencyKey: c.GetHeader("Idempotency-Key"),
+	}
+	if len(swipe.IdempotencyKey) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+		return
 	}
 
-	err = h.swipeUseCase.Swipe(swipe)
+	result, err := h.swipeUseCase.Swipe(swipe)
+	if errors.Is(err, usecase.ErrInvalidSwipeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrIdempotencyKeyReused) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	var retryErr *antibot.RetryError
+	if errors.As(err, &retryErr) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to swipe"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Swipe recorded"})
+	// Real-time update
+	switch {
+	case result.Replayed:
+		// A retried request was already announced
+	case result.Match != nil:
+		event := realtime.EventNewMatch
+		if result.SuperLikeMatch {
+			event = realtime.EventSuperLikeMatch
+		}
+		publish(h.publisher, realtime.UserChannel(userID), event, realtime.MatchData{MatchRoomID: result.Match.ID, UserID: targetUserID})
+		publish(h.publisher, realtime.UserChannel(targetUserID), event, realtime.MatchData{MatchRoomID: result.Match.ID, UserID: userID})
+	case swipe.Type == models.SwipeSuperLike:
+		data := map[string]string{"user_id": userID.String()}
+		publish(h.publisher, realtime.UserChannel(targetUserID), realtime.EventSuperLikeReceived, data)
+	}
+
+	response := gin.H{"message": "Swipe recorded", "matched": result.Match != nil}
+	if result.Match != nil {
+		response["match_room_id"] = result.Match.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *SwipeHandler) Rewind(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	swipe, err := h.swipeUseCase.Rewind(userID.(uuid.UUID))
+	if errors.Is(err, usecase.ErrNothingToRewind) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrRewindMatched) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rewind"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Swipe rewound", "target_user_id": swipe.TargetUserID})
+}
+
+func (h *SwipeHandler) LikesReceived(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	page, err := h.swipeUseCase.LikesReceived(userID.(uuid.UUID), c.Query("cursor"))
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *SwipeHandler) History(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		return
+	}
+
+	// Types are given as ?type=like,super_like
+	var types []models.SwipeType
+	if c.Query("type") != "" {
+		for _, swipeType := range strings.Split(c.Query("type"), ",") {
+			types = append(types, models.SwipeType(strings.TrimSpace(swipeType)))
+		}
+	}
+
+	page, err := h.swipeUseCase.History(userID.(uuid.UUID), types, c.Query("cursor"))
+	if errors.Is(err, usecase.ErrInvalidSwipeType) || errors.Is(err, utils.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
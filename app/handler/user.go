@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -14,10 +15,11 @@ import (
 
 type UserHandler struct {
 	userUseCase usecase.UserUseCase
+	jwtSecret   string
 }
 
-func NewUserHandler(userUseCase usecase.UserUseCase) *UserHandler {
-	return &UserHandler{userUseCase: userUseCase}
+func NewUserHandler(userUseCase usecase.UserUseCase, jwtSecret string) *UserHandler {
+	return &UserHandler{userUseCase: userUseCase, jwtSecret: jwtSecret}
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -25,6 +27,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Username string `json:"username" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required"`
+		Timezone string `json:"timezone"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -36,9 +39,14 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Username: request.Username,
 		Email:    request.Email,
 		Password: request.Password,
+		Timezone: request.Timezone,
 	}
 
 	if err := h.userUseCase.Register(user); err != nil {
+		if errors.Is(err, usecase.ErrInvalidTimezone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -58,12 +66,16 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	user, err := h.userUseCase.Login(request.Email, request.Password)
+	if errors.Is(err, usecase.ErrAccountBarred) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID.String(), "your-secret-key")
+	token, err := utils.GenerateJWT(user.ID.String(), h.jwtSecret)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
 		return
@@ -77,6 +89,9 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		Username string `json:"username" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password"`
+		Timezone string `json:"timezone"`
+		// HidePresence is left as it is when omitted
+		HidePresence *bool `json:"hide_presence"`
 	}
 
 	userID, exists := c.Get("userID")
@@ -85,11 +100,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
-		return
-	}
+	id := userID.(uuid.UUID)
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -112,8 +123,18 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		}
 		user.Password = hashedPassword
 	}
+	if request.Timezone != "" {
+		user.Timezone = request.Timezone
+	}
+	if request.HidePresence != nil {
+		user.HidePresence = *request.HidePresence
+	}
 
 	if err := h.userUseCase.UpdateUser(user); err != nil {
+		if errors.Is(err, usecase.ErrInvalidTimezone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -128,11 +149,7 @@ func (h *UserHandler) SubscribePremium(c *gin.Context) {
 		return
 	}
 
-	id, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
-		return
-	}
+	id := userID.(uuid.UUID)
 
 	// Simulate payment process
 	// In a real application, integrate with a payment gateway here and handle the response.
@@ -0,0 +1,209 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// Audio is a voice note, its duration read from the headers of its container.
+type Audio struct {
+	ContentType string
+	Duration    time.Duration
+}
+
+// maxDuration is the longest duration a header may claim, a longer one is taken for forged.
+const maxDuration = 24 * time.Hour
+
+// ParseAudio reads a WAV, Ogg (Opus or Vorbis) or MP4 (M4A) voice note.
+func ParseAudio(data []byte) (*Audio, error) {
+	var audio *Audio
+	var ok bool
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		audio, ok = parseWAV(data)
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		audio, ok = parseOgg(data)
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		audio, ok = parseMP4(data)
+	}
+
+	if !ok || audio.Duration <= 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	return audio, nil
+}
+
+// parseWAV divides the size of the data chunk by the byte rate of the fmt chunk.
+func parseWAV(data []byte) (*Audio, bool) {
+	var byteRate, dataSize uint32
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+		body := data[offset+8:]
+
+		switch id {
+		case "fmt ":
+			if size < 16 || len(body) < 16 {
+				return nil, false
+			}
+			byteRate = binary.LittleEndian.Uint32(body[8:12])
+		case "data":
+			// Recorders that stream the file may not fill in the size
+			dataSize = size
+			if uint64(size) > uint64(len(body)) {
+				dataSize = uint32(len(body))
+			}
+		}
+		if byteRate > 0 && dataSize > 0 {
+			break
+		}
+
+		// Chunks are padded to an even size
+		next := uint64(offset) + 8 + uint64(size) + uint64(size&1)
+		if next > uint64(len(data)) {
+			break
+		}
+		offset = int(next)
+	}
+
+	length, ok := duration(uint64(dataSize), uint64(byteRate))
+	if !ok {
+		return nil, false
+	}
+	return &Audio{ContentType: "audio/wav", Duration: length}, true
+}
+
+// parseOgg takes the sample rate from the header of the first stream and the granule
+// position, its sample count, from the stream's last page.
+func parseOgg(data []byte) (*Audio, bool) {
+	var serial uint32
+	var sampleRate, preSkip uint64
+	granule := int64(-1)
+
+	for offset, first := 0, true; offset+27 <= len(data); first = false {
+		page := data[offset:]
+		if string(page[0:4]) != "OggS" {
+			return nil, false
+		}
+		segments := int(page[26])
+		if len(page) < 27+segments {
+			return nil, false
+		}
+		bodySize := 0
+		for _, lacing := range page[27 : 27+segments] {
+			bodySize += int(lacing)
+		}
+		body := page[27+segments:]
+		if len(body) > bodySize {
+			body = body[:bodySize]
+		}
+
+		pageSerial := binary.LittleEndian.Uint32(page[14:18])
+		switch {
+		case first:
+			serial = pageSerial
+			switch {
+			case len(body) >= 19 && bytes.HasPrefix(body, []byte("OpusHead")):
+				// Opus always counts samples at 48 kHz, the first ones are encoder delay
+				sampleRate = 48000
+				preSkip = uint64(binary.LittleEndian.Uint16(body[10:12]))
+			case len(body) >= 16 && bytes.HasPrefix(body, []byte("\x01vorbis")):
+				sampleRate = uint64(binary.LittleEndian.Uint32(body[12:16]))
+			default:
+				return nil, false
+			}
+		case pageSerial == serial:
+			if position := int64(binary.LittleEndian.Uint64(page[6:14])); position >= 0 {
+				granule = position
+			}
+		}
+
+		offset += 27 + segments + bodySize
+	}
+
+	if granule < 0 || uint64(granule) <= preSkip {
+		return nil, false
+	}
+	length, ok := duration(uint64(granule)-preSkip, sampleRate)
+	if !ok {
+		return nil, false
+	}
+	return &Audio{ContentType: "audio/ogg", Duration: length}, true
+}
+
+// parseMP4 reads the timescale and duration of the movie header, moov/mvhd.
+func parseMP4(data []byte) (*Audio, bool) {
+	moov, ok := findBox(data, "moov")
+	if !ok {
+		return nil, false
+	}
+	mvhd, ok := findBox(moov, "mvhd")
+	if !ok || len(mvhd) < 4 {
+		return nil, false
+	}
+
+	var timescale, units uint64
+	switch mvhd[0] {
+	case 0:
+		if len(mvhd) < 20 {
+			return nil, false
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:16]))
+		units = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	case 1:
+		if len(mvhd) < 32 {
+			return nil, false
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:24]))
+		units = binary.BigEndian.Uint64(mvhd[24:32])
+	default:
+		return nil, false
+	}
+
+	length, ok := duration(units, timescale)
+	if !ok {
+		return nil, false
+	}
+	return &Audio{ContentType: "audio/mp4", Duration: length}, true
+}
+
+// duration is how long count units last at perSecond units a second. Whole seconds and the
+// rest are converted apart, so the multiplication cannot overflow. It reports false for a
+// rate of zero or a duration past maxDuration.
+func duration(count, perSecond uint64) (time.Duration, bool) {
+	if perSecond == 0 || count/perSecond > uint64(maxDuration/time.Second) {
+		return 0, false
+	}
+	seconds := time.Duration(count/perSecond) * time.Second
+	return seconds + time.Duration(count%perSecond*uint64(time.Second)/perSecond), true
+}
+
+// findBox returns the body of the first box of type name among the boxes in data.
+func findBox(data []byte, name string) ([]byte, bool) {
+	for offset := uint64(0); offset+8 <= uint64(len(data)); {
+		size := uint64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		header := uint64(8)
+		switch size {
+		case 0:
+			// The box runs to the end
+			size = uint64(len(data)) - offset
+		case 1:
+			if offset+16 > uint64(len(data)) {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(data[offset+8 : offset+16])
+			header = 16
+		}
+		// offset is within data, subtracting cannot wrap around as adding size could
+		if size < header || size > uint64(len(data))-offset {
+			return nil, false
+		}
+
+		if string(data[offset+4:offset+8]) == name {
+			return data[offset+header : offset+size], true
+		}
+		offset += size
+	}
+	return nil, false
+}
@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Formats images are accepted in
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxImagePixels refuses images that are small on disk but huge once decoded
+const maxImagePixels = 40_000_000
+
+// thumbnailSamples is how many source pixels per side are averaged into a thumbnail pixel
+const thumbnailSamples = 4
+
+// Image is a decoded JPEG, PNG or GIF.
+type Image struct {
+	Image       image.Image
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// DecodeImage checks the dimensions of data before decoding it.
+func DecodeImage(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	return &Image{Image: img, ContentType: "image/" + format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// Thumbnail scales img down to fit in size by size pixels and encodes it as a JPEG. Smaller
+// images keep their size, transparency is flattened onto white.
+func Thumbnail(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	longest := width
+	if height > longest {
+		longest = height
+	}
+	if longest > size {
+		width = (width*size + longest - 1) / longest
+		height = (height*size + longest - 1) / longest
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			thumbnail.Set(x, y, average(img, x0, y0, x1, y1))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// average blends a grid of samples from the box x0,y0 - x1,y1 of img over white.
+func average(img image.Image, x0, y0, x1, y1 int) color.RGBA {
+	stepsX, stepsY := x1-x0, y1-y0
+	if stepsX > thumbnailSamples {
+		stepsX = thumbnailSamples
+	}
+	if stepsY > thumbnailSamples {
+		stepsY = thumbnailSamples
+	}
+
+	var r, g, b uint32
+	for i := 0; i < stepsY; i++ {
+		y := y0 + i*(y1-y0)/stepsY
+		for j := 0; j < stepsX; j++ {
+			x := x0 + j*(x1-x0)/stepsX
+			// Premultiplied, so adding what the alpha leaves out puts the pixel on white
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			r += pr + 0xffff - pa
+			g += pg + 0xffff - pa
+			b += pb + 0xffff - pa
+		}
+	}
+
+	n := uint32(stepsX * stepsY)
+	return color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xff}
+}
@@ -0,0 +1,9 @@
+// Package media validates the files sent in chat and reads what the app needs from them.
+package media
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	ErrImageTooLarge     = errors.New("image dimensions are too large")
+)
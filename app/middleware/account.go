@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"gorm.io/gorm"
+)
+
+// ActiveAccount turns away users who were banned or are suspended, as their tokens outlive
+// the decision, and puts the authenticated user in the context as "user".
+func ActiveAccount(userUseCase usecase.UserUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+			c.Abort()
+			return
+		}
+
+		if CheckActiveAccount(c, userUseCase, userID.(uuid.UUID)) {
+			c.Next()
+		}
+	}
+}
+
+// CheckActiveAccount is ActiveAccount for handlers that authenticate themselves. It answers
+// and aborts the request, returning false, when the user may not go on.
+func CheckActiveAccount(c *gin.Context, userUseCase usecase.UserUseCase, userID uuid.UUID) bool {
+	user, err := userUseCase.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Abort()
+		return false
+	}
+	if user.Barred(time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": usecase.ErrAccountBarred.Error()})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user", user)
+	return true
+}
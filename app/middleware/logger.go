@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedParams are the query parameters kept out of the access log. WebSocket clients
+// send their JWT as ?token=, as browsers cannot set headers on those requests.
+var redactedParams = []string{"token"}
+
+// Logger is gin's request logger, with the values of redactedParams left out of the logged
+// path.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(params gin.LogFormatterParams) string {
+		if params.Latency > time.Minute {
+			params.Latency = params.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			params.StatusCode,
+			params.Latency,
+			params.ClientIP,
+			params.Method,
+			RedactQuery(params.Path),
+			params.ErrorMessage,
+		)
+	})
+}
+
+// RedactQuery replaces the values of redactedParams in the query string of path.
+func RedactQuery(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		// Whatever could not be parsed is left out as a whole
+		return path[:i] + "?REDACTED"
+	}
+	redacted := false
+	for _, param := range redactedParams {
+		if _, ok := query[param]; ok {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return path[:i] + "?" + query.Encode()
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+// Presence records the authenticated user as active. A failure to record it does not fail
+// the request.
+func Presence(presenceUseCase usecase.PresenceUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, exists := c.Get("userID"); exists {
+			if err := presenceUseCase.Touch(userID.(uuid.UUID), time.Now()); err != nil {
+				log.Printf("presence: recording the activity of %s failed: %v", userID, err)
+			}
+		}
+		c.Next()
+	}
+}
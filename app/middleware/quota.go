@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+)
+
+// QuotaHeaders adds an X-Quota-<Name>-Remaining header for every quota of the
+// authenticated user. The headers are filled in right before the response is written,
+// so they already account for quota consumed by the request itself.
+func QuotaHeaders(quotaUseCase usecase.QuotaUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		writer := &quotaHeaderWriter{
+			ResponseWriter: c.Writer,
+			userID:         userID.(uuid.UUID),
+			quotaUseCase:   quotaUseCase,
+		}
+		c.Writer = writer
+		c.Next()
+
+		// Responses without a body are flushed by gin after the handlers return
+		if !writer.Written() {
+			writer.setHeaders()
+		}
+	}
+}
+
+type quotaHeaderWriter struct {
+	gin.ResponseWriter
+	userID       uuid.UUID
+	quotaUseCase usecase.QuotaUseCase
+	done         bool
+}
+
+func (w *quotaHeaderWriter) setHeaders() {
+	if w.done {
+		return
+	}
+	w.done = true
+
+	statuses, err := w.quotaUseCase.GetQuotas(w.userID)
+	if err != nil {
+		return
+	}
+
+	for _, status := range statuses {
+		remaining := "unlimited"
+		if !status.Unlimited {
+			remaining = strconv.Itoa(status.Remaining)
+		}
+		w.Header().Set(QuotaHeaderName(status.Name), remaining)
+	}
+}
+
+// QuotaHeaderName turns a quota name like daily_swipes into X-Quota-Daily-Swipes-Remaining.
+func QuotaHeaderName(quota string) string {
+	parts := strings.Split(quota, "_")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return "X-Quota-" + strings.Join(parts, "-") + "-Remaining"
+}
+
+func (w *quotaHeaderWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *quotaHeaderWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *quotaHeaderWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mdzakyabd/dating-app/app/models"
+)
+
+// RequireRole only lets the user put in the context by ActiveAccount through when they
+// have one of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authorized"})
+			c.Abort()
+			return
+		}
+
+		if !user.(*models.User).HasRole(roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,278 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records a migration that has been applied.
+type SchemaMigration struct {
+	ID        string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+type migration struct {
+	ID string
+	Up func(tx *gorm.DB) error
+}
+
+// Migrations that AutoMigrate cannot express, applied once each in order.
+var migrations = []migration{
+	{
+		ID: "0001_created_at_range_indexes",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_swipes_user_created_at ON swipes (user_id, created_at)").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_match_rooms_user_created_at ON match_rooms (user_id, created_at)").Error
+		},
+	},
+	{
+		// Swipes used to be a liked boolean, carry it over to the type column
+		ID: "0002_swipe_type",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Swipe{}, "liked") {
+				return nil
+			}
+			if err := tx.Exec("UPDATE swipes SET type = CASE WHEN liked THEN 'like' ELSE 'pass' END").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Swipe{}, "liked")
+		},
+	},
+	{
+		// Both sides of a match share an ID, which used to be the primary key on its own,
+		// so the second side was never stored. Restore the missing sides, fold duplicate
+		// matches of the same pair into the oldest one and make pairs unique.
+		ID: "0003_match_room_pairs",
+		Up: func(tx *gorm.DB) error {
+			statements := []string{
+				"ALTER TABLE match_rooms DROP CONSTRAINT IF EXISTS match_rooms_pkey",
+				"ALTER TABLE match_rooms ADD PRIMARY KEY (id, user_id)",
+				`INSERT INTO match_rooms (id, user_id, target_user_id, created_at, updated_at, deleted_at)
+				SELECT m.id, m.target_user_id, m.user_id, m.created_at, m.updated_at, m.deleted_at
+				FROM match_rooms m
+				WHERE NOT EXISTS (SELECT 1 FROM match_rooms r WHERE r.id = m.id AND r.user_id = m.target_user_id)`,
+				`CREATE TEMPORARY TABLE match_room_keepers ON COMMIT DROP AS
+				SELECT DISTINCT id, FIRST_VALUE(id) OVER (
+					PARTITION BY LEAST(user_id, target_user_id), GREATEST(user_id, target_user_id)
+					ORDER BY created_at, id
+				) AS keep_id
+				FROM match_rooms
+				WHERE deleted_at IS NULL`,
+				`UPDATE messages SET match_room_id = k.keep_id
+				FROM match_room_keepers k
+				WHERE messages.match_room_id = k.id AND k.id <> k.keep_id`,
+				`UPDATE match_rooms SET deleted_at = NOW()
+				FROM match_room_keepers k
+				WHERE match_rooms.id = k.id AND k.id <> k.keep_id`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_match_rooms_pair ON match_rooms (user_id, target_user_id) WHERE deleted_at IS NULL",
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Keyset pagination of a room's messages, and syncing the ones changed since a time
+		ID: "0004_message_keyset_indexes",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_messages_room_created_at_id ON messages (match_room_id, created_at, id)").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_messages_room_updated_at ON messages (match_room_id, updated_at)").Error
+		},
+	},
+	{
+		// A match used to be a row per participant, both sharing the ID, and deleting a
+		// room only removed the caller's row. Move the participants and their read cursors
+		// to room_participants, close the rooms one participant had deleted and fold the
+		// rows of each room into one.
+		ID: "0005_match_room_participants",
+		Up: func(tx *gorm.DB) error {
+			// Read cursors came with user-040, rooms from before have none
+			readCursors := "NULL::uuid, NULL::timestamptz"
+			if tx.Migrator().HasColumn("match_rooms", "last_read_message_id") {
+				readCursors = "last_read_message_id, last_read_at"
+			}
+
+			statements := []string{
+				`INSERT INTO room_participants (match_room_id, user_id, last_read_message_id, last_read_at, created_at)
+				SELECT id, user_id, ` + readCursors + `, created_at FROM match_rooms
+				ON CONFLICT DO NOTHING`,
+				`UPDATE match_rooms SET deleted_at = closed.deleted_at
+				FROM (SELECT id, MIN(deleted_at) AS deleted_at FROM match_rooms WHERE deleted_at IS NOT NULL GROUP BY id) closed
+				WHERE match_rooms.id = closed.id AND match_rooms.deleted_at IS NULL`,
+				"DROP INDEX IF EXISTS idx_match_rooms_pair",
+				"DROP INDEX IF EXISTS idx_match_rooms_user_created_at",
+				// The participants point at the primary key being rebuilt
+				"ALTER TABLE room_participants DROP CONSTRAINT IF EXISTS fk_match_rooms_participants",
+				"ALTER TABLE match_rooms DROP CONSTRAINT IF EXISTS match_rooms_pkey",
+				"DELETE FROM match_rooms a USING match_rooms b WHERE a.id = b.id AND a.ctid > b.ctid",
+				"ALTER TABLE match_rooms ADD PRIMARY KEY (id)",
+				"ALTER TABLE room_participants ADD CONSTRAINT fk_match_rooms_participants FOREIGN KEY (match_room_id) REFERENCES match_rooms(id)",
+				`ALTER TABLE match_rooms
+				DROP COLUMN user_id,
+				DROP COLUMN target_user_id,
+				DROP COLUMN IF EXISTS last_read_message_id,
+				DROP COLUMN IF EXISTS last_read_at`,
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Icebreakers to start with, admins manage them from there
+		ID: "0006_icebreaker_templates",
+		Up: func(tx *gorm.DB) error {
+			templates := []models.IcebreakerTemplate{
+				{Source: models.IcebreakerInterest, Text: "You're into {interest} too! How did you get into it?", Active: true},
+				{Source: models.IcebreakerInterest, Text: "Okay {name}, settle this: what's the best thing about {interest}?", Active: true},
+				{Source: models.IcebreakerPrompt, Text: "\"{answer}\", I have to hear the story behind that one.", Active: true},
+				{Source: models.IcebreakerPrompt, Text: "Loved your answer to \"{prompt}\". What would mine have to be to impress you?", Active: true},
+				{Source: models.IcebreakerBio, Text: "Your bio says \"{bio}\", tell me more!", Active: true},
+				{Source: models.IcebreakerGeneric, Text: "Hi {name}! What's the best thing that happened to you this week?", Active: true},
+				{Source: models.IcebreakerGeneric, Text: "Two truths and a lie, {name}. You go first.", Active: true},
+			}
+			return tx.Create(&templates).Error
+		},
+	},
+	{
+		// A client retrying a swipe with the same key gets the swipe recorded the first
+		// time. 0003 used to create the index, databases that ran it have it already.
+		ID: "0007_swipe_idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_swipes_idempotency_key ON swipes (user_id, idempotency_key) WHERE idempotency_key <> ''").Error
+		},
+	},
+	{
+		// 0005 dropped the index keeping a pair to one open room along with the participant
+		// columns. Key the rooms by their pair again, fold the open rooms of a pair into the
+		// oldest one and make the key unique.
+		ID: "0008_match_room_pair_keys",
+		Up: func(tx *gorm.DB) error {
+			statements := []string{
+				`UPDATE match_rooms SET user_low_id = LEAST(a.user_id, b.user_id), user_high_id = GREATEST(a.user_id, b.user_id)
+				FROM room_participants a
+				JOIN room_participants b ON b.match_room_id = a.match_room_id AND b.user_id <> a.user_id
+				WHERE match_rooms.id = a.match_room_id`,
+				`CREATE TEMPORARY TABLE match_room_keepers ON COMMIT DROP AS
+				SELECT id, FIRST_VALUE(id) OVER (
+					PARTITION BY user_low_id, user_high_id
+					ORDER BY created_at, id
+				) AS keep_id
+				FROM match_rooms
+				WHERE deleted_at IS NULL AND user_low_id IS NOT NULL`,
+				`UPDATE messages SET match_room_id = k.keep_id
+				FROM match_room_keepers k
+				WHERE messages.match_room_id = k.id AND k.id <> k.keep_id`,
+				`UPDATE attachments SET match_room_id = k.keep_id
+				FROM match_room_keepers k
+				WHERE attachments.match_room_id = k.id AND k.id <> k.keep_id`,
+				`UPDATE match_rooms SET deleted_at = NOW()
+				FROM match_room_keepers k
+				WHERE match_rooms.id = k.id AND k.id <> k.keep_id`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_match_rooms_pair ON match_rooms (user_low_id, user_high_id) WHERE deleted_at IS NULL",
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// baselineMatchRoom is match_rooms as the first release created it, before any migration.
+type baselineMatchRoom struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	TargetUserID uuid.UUID `gorm:"type:uuid;not null"`
+	gorm.Model
+}
+
+func (baselineMatchRoom) TableName() string {
+	return "match_rooms"
+}
+
+// tables are the models whose tables AutoMigrate keeps up to date.
+var tables = []interface{}{
+	&models.User{},
+	&models.Profile{},
+	&models.Preference{},
+	&models.Deck{},
+	&models.DeckEntry{},
+	&models.QuotaCounter{},
+	&models.MatchRoom{},
+	&models.RoomParticipant{},
+	&models.Message{},
+	&models.MessageEdit{},
+	&models.MessageReaction{},
+	&models.Attachment{},
+	&models.Unmatch{},
+	&models.Block{},
+	&models.ModerationCase{},
+	&models.CaseNote{},
+	&models.Report{},
+	&models.ReportedMessage{},
+	&models.Swipe{},
+	&models.Boost{},
+	&models.ProfileImpression{},
+	&models.BotSignal{},
+	&models.IcebreakerTemplate{},
+	&models.IcebreakerSuggestion{},
+	&SchemaMigration{},
+}
+
+// Run brings the schema up to date: it auto-migrates the models and then applies every
+// pending migration in its own transaction.
+//
+// A new database starts out with the match_rooms of the first release, so it goes through
+// the same migrations as the databases created back then. Foreign keys are only created
+// once the migrations ran, as some of them rebuild the primary keys those point at.
+func Run(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&baselineMatchRoom{}) {
+		if err := db.Migrator().CreateTable(&baselineMatchRoom{}); err != nil {
+			return err
+		}
+	}
+
+	withoutKeys := db.Session(&gorm.Session{})
+	withoutKeys.DisableForeignKeyConstraintWhenMigrating = true
+	if err := withoutKeys.AutoMigrate(tables...); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&SchemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(tables...)
+}
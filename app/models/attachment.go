@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AttachmentImage = "image"
+	AttachmentVoice = "voice"
+)
+
+// Attachment is a file sent in a match room. It starts as an upload slot, is ready once
+// the file is uploaded and validated, and belongs to a message once it is sent.
+type Attachment struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	MatchRoomID uuid.UUID  `gorm:"type:uuid;not null;index" json:"match_room_id"`
+	UploaderID  uuid.UUID  `gorm:"type:uuid;not null" json:"uploader_id"`
+	MessageID   *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"message_id,omitempty"`
+	Kind        string     `gorm:"not null" json:"kind"`
+	// ContentType and Size are what the upload turned out to be, not what the client claimed
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	// Width and Height are set for images
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// DurationMs is set for voice notes
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// UploadedAt is nil while the slot waits for its file
+	UploadedAt *time.Time `json:"uploaded_at,omitempty"`
+	// UploadExpiresAt is when the slot stops accepting the file
+	UploadExpiresAt time.Time `gorm:"not null" json:"-"`
+	gorm.Model      `json:"-"`
+}
+
+func (attachment *Attachment) BeforeCreate(tx *gorm.DB) (err error) {
+	attachment.ID = uuid.New()
+	return
+}
+
+// HasThumbnail reports whether a thumbnail is stored next to the file.
+func (attachment *Attachment) HasThumbnail() bool {
+	return attachment.Kind == AttachmentImage
+}
+
+// UploadSlot is where a client uploads the file of an attachment before UploadExpiresAt.
+type UploadSlot struct {
+	AttachmentID    uuid.UUID `json:"attachment_id"`
+	UploadURL       string    `json:"upload_url"`
+	MaxSize         int64     `json:"max_size"`
+	UploadExpiresAt time.Time `json:"upload_expires_at"`
+}
+
+// AttachmentURLs are signed links to the file of an attachment, valid until ExpiresAt.
+type AttachmentURLs struct {
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
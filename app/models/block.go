@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Block keeps two users apart: neither sees the other in discovery, they cannot match and
+// a room they shared is closed.
+type Block struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_blocks_pair"`
+	BlockedUserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_blocks_pair;index"`
+	gorm.Model
+}
+
+func (block *Block) BeforeCreate(tx *gorm.DB) (err error) {
+	block.ID = uuid.New()
+	return
+}
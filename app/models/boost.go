@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Boost ranks a user's profile higher in everyone's discovery between StartsAt and EndsAt.
+type Boost struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	StartsAt time.Time `gorm:"not null"`
+	EndsAt   time.Time `gorm:"not null;index"`
+	gorm.Model
+}
+
+func (boost *Boost) BeforeCreate(tx *gorm.DB) (err error) {
+	boost.ID = uuid.New()
+	return
+}
+
+// ProfileImpression records that a profile was shown to a viewer in discovery.
+type ProfileImpression struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	ViewerID      uuid.UUID `gorm:"type:uuid;not null"`
+	ProfileUserID uuid.UUID `gorm:"type:uuid;not null;index:idx_profile_impressions_shown"`
+	ShownAt       time.Time `gorm:"not null;index:idx_profile_impressions_shown"`
+	gorm.Model
+}
+
+func (impression *ProfileImpression) BeforeCreate(tx *gorm.DB) (err error) {
+	impression.ID = uuid.New()
+	return
+}
+
+// BoostSummary reports how a boost performed. The extra counts are what the boost brought
+// on top of the views and likes the user usually gets in a window of the same length.
+type BoostSummary struct {
+	BoostID    uuid.UUID `json:"boost_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Active     bool      `json:"active"`
+	Views      int64     `json:"views"`
+	Likes      int64     `json:"likes"`
+	ExtraViews int64     `json:"extra_views"`
+	ExtraLikes int64     `json:"extra_likes"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BotLevel is how hard a user suspected of automated swiping is held back.
+type BotLevel string
+
+const (
+	BotLevelNone     BotLevel = "none"
+	BotLevelFriction BotLevel = "friction"
+	BotLevelThrottle BotLevel = "throttle"
+	BotLevelFlag     BotLevel = "flag"
+)
+
+const (
+	BotSignalSwipeRate  = "swipe_rate"
+	BotSignalLikeRatio  = "like_ratio"
+	BotSignalInterval   = "swipe_interval"
+	BotSignalRegularity = "swipe_regularity"
+)
+
+// BotSignal records a swipe that crossed one of the anti-bot thresholds, kept for review.
+// Level is where the user stood after the swipe, BotLevelFlag marking them for moderation.
+type BotSignal struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Signal    string    `gorm:"type:varchar(32);not null"`
+	Value     float64   `gorm:"not null"`
+	Threshold float64   `gorm:"not null"`
+	Level     BotLevel  `gorm:"type:varchar(16);not null;index"`
+	gorm.Model
+}
+
+func (signal *BotSignal) BeforeCreate(tx *gorm.DB) (err error) {
+	signal.ID = uuid.New()
+	return
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Deck is a user's precomputed discovery queue. GeneratedAt doubles as its version, so
+// cursors issued for an older deck can be recognised.
+type Deck struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID      uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	GeneratedAt time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	gorm.Model
+}
+
+func (deck *Deck) BeforeCreate(tx *gorm.DB) (err error) {
+	if deck.ID == uuid.Nil {
+		deck.ID = uuid.New()
+	}
+	return
+}
+
+// DeckEntry is a single candidate in a deck. Entries are removed once the owner swipes on them.
+type DeckEntry struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID          uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_deck_entries_candidate;index:idx_deck_entries_position"`
+	CandidateUserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_deck_entries_candidate"`
+	Position        int       `gorm:"not null;index:idx_deck_entries_position"`
+	gorm.Model
+}
+
+func (entry *DeckEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	entry.ID = uuid.New()
+	return
+}
+
+// ProfilePage is one page of discovery results.
+type ProfilePage struct {
+	Profiles   []Profile `json:"profiles"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
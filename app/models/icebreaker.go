@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IcebreakerSource is the profile data an icebreaker template is filled with.
+type IcebreakerSource string
+
+const (
+	// IcebreakerInterest templates take {interest}, one the pair share
+	IcebreakerInterest IcebreakerSource = "interest"
+	// IcebreakerPrompt templates take {prompt} and {answer}, a prompt the other user answered
+	IcebreakerPrompt IcebreakerSource = "prompt"
+	// IcebreakerBio templates take {bio}, an excerpt of the other user's bio
+	IcebreakerBio IcebreakerSource = "bio"
+	// IcebreakerGeneric templates need no profile data
+	IcebreakerGeneric IcebreakerSource = "generic"
+)
+
+func (s IcebreakerSource) Valid() bool {
+	switch s {
+	case IcebreakerInterest, IcebreakerPrompt, IcebreakerBio, IcebreakerGeneric:
+		return true
+	}
+	return false
+}
+
+// Placeholders are the placeholders a template of the source has to have one of, none for
+// generic ones. Any template may also take {name}, the other user's name.
+func (s IcebreakerSource) Placeholders() []string {
+	switch s {
+	case IcebreakerInterest:
+		return []string{"{interest}"}
+	case IcebreakerPrompt:
+		return []string{"{prompt}", "{answer}"}
+	case IcebreakerBio:
+		return []string{"{bio}"}
+	}
+	return nil
+}
+
+// IcebreakerTemplate is an opener that is suggested to a match once filled in with their
+// profiles. Inactive templates are kept for their stats but no longer suggested.
+type IcebreakerTemplate struct {
+	ID     uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	Source IcebreakerSource `gorm:"not null" json:"source"`
+	Text   string           `gorm:"not null" json:"text"`
+	Active bool             `gorm:"not null" json:"active"`
+	// Stats is how the template has done so far
+	Stats     IcebreakerStats `gorm:"-" json:"stats"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt gorm.DeletedAt  `gorm:"index" json:"-"`
+}
+
+func (template *IcebreakerTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if template.ID == uuid.Nil {
+		template.ID = uuid.New()
+	}
+	return
+}
+
+// IcebreakerStats counts the suggestions of a template, those sent as a message and those
+// the recipient answered.
+type IcebreakerStats struct {
+	TemplateID uuid.UUID `json:"-"`
+	Suggested  int64     `json:"suggested"`
+	Sent       int64     `json:"sent"`
+	Replied    int64     `json:"replied"`
+}
+
+// IcebreakerSuggestion is a template filled in for a user of a match room. Sending it is
+// recorded with the message, and so is the answer of the other participant.
+type IcebreakerSuggestion struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	TemplateID  uuid.UUID        `gorm:"type:uuid;not null;index" json:"template_id"`
+	MatchRoomID uuid.UUID        `gorm:"type:uuid;not null;index" json:"-"`
+	UserID      uuid.UUID        `gorm:"type:uuid;not null" json:"-"`
+	Source      IcebreakerSource `gorm:"not null" json:"source"`
+	Text        string           `gorm:"not null" json:"text"`
+	MessageID   *uuid.UUID       `gorm:"type:uuid" json:"-"`
+	SentAt      *time.Time       `json:"-"`
+	RepliedAt   *time.Time       `json:"-"`
+	CreatedAt   time.Time        `json:"-"`
+}
+
+func (suggestion *IcebreakerSuggestion) BeforeCreate(tx *gorm.DB) (err error) {
+	if suggestion.ID == uuid.Nil {
+		suggestion.ID = uuid.New()
+	}
+	return
+}
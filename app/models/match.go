@@ -1,13 +1,107 @@
 package models
 
 import (
+	"bytes"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// MatchRoom is a match between two users, its participants. Deleting it closes it for
+// both. A pair has one open room at a time, which a unique index on UserLowID and
+// UserHighID guarantees.
 type MatchRoom struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
-	UserID       uuid.UUID `gorm:"type:uuid;not null"`
-	TargetUserID uuid.UUID `gorm:"type:uuid;not null"`
+	ID           uuid.UUID         `gorm:"type:uuid;primaryKey"`
+	Participants []RoomParticipant `gorm:"foreignKey:MatchRoomID"`
+	// UserLowID and UserHighID are the participants in the order Postgres sorts uuids, as
+	// LEAST and GREATEST would put them, so a pair has the same key whoever matched
+	UserLowID  *uuid.UUID `gorm:"type:uuid" json:"-"`
+	UserHighID *uuid.UUID `gorm:"type:uuid" json:"-"`
+	// MatchedByID is the participant whose like made the match. Rooms matched before it was
+	// kept have none, and the conversation rules leave them be.
+	MatchedByID *uuid.UUID `gorm:"type:uuid" json:"matched_by_id"`
+	// FirstMessageAt is when FirstSenderID opened the conversation, and RepliedAt when the
+	// other participant answered
+	FirstMessageAt *time.Time `json:"first_message_at"`
+	FirstSenderID  *uuid.UUID `gorm:"type:uuid" json:"first_sender_id"`
+	RepliedAt      *time.Time `json:"replied_at"`
+	// Extended is set once a participant extended the window for the first message
+	Extended bool `gorm:"not null;default:false" json:"extended"`
+	// RemindedAt is when the participants were reminded the room is about to expire
+	RemindedAt *time.Time `json:"-"`
+	// ExpiredAt is when the room was closed for going unanswered
+	ExpiredAt *time.Time `json:"-"`
 	gorm.Model
 }
+
+// BeforeCreate keys the room by its pair of participants.
+func (room *MatchRoom) BeforeCreate(tx *gorm.DB) (err error) {
+	if len(room.Participants) != 2 {
+		return
+	}
+	low, high := room.Participants[0].UserID, room.Participants[1].UserID
+	if bytes.Compare(low[:], high[:]) > 0 {
+		low, high = high, low
+	}
+	room.UserLowID, room.UserHighID = &low, &high
+	return
+}
+
+// MatchExpiry is when a match room expires, or expired.
+type MatchExpiry struct {
+	MatchRoomID uuid.UUID `json:"match_room_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MatchCutoffs pick the match rooms that are due by a time: those matched before
+// FirstMessage, or before Extended when extended, that nobody wrote in, and those whose
+// first message, sent before Reply, went unanswered. A zero cutoff picks none.
+type MatchCutoffs struct {
+	FirstMessage time.Time
+	Extended     time.Time
+	Reply        time.Time
+}
+
+// RoomParticipant is a user taking part in a match room.
+type RoomParticipant struct {
+	MatchRoomID uuid.UUID `gorm:"type:uuid;primaryKey" json:"match_room_id"`
+	UserID      uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	// LastReadMessageID is the latest message this participant has read, and LastReadAt
+	// when they read it
+	LastReadMessageID *uuid.UUID `gorm:"type:uuid" json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// MessagePreview is the gist of the last message of a match room.
+type MessagePreview struct {
+	ID        uuid.UUID `json:"id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Content   string    `json:"content"`
+	Unsent    bool      `json:"unsent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MatchRoomSummary is a match room as listed to a participant, with what happened in it.
+type MatchRoomSummary struct {
+	MatchRoom
+	// TargetUserID is the other participant
+	TargetUserID uuid.UUID
+	LastMessage  *MessagePreview `json:"last_message"`
+	// UnreadCount is how many messages of the other participant came after the read cursor
+	UnreadCount    int64     `json:"unread_count"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// Presence is the other participant's, nil if they hide it
+	Presence *Presence `json:"presence"`
+	// ExpiresAt is when the room closes unless the conversation moves on, nil if it stays
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ReadReceipt tells the other participant how far a user has read.
+type ReadReceipt struct {
+	MatchRoomID uuid.UUID `json:"match_room_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	MessageID   uuid.UUID `json:"message_id"`
+	ReadAt      time.Time `json:"read_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -10,6 +12,24 @@ type Message struct {
 	MatchRoomID uuid.UUID `gorm:"type:uuid;not null"`
 	SenderID    uuid.UUID `gorm:"type:uuid;not null"`
 	Content     string    `gorm:"not null"`
+	// EditedAt is set once the sender edits the message, the earlier versions are kept as
+	// MessageEdits
+	EditedAt *time.Time
+	// UnsentAt is set when the sender unsent the message for everyone, which leaves a
+	// tombstone without content
+	UnsentAt  *time.Time
+	Reactions []MessageReaction `gorm:"foreignKey:MessageID"`
+	// Attachment is the image or voice note sent with the message, Content is its caption
+	Attachment *Attachment `gorm:"foreignKey:MessageID"`
+	// Screening is what screening found in the content, kept for moderation
+	Screening MessageScreening `gorm:"embedded;embeddedPrefix:screening_" json:"-"`
+	// HeldAt is set while the message is held for review, until then only its sender has it
+	HeldAt *time.Time `gorm:"index" json:"-"`
+	// UnwantedAt is set once the recipient marked the message as unwanted
+	UnwantedAt *time.Time `json:"-"`
+	// IcebreakerID is the icebreaker suggestion the message was sent from, recorded on the
+	// suggestion
+	IcebreakerID *uuid.UUID `gorm:"-" json:"-"`
 	gorm.Model
 }
 
@@ -17,3 +37,61 @@ func (message *Message) BeforeCreate(tx *gorm.DB) (err error) {
 	message.ID = uuid.New()
 	return
 }
+
+// MessageEdit is an earlier version of an edited message.
+type MessageEdit struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	MessageID uuid.UUID `gorm:"type:uuid;not null;index" json:"message_id"`
+	Content   string    `gorm:"not null" json:"content"`
+	// EditedAt is when this version was replaced
+	EditedAt time.Time `gorm:"not null" json:"edited_at"`
+}
+
+func (edit *MessageEdit) BeforeCreate(tx *gorm.DB) (err error) {
+	edit.ID = uuid.New()
+	return
+}
+
+// MessageReaction is the emoji a participant reacted to a message with, one per participant.
+type MessageReaction struct {
+	MessageID uuid.UUID `gorm:"type:uuid;primaryKey" json:"message_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	Emoji     string    `gorm:"not null" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageQuery selects a page of a match room's messages. Without a cursor the page holds
+// the latest messages.
+type MessageQuery struct {
+	// Before is the cursor of the page of older messages, listed newest first
+	Before string
+	// After is the cursor of the page of newer messages, listed oldest first
+	After string
+	Limit int
+}
+
+// MessagePage is one page of a match room's messages. NextCursor continues in the same
+// direction and is empty on the last page.
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// MessageUpdates are the messages of a match room that changed since a sync. Messages holds
+// the new, edited and reacted to ones, and tombstones of the unsent ones. Deleted holds the
+// IDs of the ones removed. A client passes the NextSince cursor to its next sync, right away
+// when HasMore is set.
+type MessageUpdates struct {
+	Messages  []Message   `json:"messages"`
+	Deleted   []uuid.UUID `json:"deleted"`
+	NextSince string      `json:"next_since"`
+	HasMore   bool        `json:"has_more"`
+}
+
+// ChangedAt is when the message was last created, edited or deleted.
+func (message *Message) ChangedAt() time.Time {
+	if message.DeletedAt.Valid && message.DeletedAt.Time.After(message.UpdatedAt) {
+		return message.DeletedAt.Time
+	}
+	return message.UpdatedAt
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CaseStatus is where a moderation case is in its review. A case is open until a
+// moderator takes it, in review while assigned and resolved once decided.
+type CaseStatus string
+
+const (
+	CaseOpen     CaseStatus = "open"
+	CaseInReview CaseStatus = "in_review"
+	CaseResolved CaseStatus = "resolved"
+)
+
+func (s CaseStatus) Valid() bool {
+	switch s {
+	case CaseOpen, CaseInReview, CaseResolved:
+		return true
+	}
+	return false
+}
+
+// CaseSource is what opened a moderation case or was added to it later.
+type CaseSource string
+
+const (
+	CaseSourceReport     CaseSource = "report"
+	CaseSourcePhoto      CaseSource = "photo"
+	CaseSourceBot        CaseSource = "bot_detection"
+	CaseSourceTextFilter CaseSource = "text_filter"
+	// CaseSourceUnwanted is a message its recipient marked as unwanted
+	CaseSourceUnwanted CaseSource = "unwanted_message"
+)
+
+// Valid reports whether s is one of the known case sources.
+func (s CaseSource) Valid() bool {
+	return s == CaseSourceReport || s == CaseSourcePhoto || s == CaseSourceBot || s == CaseSourceTextFilter || s == CaseSourceUnwanted
+}
+
+// CasePriority decides how soon a case is due.
+type CasePriority string
+
+const (
+	CasePriorityNormal CasePriority = "normal"
+	CasePriorityUrgent CasePriority = "urgent"
+)
+
+// CaseDecision is the outcome of a case and what it does to the user.
+type CaseDecision string
+
+const (
+	// DecisionDismiss finds nothing wrong, a profile hidden pending the review shows again
+	DecisionDismiss CaseDecision = "dismiss"
+	// DecisionWarn counts a warning against the user
+	DecisionWarn CaseDecision = "warn"
+	// DecisionRemoveContent clears the bio and photo of the profile
+	DecisionRemoveContent CaseDecision = "remove_content"
+	// DecisionSuspend keeps the user out and their profile hidden for a while
+	DecisionSuspend CaseDecision = "suspend"
+	// DecisionBan keeps the user out and their profile hidden for good
+	DecisionBan CaseDecision = "ban"
+)
+
+// Valid reports whether d is one of the known decisions.
+func (d CaseDecision) Valid() bool {
+	switch d {
+	case DecisionDismiss, DecisionWarn, DecisionRemoveContent, DecisionSuspend, DecisionBan:
+		return true
+	}
+	return false
+}
+
+// ModerationCase collects what is held against a user until a moderator decides on it. A
+// user has at most one unresolved case, later reports and flags are added to it.
+type ModerationCase struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	UserID     uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Source     CaseSource   `gorm:"not null;index" json:"source"`
+	Status     CaseStatus   `gorm:"not null;index" json:"status"`
+	Priority   CasePriority `gorm:"not null;default:'normal'" json:"priority"`
+	AssigneeID *uuid.UUID   `gorm:"type:uuid;index" json:"assignee_id"`
+	// DueAt is when the case breaches its SLA, it moves up when an urgent report comes in
+	DueAt        time.Time     `gorm:"not null;index" json:"due_at"`
+	Decision     *CaseDecision `json:"decision"`
+	DecidedByID  *uuid.UUID    `gorm:"type:uuid" json:"decided_by_id"`
+	ResolvedAt   *time.Time    `gorm:"index" json:"resolved_at"`
+	SuspendUntil *time.Time    `json:"suspend_until,omitempty"`
+	// ProfileHiddenAt is set when the profile was hidden from discovery pending the review
+	ProfileHiddenAt *time.Time `json:"profile_hidden_at"`
+	// ReporterCount is how many users reported the user on this case
+	ReporterCount int64          `gorm:"-" json:"reporter_count"`
+	Reports       []Report       `gorm:"foreignKey:CaseID" json:"reports,omitempty"`
+	Notes         []CaseNote     `gorm:"foreignKey:CaseID" json:"notes,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (moderationCase *ModerationCase) BeforeCreate(tx *gorm.DB) (err error) {
+	moderationCase.ID = uuid.New()
+	return
+}
+
+// Overdue reports whether the case breached its SLA at now.
+func (moderationCase *ModerationCase) Overdue(now time.Time) bool {
+	if moderationCase.ResolvedAt != nil {
+		return moderationCase.ResolvedAt.After(moderationCase.DueAt)
+	}
+	return now.After(moderationCase.DueAt)
+}
+
+// CaseNote is a remark on a case. Notes without an author are written by the system for
+// the flags added to the case, what moderators do with the case is logged as their notes.
+type CaseNote struct {
+	ID       uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	CaseID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"case_id"`
+	AuthorID *uuid.UUID `gorm:"type:uuid" json:"author_id"`
+	// Source is set on the notes of flags added to the case after it was opened
+	Source    CaseSource `json:"source,omitempty"`
+	Body      string     `gorm:"not null" json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (note *CaseNote) BeforeCreate(tx *gorm.DB) (err error) {
+	note.ID = uuid.New()
+	return
+}
+
+// CaseFilter selects the cases of the moderation queue.
+type CaseFilter struct {
+	Status     CaseStatus
+	Source     CaseSource
+	AssigneeID *uuid.UUID
+	// Unassigned only keeps the cases nobody took
+	Unassigned bool
+	// OverdueAt only keeps the unresolved cases due before it
+	OverdueAt time.Time
+}
+
+// CasePage is a page of the moderation queue, the soonest due first.
+type CasePage struct {
+	Cases      []ModerationCase `json:"cases"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// ModerationMetrics sums up the moderation queue.
+type ModerationMetrics struct {
+	Open       int64 `json:"open"`
+	InReview   int64 `json:"in_review"`
+	Unassigned int64 `json:"unassigned"`
+	Overdue    int64 `json:"overdue"`
+	// OldestOpenAt is when the longest waiting unresolved case was opened
+	OldestOpenAt *time.Time `json:"oldest_open_at"`
+	// BySource counts the unresolved cases by what opened them
+	BySource map[CaseSource]int64 `json:"by_source"`
+	// The cases resolved over the metrics window
+	Resolved            int64   `json:"resolved"`
+	ResolvedWithinSLA   int64   `json:"resolved_within_sla"`
+	AverageResolveHours float64 `json:"average_resolve_hours"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Preference describes who a user wants to see in discovery. Zero values mean "no preference".
+type Preference struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID        uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	Gender        string
+	MinAge        int
+	MaxAge        int
+	MaxDistanceKm float64
+	gorm.Model
+}
+
+func (preference *Preference) BeforeCreate(tx *gorm.DB) (err error) {
+	if preference.ID == uuid.Nil {
+		preference.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Presence is what others see of a user's activity: online now, or when they were last
+// active. Users who hide their presence have none.
+type Presence struct {
+	Online       bool      `json:"online"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+// UserActivity is when a user was last active and whether they let others see it.
+type UserActivity struct {
+	UserID       uuid.UUID
+	LastActiveAt time.Time
+	HidePresence bool
+}
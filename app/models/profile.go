@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -11,9 +13,35 @@ type Profile struct {
 	Name         string
 	Bio          string
 	ProfileImage string
+	Gender       string
+	BirthDate    time.Time
+	// Latitude and Longitude are never sent out, others only get to see DistanceKm
+	Latitude  float64  `json:"-"`
+	Longitude float64  `json:"-"`
+	Interests []string `gorm:"serializer:json"`
+	// Prompts are the prompts the user picked for their profile with their answers
+	Prompts []PromptAnswer `gorm:"serializer:json"`
+	Rating  float64        `gorm:"not null;default:1200" json:"-"`
+	// LastActiveAt is only shown through Presence, which respects the owner hiding it
+	LastActiveAt time.Time `gorm:"index" json:"-"`
+	// HiddenAt is set while the profile is kept out of discovery pending a moderation review
+	HiddenAt *time.Time `json:"-"`
+	// DistanceKm is how far the profile is from the viewer, rounded up to whole kilometres.
+	// It is nil when either of them has no location.
+	DistanceKm *int `gorm:"-"`
+	// SuperLikedYou is set on discovery results whose owner super liked the viewer
+	SuperLikedYou bool `gorm:"-"`
+	// Presence is filled in when a single profile is viewed, nil if its owner hides it
+	Presence *Presence `gorm:"-"`
 	gorm.Model
 }
 
+// PromptAnswer is a profile prompt, like "My ideal Sunday", with the user's answer.
+type PromptAnswer struct {
+	Prompt string `json:"prompt"`
+	Answer string `json:"answer"`
+}
+
 func (profile *Profile) BeforeCreate(tx *gorm.DB) (err error) {
 	profile.ID = uuid.New()
 	return
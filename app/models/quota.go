@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaDailySwipes     = "daily_swipes"
+	QuotaSuperLikes      = "super_likes"
+	QuotaBoosts          = "boosts"
+	QuotaRewinds         = "rewinds"
+	QuotaMatchExtensions = "match_extensions"
+)
+
+// QuotaCounter counts how much of a quota a user has used in one window.
+type QuotaCounter struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_quota_counters_window"`
+	Quota       string    `gorm:"not null;uniqueIndex:idx_quota_counters_window"`
+	WindowStart time.Time `gorm:"not null;uniqueIndex:idx_quota_counters_window"`
+	Used        int       `gorm:"not null"`
+	gorm.Model
+}
+
+func (counter *QuotaCounter) BeforeCreate(tx *gorm.DB) (err error) {
+	if counter.ID == uuid.Nil {
+		counter.ID = uuid.New()
+	}
+	return
+}
+
+// QuotaStatus is the state of one quota for a user in the current window.
+type QuotaStatus struct {
+	Name      string    `json:"name"`
+	Plan      string    `json:"plan"`
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	Unlimited bool      `json:"unlimited"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportCategory is what a user is reported for.
+type ReportCategory string
+
+const (
+	ReportFakeProfile   ReportCategory = "fake_profile"
+	ReportHarassment    ReportCategory = "harassment"
+	ReportInappropriate ReportCategory = "inappropriate_content"
+	ReportSpam          ReportCategory = "spam"
+	ReportScam          ReportCategory = "scam"
+	ReportUnderage      ReportCategory = "underage"
+	ReportOther         ReportCategory = "other"
+)
+
+// Valid reports whether c is one of the known report categories.
+func (c ReportCategory) Valid() bool {
+	switch c {
+	case ReportFakeProfile, ReportHarassment, ReportInappropriate, ReportSpam, ReportScam, ReportUnderage, ReportOther:
+		return true
+	}
+	return false
+}
+
+// Urgent reports whether reports in c put the safety of users at stake, which makes their
+// case urgent.
+func (c ReportCategory) Urgent() bool {
+	return c == ReportHarassment || c == ReportUnderage || c == ReportScam
+}
+
+// Report is a complaint of one user about another, filed on the moderation case of the
+// reported user.
+type Report struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CaseID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"-"`
+	ReporterID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"reporter_id"`
+	ReportedUserID uuid.UUID      `gorm:"type:uuid;not null;index" json:"reported_user_id"`
+	Category       ReportCategory `gorm:"not null" json:"category"`
+	Details        string         `json:"details"`
+	// Messages are copies of the messages the report refers to, kept even once the sender
+	// unsends them or they are purged
+	Messages   []ReportedMessage `gorm:"foreignKey:ReportID" json:"messages"`
+	gorm.Model `json:"-"`
+}
+
+func (report *Report) BeforeCreate(tx *gorm.DB) (err error) {
+	report.ID = uuid.New()
+	return
+}
+
+// ReportedMessage is a message as it was when it was reported.
+type ReportedMessage struct {
+	ReportID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	MessageID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"message_id"`
+	MatchRoomID uuid.UUID `gorm:"type:uuid;not null" json:"match_room_id"`
+	SenderID    uuid.UUID `gorm:"type:uuid;not null" json:"sender_id"`
+	Content     string    `json:"content"`
+	SentAt      time.Time `json:"sent_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// ScreeningVerdict is what screening made of the content of a message.
+type ScreeningVerdict string
+
+const (
+	ScreeningDeliver ScreeningVerdict = "deliver"
+	// ScreeningNudge delivers the message, asking its sender whether they meant to send it
+	ScreeningNudge ScreeningVerdict = "nudge"
+	// ScreeningHold keeps the message from the recipient until a moderator releases it
+	ScreeningHold ScreeningVerdict = "hold"
+)
+
+// MessageScreening is what the screening stages found in a message, kept with it.
+type MessageScreening struct {
+	Verdict ScreeningVerdict `gorm:"type:varchar(16);not null;default:'deliver'" json:"verdict"`
+	Score   int              `gorm:"not null;default:0" json:"score"`
+	// Flags name what was found, such as "profanity" or "phone_number"
+	Flags []string `gorm:"serializer:json" json:"flags,omitempty"`
+}
@@ -1,15 +1,39 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+type SwipeType string
+
+const (
+	SwipePass      SwipeType = "pass"
+	SwipeLike      SwipeType = "like"
+	SwipeSuperLike SwipeType = "super_like"
+)
+
+// Valid reports whether t is one of the known swipe types.
+func (t SwipeType) Valid() bool {
+	return t == SwipePass || t == SwipeLike || t == SwipeSuperLike
+}
+
+// IsLike reports whether t counts as a like, which a super like does too.
+func (t SwipeType) IsLike() bool {
+	return t == SwipeLike || t == SwipeSuperLike
+}
+
 type Swipe struct {
 	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
 	UserID       uuid.UUID `gorm:"type:uuid;not null"`
-	TargetUserID uuid.UUID `gorm:"type:uuid;not null"`
-	Liked        bool      `gorm:"not null"`
+	TargetUserID uuid.UUID `gorm:"type:uuid;not null;index:idx_swipes_target_type"`
+	Type         SwipeType `gorm:"type:varchar(16);not null;default:'pass';index:idx_swipes_target_type"`
+	// IdempotencyKey lets a client retry a swipe without recording it twice
+	IdempotencyKey string `gorm:"type:varchar(255);not null;default:''"`
+	// RatingChange is how much the swipe moved the rating of the target, undone on a rewind
+	RatingChange float64 `gorm:"not null;default:0"`
 	gorm.Model
 }
 
@@ -17,3 +41,41 @@ func (swipe *Swipe) BeforeCreate(tx *gorm.DB) (err error) {
 	swipe.ID = uuid.New()
 	return
 }
+
+// SwipeResult is the outcome of a swipe. Match is set when the swipe completed a mutual
+// like, and SuperLikeMatch when either side of that match was a super like. Replayed is
+// set when the swipe was a retry of one already recorded under the same idempotency key.
+type SwipeResult struct {
+	Match          *MatchRoom
+	SuperLikeMatch bool
+	Replayed       bool
+}
+
+// ReceivedLike is a like from someone the user has not swiped on yet.
+type ReceivedLike struct {
+	Profile Profile   `json:"profile"`
+	Type    SwipeType `json:"type"`
+	LikedAt time.Time `json:"liked_at"`
+}
+
+// SwipedProfile is a profile the user swiped on, as listed in their swipe history.
+type SwipedProfile struct {
+	Profile  Profile   `json:"profile"`
+	Type     SwipeType `json:"type"`
+	SwipedAt time.Time `json:"swiped_at"`
+}
+
+// SwipeHistoryPage is one page of a user's swipes, newest first.
+type SwipeHistoryPage struct {
+	Swipes     []SwipedProfile `json:"swipes"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// LikesPage is one page of the likes a user received. Free users only get the count,
+// with Blurred set and no likes.
+type LikesPage struct {
+	Count      int64          `json:"count"`
+	Blurred    bool           `json:"blurred"`
+	Likes      []ReceivedLike `json:"likes"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UnmatchReason is why a participant unmatched.
+type UnmatchReason string
+
+const (
+	UnmatchUnspecified   UnmatchReason = "unspecified"
+	UnmatchNotInterested UnmatchReason = "not_interested"
+	UnmatchNoResponse    UnmatchReason = "no_response"
+	UnmatchInappropriate UnmatchReason = "inappropriate"
+	UnmatchFakeProfile   UnmatchReason = "fake_profile"
+	UnmatchMetSomeone    UnmatchReason = "met_someone"
+	UnmatchOther         UnmatchReason = "other"
+	// UnmatchBlocked is recorded when a block closes the room, it cannot be given
+	UnmatchBlocked UnmatchReason = "blocked"
+)
+
+// Valid reports whether r is one of the known unmatch reasons.
+func (r UnmatchReason) Valid() bool {
+	switch r {
+	case UnmatchUnspecified, UnmatchNotInterested, UnmatchNoResponse, UnmatchInappropriate, UnmatchFakeProfile, UnmatchMetSomeone, UnmatchOther:
+		return true
+	}
+	return false
+}
+
+// Unmatch records that UserID closed the match room they had with TargetUserID. The pair
+// never matches again. The messages of the room are kept for moderation until they are
+// purged after the retention period.
+type Unmatch struct {
+	ID               uuid.UUID     `gorm:"type:uuid;primary_key" json:"id"`
+	MatchRoomID      uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex" json:"match_room_id"`
+	UserID           uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	TargetUserID     uuid.UUID     `gorm:"type:uuid;not null;index" json:"target_user_id"`
+	Reason           UnmatchReason `gorm:"not null" json:"reason"`
+	UnmatchedAt      time.Time     `gorm:"not null;index" json:"unmatched_at"`
+	MessagesPurgedAt *time.Time    `json:"-"`
+	gorm.Model       `json:"-"`
+}
+
+func (unmatch *Unmatch) BeforeCreate(tx *gorm.DB) (err error) {
+	unmatch.ID = uuid.New()
+	return
+}
+
+// UnmatchReasonCount is how many unmatches gave a reason.
+type UnmatchReasonCount struct {
+	Reason UnmatchReason `json:"reason"`
+	Count  int64         `json:"count"`
+}
+
+// UnmatchReasonReport breaks the unmatches between From and To down by reason, the most
+// common first.
+type UnmatchReasonReport struct {
+	From    time.Time            `json:"from"`
+	To      time.Time            `json:"to"`
+	Total   int64                `json:"total"`
+	Reasons []UnmatchReasonCount `json:"reasons"`
+}
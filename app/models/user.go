@@ -7,6 +7,12 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID                uuid.UUID `gorm:"type:uuid;primary_key"`
 	Username          string    `gorm:"uniqueIndex;not null"`
@@ -14,6 +20,15 @@ type User struct {
 	Password          string    `gorm:"not null"`
 	IsPremium         bool
 	PremiumExpiryTime time.Time
+	Timezone          string `gorm:"not null;default:'UTC'"`
+	// HidePresence keeps others from seeing when the user is online or was last active
+	HidePresence bool `gorm:"not null;default:false"`
+	// Role grants access to the admin API, it is only ever changed in the database
+	Role string `gorm:"not null;default:'user'"`
+	// Warnings counts the moderation cases decided with a warning against the user
+	Warnings       int `gorm:"not null;default:0"`
+	SuspendedUntil *time.Time
+	BannedAt       *time.Time
 	gorm.Model
 }
 
@@ -21,3 +36,24 @@ func (user *User) BeforeCreate(tx *gorm.DB) (err error) {
 	user.ID = uuid.New()
 	return
 }
+
+// HasPremium reports whether the user's premium subscription is active at now. A premium
+// user without an expiry time never expires.
+func (user *User) HasPremium(now time.Time) bool {
+	return user.IsPremium && (user.PremiumExpiryTime.IsZero() || user.PremiumExpiryTime.After(now))
+}
+
+// HasRole reports whether the user has one of roles.
+func (user *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Barred reports whether the user is banned or suspended at now.
+func (user *User) Barred(now time.Time) bool {
+	return user.BannedAt != nil || (user.SuspendedUntil != nil && user.SuspendedUntil.After(now))
+}
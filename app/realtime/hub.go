@@ -0,0 +1,355 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// sendBuffer is how many events a connection may fall behind before it is dropped
+	sendBuffer = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Connections are authenticated with a token rather than cookies, so any origin is fine
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// subscriber is what the hub delivers the events of a channel to, a WebSocket connection or
+// the stream session of a user.
+type subscriber interface {
+	enqueue(payload []byte)
+}
+
+// Hub is a RealtimePublisher serving events to WebSocket connections and event streams.
+// Each of them is subscribed to the channel of its user and the channels of the user's
+// match rooms. Events go through the backplane, so they reach every instance.
+type Hub struct {
+	matchRepo repository.MatchRepository
+	backplane Backplane
+	presence  PresenceTracker
+	cfg       config.RealtimeConfig
+	mu        sync.RWMutex
+	channels  map[string]map[subscriber]struct{}
+	// subscriptions are the channels of each subscriber
+	subscriptions map[subscriber][]string
+	sessionsMu    sync.Mutex
+	sessions      map[uuid.UUID]*session
+}
+
+// NewHub creates a hub. Without a backplane events only reach this instance, without a
+// presence tracker the activity of connected users is not recorded.
+func NewHub(matchRepo repository.MatchRepository, backplane Backplane, presence PresenceTracker, cfg config.RealtimeConfig) *Hub {
+	return &Hub{
+		matchRepo:     matchRepo,
+		backplane:     backplane,
+		presence:      presence,
+		cfg:           cfg,
+		channels:      make(map[string]map[subscriber]struct{}),
+		subscriptions: make(map[subscriber][]string),
+		sessions:      make(map[uuid.UUID]*session),
+	}
+}
+
+func (h *Hub) Publish(channel, event string, data interface{}) error {
+	payload, err := json.Marshal(Event{Channel: channel, Event: event, Data: data})
+	if err != nil {
+		return err
+	}
+
+	if h.backplane == nil {
+		h.deliver(payload)
+		return nil
+	}
+	return h.backplane.Publish(payload)
+}
+
+// Run delivers the events published on any instance and expires the idle stream sessions
+// until ctx is done.
+func (h *Hub) Run(ctx context.Context) error {
+	go h.expireSessions(ctx)
+
+	if h.backplane == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return h.backplane.Listen(ctx, h.deliver)
+}
+
+// Connect upgrades the request to a WebSocket connection of userID, who must already be
+// authenticated.
+func (h *Hub) Connect(w http.ResponseWriter, r *http.Request, userID uuid.UUID) error {
+	rooms, err := h.matchRepo.GetMatchRooms(userID)
+	if err != nil {
+		return err
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+
+	c := &client{hub: h, conn: conn, userID: userID, send: make(chan []byte, sendBuffer), done: make(chan struct{})}
+	h.subscribeUser(c, userID, rooms)
+	h.Seen(userID)
+
+	go c.writePump()
+	go c.readPump()
+	return nil
+}
+
+// Seen records that userID is active, from a heartbeat of their connection or stream.
+func (h *Hub) Seen(userID uuid.UUID) {
+	if h.presence == nil {
+		return
+	}
+	if err := h.presence.Touch(userID, time.Now()); err != nil {
+		log.Printf("realtime: recording the activity of %s failed: %v", userID, err)
+	}
+}
+
+// delivered is an event as it comes off the backplane, its data left undecoded.
+type delivered struct {
+	Channel string          `json:"channel"`
+	Event   string          `json:"event"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (h *Hub) deliver(payload []byte) {
+	var event delivered
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return
+	}
+
+	h.mu.RLock()
+	subscribers := make([]subscriber, 0, len(h.channels[event.Channel]))
+	for s := range h.channels[event.Channel] {
+		s.enqueue(payload)
+		subscribers = append(subscribers, s)
+	}
+	h.mu.RUnlock()
+
+	// Follow the user into the rooms they match and out of the rooms that are unmatched or expire
+	switch event.Event {
+	case EventNewMatch, EventSuperLikeMatch:
+		var data MatchData
+		if err := json.Unmarshal(event.Data, &data); err != nil || data.MatchRoomID == uuid.Nil {
+			return
+		}
+		for _, s := range subscribers {
+			h.subscribe(s, MatchRoomChannel(data.MatchRoomID))
+		}
+	case EventUnmatch, EventMatchExpired:
+		h.closeChannel(event.Channel)
+	}
+}
+
+// subscribeUser subscribes s to the channel of userID and the channels of their rooms.
+func (h *Hub) subscribeUser(s subscriber, userID uuid.UUID, rooms []models.MatchRoom) {
+	h.subscribe(s, UserChannel(userID))
+	for _, room := range rooms {
+		h.subscribe(s, MatchRoomChannel(room.ID))
+	}
+}
+
+func (h *Hub) subscribe(s subscriber, channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.channels[channel] == nil {
+		h.channels[channel] = make(map[subscriber]struct{})
+	}
+	if _, ok := h.channels[channel][s]; ok {
+		return
+	}
+	h.channels[channel][s] = struct{}{}
+	h.subscriptions[s] = append(h.subscriptions[s], channel)
+}
+
+func (h *Hub) unsubscribe(s subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, channel := range h.subscriptions[s] {
+		delete(h.channels[channel], s)
+		if len(h.channels[channel]) == 0 {
+			delete(h.channels, channel)
+		}
+	}
+	delete(h.subscriptions, s)
+}
+
+// closeChannel unsubscribes everyone from channel.
+func (h *Hub) closeChannel(channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.channels[channel] {
+		kept := h.subscriptions[s][:0]
+		for _, subscribed := range h.subscriptions[s] {
+			if subscribed != channel {
+				kept = append(kept, subscribed)
+			}
+		}
+		h.subscriptions[s] = kept
+	}
+	delete(h.channels, channel)
+}
+
+func (h *Hub) isSubscribed(s subscriber, channel string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.channels[channel][s]
+	return ok
+}
+
+// isMember reports whether userID takes part in the match room.
+func (h *Hub) isMember(userID, matchRoomID uuid.UUID) (bool, error) {
+	rooms, err := h.matchRepo.GetMatchRooms(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, room := range rooms {
+		if room.ID == matchRoomID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID uuid.UUID
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+// request is what clients send: a ping, a subscription to a match room created after they
+// connected, or whether they are typing in a match room.
+type request struct {
+	Action      string    `json:"action"`
+	MatchRoomID uuid.UUID `json:"match_room_id"`
+}
+
+// enqueue queues payload without blocking the hub, dropping the connection if it has
+// fallen too far behind.
+func (c *client) enqueue(payload []byte) {
+	select {
+	case c.send <- payload:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unsubscribe(c)
+		c.close()
+	}()
+
+	c.conn.SetReadLimit(1024)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.hub.Seen(c.userID)
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(message, &req); err != nil {
+			c.reply("error", map[string]string{"error": "invalid request"})
+			continue
+		}
+
+		c.hub.Seen(c.userID)
+
+		switch req.Action {
+		case "ping":
+			c.reply("pong", nil)
+		case EventTypingStarted, EventTypingStopped:
+			// Being subscribed to the room means taking part in it
+			channel := MatchRoomChannel(req.MatchRoomID)
+			if !c.hub.isSubscribed(c, channel) {
+				c.reply("error", map[string]string{"error": "match room not found"})
+				continue
+			}
+			if err := c.hub.Publish(channel, req.Action, TypingData{MatchRoomID: req.MatchRoomID, UserID: c.userID}); err != nil {
+				c.reply("error", map[string]string{"error": "typing could not be relayed"})
+			}
+		case "subscribe":
+			member, err := c.hub.isMember(c.userID, req.MatchRoomID)
+			if err != nil || !member {
+				c.reply("error", map[string]string{"error": "match room not found"})
+				continue
+			}
+			channel := MatchRoomChannel(req.MatchRoomID)
+			c.hub.subscribe(c, channel)
+			c.reply("subscribed", map[string]string{"channel": channel})
+		default:
+			c.reply("error", map[string]string{"error": "unknown action"})
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// reply sends an event to this connection only.
+func (c *client) reply(event string, data interface{}) {
+	payload, err := json.Marshal(Event{Event: event, Data: data})
+	if err == nil {
+		c.enqueue(payload)
+	}
+}
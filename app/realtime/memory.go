@@ -0,0 +1,32 @@
+package realtime
+
+import (
+	"sync"
+)
+
+// MemoryPublisher keeps published events in memory instead of sending them anywhere, for
+// tests and for running the service without a realtime provider.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(channel, event string, data interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, Event{Channel: channel, Event: event, Data: data})
+	return nil
+}
+
+// Events returns the events published so far, oldest first.
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event(nil), p.events...)
+}
@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// Backplane shares published events between the instances of the service.
+type Backplane interface {
+	// Publish sends payload to every instance, including this one.
+	Publish(payload []byte) error
+	// Listen calls deliver with every payload published until ctx is done.
+	Listen(ctx context.Context, deliver func(payload []byte)) error
+}
+
+const reconnectDelay = 5 * time.Second
+
+type postgresBackplane struct {
+	db      *gorm.DB
+	dsn     string
+	channel string
+}
+
+// NewPostgresBackplane shares events through Postgres LISTEN/NOTIFY. Notifications are
+// sent over the regular pool, and received on a dedicated connection opened from dsn.
+// Postgres limits a payload to 8000 bytes.
+func NewPostgresBackplane(db *gorm.DB, dsn, channel string) Backplane {
+	return &postgresBackplane{db: db, dsn: dsn, channel: channel}
+}
+
+func (b *postgresBackplane) Publish(payload []byte) error {
+	return b.db.Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error
+}
+
+func (b *postgresBackplane) Listen(ctx context.Context, deliver func(payload []byte)) error {
+	for {
+		err := b.listen(ctx, deliver)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Events published while reconnecting are lost, clients catch up through the API
+		log.Printf("realtime: listening on %s failed, reconnecting: %v", b.channel, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (b *postgresBackplane) listen(ctx context.Context, deliver func(payload []byte)) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		deliver([]byte(notification.Payload))
+	}
+}
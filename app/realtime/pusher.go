@@ -0,0 +1,18 @@
+package realtime
+
+import (
+	"github.com/pusher/pusher-http-go"
+)
+
+type pusherPublisher struct {
+	client *pusher.Client
+}
+
+// NewPusherPublisher publishes events through Pusher Channels.
+func NewPusherPublisher(client *pusher.Client) RealtimePublisher {
+	return &pusherPublisher{client: client}
+}
+
+func (p *pusherPublisher) Publish(channel, event string, data interface{}) error {
+	return p.client.Trigger(channel, event, data)
+}
@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+)
+
+// Events published on the channels below.
+const (
+	EventNewMessage        = "new_message"
+	EventNewMatch          = "new_match"
+	EventSuperLikeMatch    = "super_like_match"
+	EventSuperLikeReceived = "super_like_received"
+	EventUnmatch           = "unmatch"
+	EventRead              = "read"
+	EventMessageEdited     = "message_edited"
+	EventMessageUnsent     = "message_unsent"
+	EventReactionAdded     = "reaction_added"
+	EventReactionRemoved   = "reaction_removed"
+	EventModerationAction  = "moderation_action"
+	EventMatchExtended     = "match_extended"
+	EventMatchExpiring     = "match_expiring"
+	EventMatchExpired      = "match_expired"
+	// Typing events are relayed as they come and never kept. Clients repeat typing_started
+	// every few seconds while typing, so an indicator is dropped when they stop coming.
+	EventTypingStarted = "typing_started"
+	EventTypingStopped = "typing_stopped"
+)
+
+// ephemeral reports whether an event is only of interest as it happens, so it is not kept
+// for event streams to replay.
+func ephemeral(event string) bool {
+	return event == EventTypingStarted || event == EventTypingStopped
+}
+
+// RealtimePublisher pushes events to the clients subscribed to a channel.
+type RealtimePublisher interface {
+	Publish(channel, event string, data interface{}) error
+}
+
+type multiPublisher []RealtimePublisher
+
+// NewMultiPublisher publishes every event through each of publishers in turn, e.g. to
+// Pusher and the built-in hub, returning the first error once all of them were tried.
+func NewMultiPublisher(publishers ...RealtimePublisher) RealtimePublisher {
+	return multiPublisher(publishers)
+}
+
+func (m multiPublisher) Publish(channel, event string, data interface{}) error {
+	var first error
+	for _, publisher := range m {
+		if err := publisher.Publish(channel, event, data); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Event is what subscribers of a channel receive.
+type Event struct {
+	Channel string      `json:"channel"`
+	Event   string      `json:"event"`
+	Data    interface{} `json:"data"`
+}
+
+// MatchData is the data of the events about a match. Match events on a user channel
+// subscribe the user's connections to the match room, an unmatch unsubscribes them, as
+// does the match_expired event of a room that expired.
+type MatchData struct {
+	MatchRoomID uuid.UUID `json:"match_room_id"`
+	UserID      uuid.UUID `json:"user_id"`
+}
+
+// TypingData is the data of the typing events of a match room.
+type TypingData struct {
+	MatchRoomID uuid.UUID `json:"match_room_id"`
+	UserID      uuid.UUID `json:"user_id"`
+}
+
+// ModerationData is the data of a moderation action taken on the user of a user channel.
+type ModerationData struct {
+	Decision       models.CaseDecision `json:"decision"`
+	SuspendedUntil *time.Time          `json:"suspended_until,omitempty"`
+}
+
+// PresenceTracker records that connected users are active.
+type PresenceTracker interface {
+	Touch(userID uuid.UUID, now time.Time) error
+}
+
+// UserChannel carries the events of a single user, like a new match.
+func UserChannel(userID uuid.UUID) string {
+	return "user_" + userID.String()
+}
+
+// MatchRoomChannel carries the events of a match room, like a new message.
+func MatchRoomChannel(matchRoomID uuid.UUID) string {
+	return "chat_room_" + matchRoomID.String()
+}
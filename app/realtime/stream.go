@@ -0,0 +1,200 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamEvent is an event as it is sent on an event stream. IDs only grow, so a client
+// resumes by giving back the last one it received.
+type StreamEvent struct {
+	// ID is zero for ephemeral events, which cannot be resumed from
+	ID    int64
+	Event string
+	// Payload is the whole Event, JSON encoded
+	Payload json.RawMessage
+}
+
+// Stream is an open event stream of a user.
+type Stream struct {
+	// Replay are the events missed since the Last-Event-ID the stream was opened with
+	Replay []StreamEvent
+	// Reset is set when the events since Last-Event-ID are no longer kept, so the client
+	// has to reload what it shows instead of catching up
+	Reset bool
+
+	session *session
+	events  chan StreamEvent
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Events are the events that arrive while the stream is open.
+func (s *Stream) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Done is closed when the stream is closed, or dropped for falling too far behind.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Stream) Close() {
+	s.session.mu.Lock()
+	defer s.session.mu.Unlock()
+	s.session.detach(s)
+}
+
+// session keeps the latest events of a user for their streams to resume from, it outlives
+// the streams by the replay retention.
+type session struct {
+	bufferSize int
+	mu         sync.Mutex
+	buffer     []StreamEvent
+	lastID     int64
+	// horizon is the ID up to which events may have been evicted
+	horizon   int64
+	streams   map[*Stream]struct{}
+	idleSince time.Time
+}
+
+func newSession(bufferSize int, now time.Time) *session {
+	return &session{bufferSize: bufferSize, horizon: now.UnixMicro(), streams: make(map[*Stream]struct{}), idleSince: now}
+}
+
+// enqueue numbers the event, keeps it for replay and passes it on to the open streams.
+func (s *session) enqueue(payload []byte) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ephemeral(event.Event) {
+		// Without an ID, so a client resumes from the last event that is kept
+		for stream := range s.streams {
+			select {
+			case stream.events <- StreamEvent{Event: event.Event, Payload: payload}:
+			default:
+				s.detach(stream)
+			}
+		}
+		return
+	}
+
+	// IDs are timestamps, so they keep growing when the client resumes on another instance
+	id := time.Now().UnixMicro()
+	if id <= s.lastID {
+		id = s.lastID + 1
+	}
+	s.lastID = id
+	streamEvent := StreamEvent{ID: id, Event: event.Event, Payload: payload}
+
+	s.buffer = append(s.buffer, streamEvent)
+	if evicted := len(s.buffer) - s.bufferSize; evicted > 0 {
+		s.horizon = s.buffer[evicted-1].ID
+		s.buffer = append(s.buffer[:0], s.buffer[evicted:]...)
+	}
+
+	for stream := range s.streams {
+		select {
+		case stream.events <- streamEvent:
+		default:
+			// The client resumes from its last event when it reconnects
+			s.detach(stream)
+		}
+	}
+}
+
+// attach opens a stream resuming after lastEventID, or from now without one.
+func (s *session) attach(lastEventID string) *Stream {
+	stream := &Stream{session: s, events: make(chan StreamEvent, sendBuffer), done: make(chan struct{})}
+
+	if lastEventID != "" {
+		after, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || after < s.horizon {
+			stream.Reset = true
+		} else {
+			for _, event := range s.buffer {
+				if event.ID > after {
+					stream.Replay = append(stream.Replay, event)
+				}
+			}
+		}
+	}
+
+	s.streams[stream] = struct{}{}
+	return stream
+}
+
+// detach must be called with the session locked.
+func (s *session) detach(stream *Stream) {
+	stream.once.Do(func() {
+		close(stream.done)
+		delete(s.streams, stream)
+		if len(s.streams) == 0 {
+			s.idleSince = time.Now()
+		}
+	})
+}
+
+// OpenStream opens an event stream of userID, who must already be authenticated. The stream
+// gets the events of the user's channel and match rooms, resuming after lastEventID if given.
+func (h *Hub) OpenStream(userID uuid.UUID, lastEventID string) (*Stream, error) {
+	h.sessionsMu.Lock()
+	defer h.sessionsMu.Unlock()
+
+	s, ok := h.sessions[userID]
+	if !ok {
+		rooms, err := h.matchRepo.GetMatchRooms(userID)
+		if err != nil {
+			return nil, err
+		}
+		s = newSession(h.cfg.ReplayBufferSize, time.Now())
+		h.sessions[userID] = s
+		h.subscribeUser(s, userID, rooms)
+	}
+
+	h.Seen(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attach(lastEventID), nil
+}
+
+// expireSessions forgets the events of the users whose streams have been closed for longer
+// than the replay retention.
+func (h *Hub) expireSessions(ctx context.Context) {
+	interval := h.cfg.ReplayRetention
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.sessionsMu.Lock()
+			for userID, s := range h.sessions {
+				s.mu.Lock()
+				expired := len(s.streams) == 0 && now.Sub(s.idleSince) >= h.cfg.ReplayRetention
+				s.mu.Unlock()
+				if expired {
+					delete(h.sessions, userID)
+					h.unsubscribe(s)
+				}
+			}
+			h.sessionsMu.Unlock()
+		}
+	}
+}
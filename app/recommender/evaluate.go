@@ -0,0 +1,171 @@
+package recommender
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+// Evaluation summarises an offline replay of historical swipes.
+type Evaluation struct {
+	Sessions      int
+	Swipes        int
+	Likes         int
+	TopK          int
+	TopKSwipes    int
+	TopKLikes     int
+	BaselineRate  float64
+	TopKLikeRate  float64
+	Lift          float64
+	SkippedNoData int
+}
+
+type session struct {
+	userID uuid.UUID
+	swipes []models.Swipe
+}
+
+// Evaluate replays swipes in chronological order, grouped into per-user daily sessions.
+// For every session the swiped profiles are ranked with the scorer, using desirability
+// ratings and activity as they stood before the session, and the like-rate of the top k is
+// compared against the like-rate of the whole session.
+//
+// Profiles only keep when their user was last active, so activity is rebuilt from the
+// swipes: a candidate was last active at their latest swipe before the session, or at
+// LastActiveAt when that came later but still before the session.
+func Evaluate(profiles []models.Profile, preferences []models.Preference, swipes []models.Swipe, cfg config.RecommenderConfig, k int) Evaluation {
+	scorer := NewScorer(cfg)
+
+	profileByUser := make(map[uuid.UUID]models.Profile, len(profiles))
+	ratings := make(map[uuid.UUID]float64, len(profiles))
+	lastActive := make(map[uuid.UUID]time.Time, len(profiles))
+	for _, profile := range profiles {
+		profileByUser[profile.UserID] = profile
+		ratings[profile.UserID] = DefaultRating
+	}
+
+	preferenceByUser := make(map[uuid.UUID]*models.Preference, len(preferences))
+	for i := range preferences {
+		preferenceByUser[preferences[i].UserID] = &preferences[i]
+	}
+
+	sort.SliceStable(swipes, func(i, j int) bool {
+		return swipes[i].CreatedAt.Before(swipes[j].CreatedAt)
+	})
+
+	result := Evaluation{TopK: k}
+
+	var sessions []*session
+	open := make(map[uuid.UUID]*session)
+	for _, swipe := range swipes {
+		current := open[swipe.UserID]
+		if current == nil || !sameDay(current.swipes[0], swipe) {
+			current = &session{userID: swipe.UserID}
+			open[swipe.UserID] = current
+			sessions = append(sessions, current)
+		}
+		current.swipes = append(current.swipes, swipe)
+	}
+
+	// Sessions are ordered by their first swipe, so ratings and activity can be replayed as
+	// we go.
+	for _, s := range sessions {
+		viewer, ok := profileByUser[s.userID]
+		if !ok {
+			result.SkippedNoData++
+			applyRatings(ratings, s.swipes, cfg.EloK)
+			lastActive[s.userID] = s.swipes[len(s.swipes)-1].CreatedAt
+			continue
+		}
+
+		type ranked struct {
+			liked bool
+			score float64
+		}
+
+		now := s.swipes[0].CreatedAt
+		candidates := make([]ranked, 0, len(s.swipes))
+		for _, swipe := range s.swipes {
+			candidate, ok := profileByUser[swipe.TargetUserID]
+			if !ok {
+				result.SkippedNoData++
+				continue
+			}
+			candidate.Rating = ratings[swipe.TargetUserID]
+			candidate.LastActiveAt = activeAsOf(candidate, lastActive[swipe.TargetUserID], now)
+
+			score, _ := scorer.Score(&viewer, preferenceByUser[s.userID], &candidate, preferenceByUser[swipe.TargetUserID], now)
+			candidates = append(candidates, ranked{swipe.Type.IsLike(), score})
+		}
+
+		applyRatings(ratings, s.swipes, cfg.EloK)
+		lastActive[s.userID] = s.swipes[len(s.swipes)-1].CreatedAt
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score > candidates[j].score
+		})
+
+		result.Sessions++
+		for i, candidate := range candidates {
+			result.Swipes++
+			if candidate.liked {
+				result.Likes++
+			}
+			if i < k {
+				result.TopKSwipes++
+				if candidate.liked {
+					result.TopKLikes++
+				}
+			}
+		}
+	}
+
+	if result.Swipes > 0 {
+		result.BaselineRate = float64(result.Likes) / float64(result.Swipes)
+	}
+	if result.TopKSwipes > 0 {
+		result.TopKLikeRate = float64(result.TopKLikes) / float64(result.TopKSwipes)
+	}
+	if result.BaselineRate > 0 {
+		result.Lift = result.TopKLikeRate / result.BaselineRate
+	}
+
+	return result
+}
+
+func applyRatings(ratings map[uuid.UUID]float64, swipes []models.Swipe, k float64) {
+	for _, swipe := range swipes {
+		swiperRating, ok := ratings[swipe.UserID]
+		if !ok {
+			swiperRating = DefaultRating
+		}
+		targetRating, ok := ratings[swipe.TargetUserID]
+		if !ok {
+			targetRating = DefaultRating
+		}
+		ratings[swipe.TargetUserID] = UpdateRating(targetRating, swiperRating, swipe.Type.IsLike(), k)
+	}
+}
+
+// activeAsOf is when the candidate was last active before now, given their latest swipe
+// before it. The LastActiveAt of the profile only counts when it was before now, a later one
+// is from the future of the replay.
+func activeAsOf(candidate models.Profile, lastSwipe, now time.Time) time.Time {
+	if candidate.LastActiveAt.Before(now) && candidate.LastActiveAt.After(lastSwipe) {
+		return candidate.LastActiveAt
+	}
+	return lastSwipe
+}
+
+func sameDay(a, b models.Swipe) bool {
+	y1, m1, d1 := a.CreatedAt.Date()
+	y2, m2, d2 := b.CreatedAt.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
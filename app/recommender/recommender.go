@@ -0,0 +1,195 @@
+package recommender
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+type Recommender interface {
+	// Recommend returns up to limit profiles for userID, best match first.
+	Recommend(userID uuid.UUID, excludeIDs []uuid.UUID, limit int) ([]models.Profile, error)
+	// Boosted returns up to limit profiles with a running boost that suit userID, best
+	// match first.
+	Boosted(userID uuid.UUID, limit int) ([]models.Profile, error)
+	// RecordSwipe updates the desirability rating of the swiped profile and sets the change
+	// on swipe.
+	RecordSwipe(swipe *models.Swipe) error
+}
+
+type scoringRecommender struct {
+	profileRepo repository.ProfileRepository
+	boostRepo   repository.BoostRepository
+	scorer      *Scorer
+	cfg         config.RecommenderConfig
+	now         func() time.Time
+}
+
+func NewRecommender(profileRepo repository.ProfileRepository, boostRepo repository.BoostRepository, cfg config.RecommenderConfig) Recommender {
+	return &scoringRecommender{profileRepo, boostRepo, NewScorer(cfg), cfg, time.Now}
+}
+
+type scoredProfile struct {
+	profile models.Profile
+	score   float64
+}
+
+func (r *scoringRecommender) Recommend(userID uuid.UUID, excludeIDs []uuid.UUID, limit int) ([]models.Profile, error) {
+	if limit <= 0 {
+		return []models.Profile{}, nil
+	}
+
+	poolSize := r.cfg.CandidatePoolSize
+	if poolSize < limit {
+		poolSize = limit
+	}
+
+	candidates, err := r.profileRepo.GetProfilesExcluding(excludeIDs, poolSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	boosted, err := r.boostedUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.rank(userID, candidates, boosted, limit)
+}
+
+func (r *scoringRecommender) Boosted(userID uuid.UUID, limit int) ([]models.Profile, error) {
+	if limit <= 0 {
+		return []models.Profile{}, nil
+	}
+
+	boosted, err := r.boostedUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	candidateIDs := make([]uuid.UUID, 0, len(boosted))
+	for boostedID := range boosted {
+		if boostedID != userID {
+			candidateIDs = append(candidateIDs, boostedID)
+		}
+	}
+	if len(candidateIDs) == 0 {
+		return []models.Profile{}, nil
+	}
+
+	candidates, err := r.profileRepo.GetProfilesByUserIDs(candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.rank(userID, candidates, boosted, limit)
+}
+
+func (r *scoringRecommender) boostedUsers() (map[uuid.UUID]bool, error) {
+	boostedIDs, err := r.boostRepo.GetBoostedUserIDs(r.now())
+	if err != nil {
+		return nil, err
+	}
+
+	boosted := make(map[uuid.UUID]bool, len(boostedIDs))
+	for _, boostedID := range boostedIDs {
+		boosted[boostedID] = true
+	}
+	return boosted, nil
+}
+
+// rank scores the candidates for userID and returns the best limit of them. Candidates
+// the viewer's preferences rule out are dropped, boosted ones get their score multiplied.
+func (r *scoringRecommender) rank(userID uuid.UUID, candidates []models.Profile, boosted map[uuid.UUID]bool, limit int) ([]models.Profile, error) {
+	viewer, err := r.profileRepo.GetProfileByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		viewer = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	viewerPref, err := r.profileRepo.GetPreferenceByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		viewerPref = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	candidateIDs := make([]uuid.UUID, len(candidates))
+	for i, candidate := range candidates {
+		candidateIDs[i] = candidate.UserID
+	}
+
+	preferences, err := r.profileRepo.GetPreferencesByUserIDs(candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	preferenceByUser := make(map[uuid.UUID]*models.Preference, len(preferences))
+	for i := range preferences {
+		preferenceByUser[preferences[i].UserID] = &preferences[i]
+	}
+
+	now := r.now()
+	scored := make([]scoredProfile, 0, len(candidates))
+	for i := range candidates {
+		score, ok := r.scorer.Score(viewer, viewerPref, &candidates[i], preferenceByUser[candidates[i].UserID], now)
+		if !ok {
+			continue
+		}
+		if boosted[candidates[i].UserID] {
+			score *= r.cfg.BoostMultiplier
+		}
+		scored = append(scored, scoredProfile{candidates[i], score})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	profiles := make([]models.Profile, len(scored))
+	for i, s := range scored {
+		profiles[i] = s.profile
+	}
+
+	return profiles, nil
+}
+
+func (r *scoringRecommender) RecordSwipe(swipe *models.Swipe) error {
+	target, err := r.profileRepo.GetProfileByUserID(swipe.TargetUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	swiperRating := float64(DefaultRating)
+	swiper, err := r.profileRepo.GetProfileByUserID(swipe.UserID)
+	if err == nil {
+		swiperRating = swiper.Rating
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// Only the change is written, added to the rating as it is by then, so swipes on the
+	// same profile at once do not overwrite one another
+	change := UpdateRating(target.Rating, swiperRating, swipe.Type.IsLike(), r.cfg.EloK) - target.Rating
+	if err := r.profileRepo.AdjustRating(swipe.TargetUserID, change); err != nil {
+		return err
+	}
+	swipe.RatingChange = change
+	return nil
+}
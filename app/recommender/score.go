@@ -0,0 +1,206 @@
+package recommender
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+// DefaultRating is the desirability rating every profile starts with.
+const DefaultRating = 1200
+
+const earthRadiusKm = 6371
+
+// Scorer combines the individual ranking signals into a single weighted score.
+type Scorer struct {
+	cfg config.RecommenderConfig
+}
+
+func NewScorer(cfg config.RecommenderConfig) *Scorer {
+	return &Scorer{cfg}
+}
+
+// Score rates how good a recommendation candidate is for viewer. Both viewer and the
+// preferences may be nil when they are not known. The second return value is false
+// when the candidate falls outside the viewer's preferences and should not be shown.
+func (s *Scorer) Score(viewer *models.Profile, viewerPref *models.Preference, candidate *models.Profile, candidatePref *models.Preference, now time.Time) (float64, bool) {
+	fit := PreferenceFit(viewerPref, candidate, now)
+	if fit == 0 {
+		return 0, false
+	}
+	if viewer != nil {
+		// Candidates who would not be interested in the viewer are ranked lower, not hidden.
+		fit *= 0.5 + 0.5*PreferenceFit(candidatePref, viewer, now)
+	}
+
+	distance := 0.5
+	interests := 0.0
+	if viewer != nil {
+		km, ok := DistanceKm(viewer, candidate)
+		if ok {
+			if viewerPref != nil && viewerPref.MaxDistanceKm > 0 && km > viewerPref.MaxDistanceKm {
+				return 0, false
+			}
+			distance = math.Exp(-km / s.cfg.DistanceScaleKm)
+		}
+		interests = SharedInterests(viewer.Interests, candidate.Interests)
+	}
+
+	w := s.cfg.Weights
+	score := w.Preference*fit +
+		w.Distance*distance +
+		w.Activity*ActivityScore(candidate.LastActiveAt, now, s.cfg.ActivityHalfLifeHours) +
+		w.Completeness*Completeness(candidate) +
+		w.Interests*interests +
+		w.Desirability*Desirability(candidate.Rating)
+
+	return score, true
+}
+
+// PreferenceFit returns 1 when profile matches pref, 0 when it is excluded by it and
+// 0.5 when the profile is missing the data needed to decide.
+func PreferenceFit(pref *models.Preference, profile *models.Profile, now time.Time) float64 {
+	if pref == nil {
+		return 1
+	}
+
+	fit := 1.0
+	if pref.Gender != "" {
+		if profile.Gender == "" {
+			fit = 0.5
+		} else if !strings.EqualFold(pref.Gender, profile.Gender) {
+			return 0
+		}
+	}
+
+	if pref.MinAge > 0 || pref.MaxAge > 0 {
+		if profile.BirthDate.IsZero() {
+			return fit * 0.5
+		}
+		age := Age(profile.BirthDate, now)
+		if pref.MinAge > 0 && age < pref.MinAge {
+			return 0
+		}
+		if pref.MaxAge > 0 && age > pref.MaxAge {
+			return 0
+		}
+	}
+
+	return fit
+}
+
+// Age returns the age in whole years of someone born on birthDate.
+func Age(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// DistanceKm returns the great-circle distance between two profiles. The second return
+// value is false when either profile has no location.
+func DistanceKm(a, b *models.Profile) (float64, bool) {
+	if (a.Latitude == 0 && a.Longitude == 0) || (b.Latitude == 0 && b.Longitude == 0) {
+		return 0, false
+	}
+
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h)), true
+}
+
+// ActivityScore decays from 1 towards 0 the longer ago lastActive was.
+func ActivityScore(lastActive, now time.Time, halfLifeHours float64) float64 {
+	if lastActive.IsZero() {
+		return 0
+	}
+	hours := now.Sub(lastActive).Hours()
+	if hours < 0 {
+		hours = 0
+	}
+	return math.Pow(0.5, hours/halfLifeHours)
+}
+
+// Completeness is the fraction of optional profile fields that have been filled in.
+func Completeness(profile *models.Profile) float64 {
+	filled := 0
+	fields := []bool{
+		profile.Name != "",
+		profile.Bio != "",
+		profile.ProfileImage != "",
+		profile.Gender != "",
+		!profile.BirthDate.IsZero(),
+		len(profile.Interests) > 0,
+		profile.Latitude != 0 || profile.Longitude != 0,
+	}
+	for _, ok := range fields {
+		if ok {
+			filled++
+		}
+	}
+	return float64(filled) / float64(len(fields))
+}
+
+// SharedInterests returns the Jaccard similarity of two interest lists.
+func SharedInterests(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(a))
+	for _, interest := range a {
+		set[strings.ToLower(strings.TrimSpace(interest))] = true
+	}
+
+	union := len(set)
+	shared := 0
+	seen := make(map[string]bool, len(b))
+	for _, interest := range b {
+		key := strings.ToLower(strings.TrimSpace(interest))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if set[key] {
+			shared++
+		} else {
+			union++
+		}
+	}
+
+	return float64(shared) / float64(union)
+}
+
+// Desirability maps an Elo rating onto (0, 1), with DefaultRating at 0.5.
+func Desirability(rating float64) float64 {
+	if rating == 0 {
+		rating = DefaultRating
+	}
+	return 1 / (1 + math.Pow(10, (DefaultRating-rating)/400))
+}
+
+// UpdateRating applies one Elo step to targetRating after a swipe from someone rated
+// swiperRating. A like counts as a win for the target and a pass as a loss.
+func UpdateRating(targetRating, swiperRating float64, liked bool, k float64) float64 {
+	if targetRating == 0 {
+		targetRating = DefaultRating
+	}
+	if swiperRating == 0 {
+		swiperRating = DefaultRating
+	}
+
+	expected := 1 / (1 + math.Pow(10, (swiperRating-targetRating)/400))
+	outcome := 0.0
+	if liked {
+		outcome = 1
+	}
+	return targetRating + k*(outcome-expected)
+}
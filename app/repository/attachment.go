@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository interface {
+	CreateAttachment(attachment *models.Attachment) error
+	GetAttachment(id uuid.UUID) (*models.Attachment, error)
+	MarkUploaded(attachment *models.Attachment) (bool, error)
+	GetPurgeableAttachments(unsentBefore time.Time, limit int) ([]models.Attachment, error)
+	DeleteAttachment(id uuid.UUID) error
+}
+
+type attachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+func (r *attachmentRepository) CreateAttachment(attachment *models.Attachment) error {
+	return r.db.Create(attachment).Error
+}
+
+func (r *attachmentRepository) GetAttachment(id uuid.UUID) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.First(&attachment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// MarkUploaded saves what the uploaded file of attachment turned out to be. It reports
+// false when the file had already been uploaded.
+func (r *attachmentRepository) MarkUploaded(attachment *models.Attachment) (bool, error) {
+	result := r.db.Model(&models.Attachment{}).
+		Where("id = ? AND uploaded_at IS NULL", attachment.ID).
+		Updates(map[string]interface{}{
+			"content_type": attachment.ContentType,
+			"size":         attachment.Size,
+			"width":        attachment.Width,
+			"height":       attachment.Height,
+			"duration_ms":  attachment.DurationMs,
+			"uploaded_at":  attachment.UploadedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetPurgeableAttachments returns attachments whose files are no longer needed: those of
+// unsent messages and those never sent whose upload slot expired before unsentBefore.
+func (r *attachmentRepository) GetPurgeableAttachments(unsentBefore time.Time, limit int) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL OR (message_id IS NULL AND upload_expires_at < ?)", unsentBefore).
+		Limit(limit).
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *attachmentRepository) DeleteAttachment(id uuid.UUID) error {
+	return r.db.Unscoped().Delete(&models.Attachment{}, "id = ?", id).Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockRepository interface {
+	CreateBlock(block *models.Block) error
+	GetBlockedUsersID(userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+type blockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	return &blockRepository{db: db}
+}
+
+// CreateBlock stores the block, blocking a user again is a no-op.
+func (r *blockRepository) CreateBlock(block *models.Block) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(block).Error
+}
+
+// GetBlockedUsersID returns the users userID blocked or was blocked by.
+func (r *blockRepository) GetBlockedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	var blocks []models.Block
+	err := r.db.Where("user_id = ? OR blocked_user_id = ?", userID, userID).Find(&blocks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, len(blocks))
+	for i, block := range blocks {
+		userIDs[i] = block.BlockedUserID
+		if block.BlockedUserID == userID {
+			userIDs[i] = block.UserID
+		}
+	}
+	return userIDs, nil
+}
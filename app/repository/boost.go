@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type BoostRepository interface {
+	CreateBoost(boost *models.Boost) (bool, error)
+	GetBoost(id uuid.UUID) (*models.Boost, error)
+	GetActiveBoost(userID uuid.UUID, at time.Time) (*models.Boost, error)
+	GetBoostedUserIDs(at time.Time) ([]uuid.UUID, error)
+}
+
+type boostRepository struct {
+	db *gorm.DB
+}
+
+func NewBoostRepository(db *gorm.DB) BoostRepository {
+	return &boostRepository{db: db}
+}
+
+// CreateBoost starts the boost unless another boost of the user is running by its start,
+// reporting whether it was started. Boosts of the same user are serialised by an advisory
+// lock, so two requests at once start exactly one boost.
+func (r *boostRepository) CreateBoost(boost *models.Boost) (bool, error) {
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "boost:"+boost.UserID.String()).Error
+		if err != nil {
+			return err
+		}
+
+		var running int64
+		err = tx.Model(&models.Boost{}).Where("user_id = ? AND ends_at > ?", boost.UserID, boost.StartsAt).Count(&running).Error
+		if err != nil || running > 0 {
+			return err
+		}
+
+		if err := tx.Create(boost).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *boostRepository) GetBoost(id uuid.UUID) (*models.Boost, error) {
+	var boost models.Boost
+	err := r.db.First(&boost, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &boost, nil
+}
+
+func (r *boostRepository) GetActiveBoost(userID uuid.UUID, at time.Time) (*models.Boost, error) {
+	var boost models.Boost
+	err := r.db.Where("user_id = ? AND starts_at <= ? AND ends_at > ?", userID, at, at).First(&boost).Error
+	if err != nil {
+		return nil, err
+	}
+	return &boost, nil
+}
+
+// GetBoostedUserIDs returns the users with a boost running at the given time.
+func (r *boostRepository) GetBoostedUserIDs(at time.Time) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&models.Boost{}).
+		Where("starts_at <= ? AND ends_at > ?", at, at).
+		Distinct().
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type BotSignalRepository interface {
+	CreateSignals(signals []models.BotSignal) error
+}
+
+type botSignalRepository struct {
+	db *gorm.DB
+}
+
+func NewBotSignalRepository(db *gorm.DB) BotSignalRepository {
+	return &botSignalRepository{db: db}
+}
+
+func (r *botSignalRepository) CreateSignals(signals []models.BotSignal) error {
+	if len(signals) == 0 {
+		return nil
+	}
+	return r.db.Create(&signals).Error
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeckRepository interface {
+	GetDeck(userID uuid.UUID) (*models.Deck, error)
+	ReplaceDeck(deck *models.Deck, candidateIDs []uuid.UUID) error
+	AppendEntries(userID uuid.UUID, candidateIDs []uuid.UUID) error
+	GetEntries(userID uuid.UUID, afterPosition, limit int) ([]models.DeckEntry, error)
+	GetCandidateIDs(userID uuid.UUID) ([]uuid.UUID, error)
+	CountEntries(userID uuid.UUID) (int64, error)
+	ConsumeEntry(userID, candidateID uuid.UUID) error
+	PushFront(userID, candidateID uuid.UUID) error
+	DeleteDeck(userID uuid.UUID) error
+}
+
+type deckRepository struct {
+	db *gorm.DB
+}
+
+func NewDeckRepository(db *gorm.DB) DeckRepository {
+	return &deckRepository{db: db}
+}
+
+func (r *deckRepository) GetDeck(userID uuid.UUID) (*models.Deck, error) {
+	var deck models.Deck
+	err := r.db.First(&deck, "user_id = ?", userID).Error
+	return &deck, err
+}
+
+func (r *deckRepository) ReplaceDeck(deck *models.Deck, candidateIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", deck.UserID).Delete(&models.DeckEntry{}).Error; err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"generated_at", "expires_at", "updated_at"}),
+		}).Create(deck).Error
+		if err != nil {
+			return err
+		}
+
+		return insertEntries(tx, deck.UserID, candidateIDs, 0)
+	})
+}
+
+func (r *deckRepository) AppendEntries(userID uuid.UUID, candidateIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&models.DeckEntry{}).
+			Select("COALESCE(MAX(position), 0)").
+			Where("user_id = ?", userID).
+			Scan(&last).Error
+		if err != nil {
+			return err
+		}
+
+		return insertEntries(tx, userID, candidateIDs, last)
+	})
+}
+
+func insertEntries(tx *gorm.DB, userID uuid.UUID, candidateIDs []uuid.UUID, after int) error {
+	if len(candidateIDs) == 0 {
+		return nil
+	}
+
+	entries := make([]models.DeckEntry, len(candidateIDs))
+	for i, candidateID := range candidateIDs {
+		entries[i] = models.DeckEntry{
+			UserID:          userID,
+			CandidateUserID: candidateID,
+			Position:        after + i + 1,
+		}
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entries).Error
+}
+
+func (r *deckRepository) GetEntries(userID uuid.UUID, afterPosition, limit int) ([]models.DeckEntry, error) {
+	var entries []models.DeckEntry
+	err := r.db.Where("user_id = ? AND position > ?", userID, afterPosition).
+		Order("position asc").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *deckRepository) GetCandidateIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var candidateIDs []uuid.UUID
+	err := r.db.Model(&models.DeckEntry{}).Where("user_id = ?", userID).Pluck("candidate_user_id", &candidateIDs).Error
+	return candidateIDs, err
+}
+
+func (r *deckRepository) CountEntries(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.DeckEntry{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *deckRepository) ConsumeEntry(userID, candidateID uuid.UUID) error {
+	return r.db.Unscoped().Where("user_id = ? AND candidate_user_id = ?", userID, candidateID).Delete(&models.DeckEntry{}).Error
+}
+
+// PushFront moves candidateID to the front of the user's deck, adding it when it is not
+// queued yet. Positions below the current head keep cursors of the deck valid.
+func (r *deckRepository) PushFront(userID, candidateID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var head int
+		err := tx.Model(&models.DeckEntry{}).
+			Select("COALESCE(MIN(position), 1)").
+			Where("user_id = ?", userID).
+			Scan(&head).Error
+		if err != nil {
+			return err
+		}
+
+		entry := models.DeckEntry{UserID: userID, CandidateUserID: candidateID, Position: head - 1}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "candidate_user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"position", "updated_at"}),
+		}).Create(&entry).Error
+	})
+}
+
+func (r *deckRepository) DeleteDeck(userID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.DeckEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Deck{}).Error
+	})
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type IcebreakerRepository interface {
+	GetTemplates(activeOnly bool) ([]models.IcebreakerTemplate, error)
+	GetTemplate(id uuid.UUID) (*models.IcebreakerTemplate, error)
+	CreateTemplate(template *models.IcebreakerTemplate) error
+	UpdateTemplate(template *models.IcebreakerTemplate) error
+	DeleteTemplate(id uuid.UUID) error
+	GetUnsentSuggestions(matchRoomID, userID uuid.UUID) ([]models.IcebreakerSuggestion, error)
+	CreateSuggestions(suggestions []models.IcebreakerSuggestion) error
+}
+
+type icebreakerRepository struct {
+	db *gorm.DB
+}
+
+func NewIcebreakerRepository(db *gorm.DB) IcebreakerRepository {
+	return &icebreakerRepository{db: db}
+}
+
+// GetTemplates returns the icebreaker templates, only the active ones when activeOnly is
+// set, with their stats and the oldest first.
+func (r *icebreakerRepository) GetTemplates(activeOnly bool) ([]models.IcebreakerTemplate, error) {
+	query := r.db.Order("created_at, id")
+	if activeOnly {
+		query = query.Where("active")
+	}
+
+	var templates []models.IcebreakerTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return templates, nil
+	}
+
+	ids := make([]uuid.UUID, len(templates))
+	for i, template := range templates {
+		ids[i] = template.ID
+	}
+	var stats []models.IcebreakerStats
+	err := r.db.Model(&models.IcebreakerSuggestion{}).
+		Select("template_id, COUNT(*) AS suggested, COUNT(sent_at) AS sent, COUNT(replied_at) AS replied").
+		Where("template_id IN ?", ids).
+		Group("template_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	statsByTemplate := make(map[uuid.UUID]models.IcebreakerStats, len(stats))
+	for _, stat := range stats {
+		statsByTemplate[stat.TemplateID] = stat
+	}
+	for i := range templates {
+		templates[i].Stats = statsByTemplate[templates[i].ID]
+	}
+	return templates, nil
+}
+
+func (r *icebreakerRepository) GetTemplate(id uuid.UUID) (*models.IcebreakerTemplate, error) {
+	var template models.IcebreakerTemplate
+	if err := r.db.Where("id = ?", id).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *icebreakerRepository) CreateTemplate(template *models.IcebreakerTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// UpdateTemplate saves the source, text and whether the template is active.
+func (r *icebreakerRepository) UpdateTemplate(template *models.IcebreakerTemplate) error {
+	return r.db.Model(template).Select("source", "text", "active").Updates(template).Error
+}
+
+// DeleteTemplate removes the template, its suggestions are kept.
+func (r *icebreakerRepository) DeleteTemplate(id uuid.UUID) error {
+	result := r.db.Where("id = ?", id).Delete(&models.IcebreakerTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetUnsentSuggestions returns the suggestions userID got for the match room and has not
+// sent, including as a message held for review.
+func (r *icebreakerRepository) GetUnsentSuggestions(matchRoomID, userID uuid.UUID) ([]models.IcebreakerSuggestion, error) {
+	var suggestions []models.IcebreakerSuggestion
+	err := r.db.Where("match_room_id = ? AND user_id = ? AND message_id IS NULL", matchRoomID, userID).
+		Order("created_at, id").
+		Find(&suggestions).Error
+	return suggestions, err
+}
+
+func (r *icebreakerRepository) CreateSuggestions(suggestions []models.IcebreakerSuggestion) error {
+	if len(suggestions) == 0 {
+		return nil
+	}
+	return r.db.Create(&suggestions).Error
+}
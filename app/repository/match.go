@@ -6,15 +6,41 @@ import (
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MatchRepository interface {
 	CreateMatch(match *models.MatchRoom) error
-	GetMatchedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error)
+	GetMatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error)
 	GetMatchRooms(userID uuid.UUID) ([]models.MatchRoom, error)
-	DeleteMatchRoom(id, userID uuid.UUID) error
+	GetMatchRoom(id, userID uuid.UUID) (*models.MatchRoom, error)
+	GetMatchRoomSummaries(userID uuid.UUID) ([]models.MatchRoomSummary, error)
+	GetParticipants(matchRoomID uuid.UUID) ([]models.RoomParticipant, error)
+	MarkRead(matchRoomID, userID uuid.UUID, message *models.Message, readAt time.Time) (bool, error)
+	Unmatch(unmatch *models.Unmatch) error
+	GetUnmatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error)
+	GetUnmatchesToPurge(unmatchedBefore time.Time, limit int) ([]models.Unmatch, error)
+	PurgeMessages(unmatch *models.Unmatch, purgedAt time.Time) error
+	CountUnmatchReasons(from, to time.Time) ([]models.UnmatchReasonCount, error)
+	GetMatchBetween(userID, targetUserID uuid.UUID) (*models.MatchRoom, error)
+	ExtendMatch(id uuid.UUID) (bool, error)
+	GetMatchesDue(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error)
+	GetMatchesToRemind(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error)
+	MarkReminded(id uuid.UUID, remindedAt time.Time) (bool, error)
+	ExpireMatch(id uuid.UUID, cutoffs models.MatchCutoffs, expiredAt time.Time) (bool, error)
+	CountMessages(matchRoomID uuid.UUID) (int64, error)
 	CreateMessage(message *models.Message) error
-	GetMessages(matchRoomID uuid.UUID) ([]models.Message, error)
+	GetMessage(id uuid.UUID) (*models.Message, error)
+	EditMessage(message *models.Message, edit *models.MessageEdit) error
+	ReleaseMessage(id uuid.UUID, releasedAt time.Time) error
+	MarkUnwanted(id uuid.UUID, markedAt time.Time) (bool, error)
+	UnsendMessage(message *models.Message) error
+	GetMessageEdits(messageID uuid.UUID) ([]models.MessageEdit, error)
+	SetReaction(reaction *models.MessageReaction) error
+	DeleteReaction(messageID, userID uuid.UUID) (bool, error)
+	GetMessagesBefore(matchRoomID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]models.Message, error)
+	GetMessagesAfter(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error)
+	GetMessageUpdates(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error)
 }
 
 type matchRepository struct {
@@ -29,37 +55,537 @@ func (r *matchRepository) CreateMatch(match *models.MatchRoom) error {
 	return r.db.Create(match).Error
 }
 
-func (r *matchRepository) GetMatchedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error) {
-	var matches []models.MatchRoom
-	err := r.db.Select("target_user_id").Where("user_id = ? AND DATE(created_at) = ?", userID, date).Find(&matches).Error
+// participates filters match rooms down to those a user takes part in.
+const participates = "EXISTS (SELECT 1 FROM room_participants p WHERE p.match_room_id = match_rooms.id AND p.user_id = ?)"
+
+// GetMatchedUsersID returns the users userID is currently matched with.
+func (r *matchRepository) GetMatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	var matchedUserIDs []uuid.UUID
+	err := r.db.Model(&models.RoomParticipant{}).
+		Joins("JOIN match_rooms ON match_rooms.id = room_participants.match_room_id AND match_rooms.deleted_at IS NULL").
+		Where(participates, userID).
+		Where("room_participants.user_id <> ?", userID).
+		Pluck("room_participants.user_id", &matchedUserIDs).Error
+	return matchedUserIDs, err
+}
+
+func (r *matchRepository) GetMatchRooms(userID uuid.UUID) ([]models.MatchRoom, error) {
+	var matchRooms []models.MatchRoom
+	err := r.db.Preload("Participants").Where(participates, userID).Find(&matchRooms).Error
+	return matchRooms, err
+}
+
+// GetMatchRoom returns the match room with its participants, if userID is one of them.
+func (r *matchRepository) GetMatchRoom(id, userID uuid.UUID) (*models.MatchRoom, error) {
+	var matchRoom models.MatchRoom
+	err := r.db.Preload("Participants").Where("id = ?", id).Where(participates, userID).First(&matchRoom).Error
 	if err != nil {
 		return nil, err
 	}
+	return &matchRoom, nil
+}
+
+// GetParticipants returns the participants of a match room, closed or not.
+func (r *matchRepository) GetParticipants(matchRoomID uuid.UUID) ([]models.RoomParticipant, error) {
+	var participants []models.RoomParticipant
+	err := r.db.Where("match_room_id = ?", matchRoomID).Find(&participants).Error
+	return participants, err
+}
 
-	MatchedUserID := make([]uuid.UUID, len(matches))
-	for i, match := range matches {
-		MatchedUserID[i] = match.TargetUserID
+// matchRoomSummaryRow is a summary as the query returns it, flat.
+type matchRoomSummaryRow struct {
+	models.MatchRoom
+	TargetUserID        uuid.UUID
+	LastMessageID       *uuid.UUID
+	LastMessageSenderID uuid.UUID
+	LastMessageContent  string
+	LastMessageUnsent   bool
+	LastMessageAt       time.Time
+	UnreadCount         int64
+	LastActivityAt      time.Time
+}
+
+// GetMatchRoomSummaries returns the match rooms of userID with their last message and
+// unread count, the most recently active first.
+func (r *matchRepository) GetMatchRoomSummaries(userID uuid.UUID) ([]models.MatchRoomSummary, error) {
+	var rows []matchRoomSummaryRow
+	err := r.db.Raw(`
+		SELECT mr.*,
+			other.user_id AS target_user_id,
+			lm.id AS last_message_id,
+			lm.sender_id AS last_message_sender_id,
+			lm.content AS last_message_content,
+			lm.unsent_at IS NOT NULL AS last_message_unsent,
+			lm.created_at AS last_message_at,
+			(
+				SELECT COUNT(*) FROM messages m
+				WHERE m.match_room_id = mr.id AND m.deleted_at IS NULL AND m.held_at IS NULL AND m.sender_id <> me.user_id
+				AND (me.last_read_message_id IS NULL OR (m.created_at, m.id) > (
+					SELECT r.created_at, r.id FROM messages r WHERE r.id = me.last_read_message_id
+				))
+			) AS unread_count,
+			COALESCE(lm.created_at, mr.created_at) AS last_activity_at
+		FROM room_participants me
+		JOIN match_rooms mr ON mr.id = me.match_room_id AND mr.deleted_at IS NULL
+		JOIN room_participants other ON other.match_room_id = mr.id AND other.user_id <> me.user_id
+		LEFT JOIN LATERAL (
+			SELECT id, sender_id, content, unsent_at, created_at FROM messages
+			WHERE match_room_id = mr.id AND deleted_at IS NULL AND held_at IS NULL
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) lm ON true
+		WHERE me.user_id = ?
+		ORDER BY last_activity_at DESC, mr.id`, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
 	}
 
-	return MatchedUserID, nil
+	roomIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		roomIDs[i] = row.ID
+	}
+	var participants []models.RoomParticipant
+	if len(roomIDs) > 0 {
+		if err := r.db.Where("match_room_id IN ?", roomIDs).Find(&participants).Error; err != nil {
+			return nil, err
+		}
+	}
+	participantsByRoom := make(map[uuid.UUID][]models.RoomParticipant)
+	for _, participant := range participants {
+		participantsByRoom[participant.MatchRoomID] = append(participantsByRoom[participant.MatchRoomID], participant)
+	}
+
+	summaries := make([]models.MatchRoomSummary, len(rows))
+	for i, row := range rows {
+		row.MatchRoom.Participants = participantsByRoom[row.ID]
+		summaries[i] = models.MatchRoomSummary{
+			MatchRoom:      row.MatchRoom,
+			TargetUserID:   row.TargetUserID,
+			UnreadCount:    row.UnreadCount,
+			LastActivityAt: row.LastActivityAt,
+		}
+		if row.LastMessageID != nil {
+			summaries[i].LastMessage = &models.MessagePreview{
+				ID:        *row.LastMessageID,
+				SenderID:  row.LastMessageSenderID,
+				Content:   row.LastMessageContent,
+				Unsent:    row.LastMessageUnsent,
+				CreatedAt: row.LastMessageAt,
+			}
+		}
+	}
+	return summaries, nil
 }
 
-func (r *matchRepository) GetMatchRooms(userID uuid.UUID) ([]models.MatchRoom, error) {
+// MarkRead moves the read cursor of userID up to message, reporting false when they had
+// already read as far or further.
+func (r *matchRepository) MarkRead(matchRoomID, userID uuid.UUID, message *models.Message, readAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RoomParticipant{}).
+		Where("match_room_id = ? AND user_id = ?", matchRoomID, userID).
+		Where(`last_read_message_id IS NULL OR (?, ?) > (
+			SELECT r.created_at, r.id FROM messages r WHERE r.id = room_participants.last_read_message_id
+		)`, message.CreatedAt, message.ID).
+		Updates(map[string]interface{}{"last_read_message_id": message.ID, "last_read_at": readAt})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Unmatch closes the match room for both participants and records the unmatch, if its
+// user is one of the participants. Otherwise it is gorm.ErrRecordNotFound.
+func (r *matchRepository) Unmatch(unmatch *models.Unmatch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var matchRoom models.MatchRoom
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", unmatch.MatchRoomID).
+			Where(participates, unmatch.UserID).
+			First(&matchRoom).Error
+		if err != nil {
+			return err
+		}
+
+		var participant models.RoomParticipant
+		err = tx.Where("match_room_id = ? AND user_id <> ?", matchRoom.ID, unmatch.UserID).First(&participant).Error
+		if err != nil {
+			return err
+		}
+		unmatch.TargetUserID = participant.UserID
+
+		if err := tx.Delete(&matchRoom).Error; err != nil {
+			return err
+		}
+		return tx.Create(unmatch).Error
+	})
+}
+
+// GetUnmatchedUsersID returns the users userID unmatched or was unmatched by.
+func (r *matchRepository) GetUnmatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	var unmatches []models.Unmatch
+	err := r.db.Where("user_id = ? OR target_user_id = ?", userID, userID).Find(&unmatches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, len(unmatches))
+	for i, unmatch := range unmatches {
+		userIDs[i] = unmatch.TargetUserID
+		if unmatch.TargetUserID == userID {
+			userIDs[i] = unmatch.UserID
+		}
+	}
+	return userIDs, nil
+}
+
+// GetUnmatchesToPurge returns the unmatches from before unmatchedBefore whose messages are
+// still kept, the oldest first.
+func (r *matchRepository) GetUnmatchesToPurge(unmatchedBefore time.Time, limit int) ([]models.Unmatch, error) {
+	var unmatches []models.Unmatch
+	err := r.db.Where("unmatched_at < ? AND messages_purged_at IS NULL", unmatchedBefore).
+		Order("unmatched_at").
+		Limit(limit).
+		Find(&unmatches).Error
+	return unmatches, err
+}
+
+// PurgeMessages deletes the messages of an unmatched room for good, along with their edits
+// and reactions. Their attachments are soft deleted for the attachment purge to remove
+// together with their files.
+func (r *matchRepository) PurgeMessages(unmatch *models.Unmatch, purgedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		messageIDs := tx.Unscoped().Model(&models.Message{}).Select("id").Where("match_room_id = ?", unmatch.MatchRoomID)
+
+		if err := tx.Where("message_id IN (?)", messageIDs).Delete(&models.MessageEdit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN (?)", messageIDs).Delete(&models.MessageReaction{}).Error; err != nil {
+			return err
+		}
+		err := tx.Unscoped().Model(&models.Attachment{}).
+			Where("match_room_id = ?", unmatch.MatchRoomID).
+			Updates(map[string]interface{}{"message_id": nil, "deleted_at": gorm.Expr("COALESCE(deleted_at, ?)", purgedAt)}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("match_room_id = ?", unmatch.MatchRoomID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+
+		unmatch.MessagesPurgedAt = &purgedAt
+		return tx.Model(unmatch).Update("messages_purged_at", purgedAt).Error
+	})
+}
+
+// CountUnmatchReasons counts the unmatches from from up to to by reason, the most common
+// reason first.
+func (r *matchRepository) CountUnmatchReasons(from, to time.Time) ([]models.UnmatchReasonCount, error) {
+	var counts []models.UnmatchReasonCount
+	err := r.db.Model(&models.Unmatch{}).
+		Select("reason, COUNT(*) AS count").
+		Where("unmatched_at >= ? AND unmatched_at < ?", from, to).
+		Group("reason").
+		Order("count DESC, reason").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *matchRepository) GetMatchBetween(userID, targetUserID uuid.UUID) (*models.MatchRoom, error) {
+	return matchBetween(r.db, userID, targetUserID)
+}
+
+// matchBetween returns the open match room of a pair of users.
+func matchBetween(db *gorm.DB, userID, targetUserID uuid.UUID) (*models.MatchRoom, error) {
+	var match models.MatchRoom
+	err := db.Preload("Participants").Where(participates, userID).Where(participates, targetUserID).First(&match).Error
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// ExtendMatch extends the window for the first message of a match room, reporting false
+// when it was extended already or the conversation has started.
+func (r *matchRepository) ExtendMatch(id uuid.UUID) (bool, error) {
+	// Reminded again before the new deadline
+	result := r.db.Model(&models.MatchRoom{}).
+		Where("id = ? AND NOT extended AND first_message_at IS NULL", id).
+		Updates(map[string]interface{}{"extended": true, "reminded_at": nil})
+	return result.RowsAffected > 0, result.Error
+}
+
+// due filters match rooms down to those due by cutoffs. Rooms matched before the rules
+// were kept, and rooms answered, are never due.
+func due(db *gorm.DB, cutoffs models.MatchCutoffs) *gorm.DB {
+	return db.Where("match_rooms.matched_by_id IS NOT NULL AND match_rooms.replied_at IS NULL").
+		Where(`((match_rooms.first_message_at IS NULL AND (
+			(NOT match_rooms.extended AND match_rooms.created_at < ?) OR (match_rooms.extended AND match_rooms.created_at < ?)
+		)) OR match_rooms.first_message_at < ?)`, cutoffs.FirstMessage, cutoffs.Extended, cutoffs.Reply)
+}
+
+// GetMatchesDue returns up to limit open match rooms due by cutoffs, with their participants.
+func (r *matchRepository) GetMatchesDue(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error) {
+	var matchRooms []models.MatchRoom
+	err := due(r.db.Preload("Participants"), cutoffs).Order("created_at, id").Limit(limit).Find(&matchRooms).Error
+	return matchRooms, err
+}
+
+// GetMatchesToRemind returns up to limit open match rooms due by cutoffs whose
+// participants were not reminded yet, with their participants.
+func (r *matchRepository) GetMatchesToRemind(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error) {
 	var matchRooms []models.MatchRoom
-	err := r.db.Where("user_id = ?", userID).Find(&matchRooms).Error
+	err := due(r.db.Preload("Participants"), cutoffs).Where("reminded_at IS NULL").
+		Order("created_at, id").Limit(limit).Find(&matchRooms).Error
 	return matchRooms, err
 }
 
-func (r *matchRepository) DeleteMatchRoom(id, userID uuid.UUID) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.MatchRoom{}).Error
+// MarkReminded records that the participants of a match room were reminded it is about to
+// expire, reporting false when they had been already.
+func (r *matchRepository) MarkReminded(id uuid.UUID, remindedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.MatchRoom{}).Where("id = ? AND reminded_at IS NULL", id).UpdateColumn("reminded_at", remindedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExpireMatch closes a match room for both participants if it is still due by cutoffs,
+// reporting false when the conversation moved on in the meantime.
+func (r *matchRepository) ExpireMatch(id uuid.UUID, cutoffs models.MatchCutoffs, expiredAt time.Time) (bool, error) {
+	result := due(r.db.Model(&models.MatchRoom{}).Where("id = ?", id), cutoffs).
+		Updates(map[string]interface{}{"expired_at": expiredAt, "deleted_at": expiredAt})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *matchRepository) CountMessages(matchRoomID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Message{}).Where("match_room_id = ?", matchRoomID).Count(&count).Error
+	return count, err
 }
 
+// CreateMessage saves message, and when it carries an attachment hands that over to it. An
+// attachment that is not ready in the room, or was sent already, is gorm.ErrRecordNotFound.
+// A delivered message opens the conversation of the room, or answers its opener.
 func (r *matchRepository) CreateMessage(message *models.Message) error {
-	return r.db.Create(message).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(message).Error; err != nil {
+			return err
+		}
+
+		// A held message is recorded once released
+		if message.HeldAt == nil {
+			if err := recordTurn(tx, message, message.CreatedAt); err != nil {
+				return err
+			}
+		}
+		if err := recordIcebreakers(tx, message, message.CreatedAt); err != nil {
+			return err
+		}
+
+		if message.Attachment == nil {
+			return nil
+		}
+
+		result := tx.Model(&models.Attachment{}).
+			Where("id = ? AND match_room_id = ? AND uploader_id = ?", message.Attachment.ID, message.MatchRoomID, message.SenderID).
+			Where("uploaded_at IS NOT NULL AND message_id IS NULL").
+			Update("message_id", message.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.First(message.Attachment, "id = ?", message.Attachment.ID).Error
+	})
+}
+
+// recordTurn records message, delivered at, as the first message of its room, or as the
+// answer to it when the other participant sent the first.
+func recordTurn(tx *gorm.DB, message *models.Message, at time.Time) error {
+	opened := tx.Model(&models.MatchRoom{}).
+		Where("id = ? AND first_message_at IS NULL", message.MatchRoomID).
+		Updates(map[string]interface{}{"first_message_at": at, "first_sender_id": message.SenderID, "reminded_at": nil})
+	if opened.Error != nil || opened.RowsAffected > 0 {
+		return opened.Error
+	}
+
+	return tx.Model(&models.MatchRoom{}).
+		Where("id = ? AND replied_at IS NULL AND first_sender_id <> ?", message.MatchRoomID, message.SenderID).
+		UpdateColumn("replied_at", at).Error
+}
+
+// recordIcebreakers links message to the icebreaker suggestion it was sent from, if any.
+// Once message is delivered at, the suggestion counts as sent and message answers the ones
+// the other participant sent.
+func recordIcebreakers(tx *gorm.DB, message *models.Message, at time.Time) error {
+	if message.IcebreakerID != nil {
+		err := tx.Model(&models.IcebreakerSuggestion{}).
+			Where("id = ? AND match_room_id = ? AND user_id = ? AND message_id IS NULL", message.IcebreakerID, message.MatchRoomID, message.SenderID).
+			UpdateColumn("message_id", message.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	if message.HeldAt != nil {
+		return nil
+	}
+
+	err := tx.Model(&models.IcebreakerSuggestion{}).
+		Where("message_id = ? AND sent_at IS NULL", message.ID).
+		UpdateColumn("sent_at", at).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.IcebreakerSuggestion{}).
+		Where("match_room_id = ? AND user_id <> ? AND sent_at IS NOT NULL AND replied_at IS NULL", message.MatchRoomID, message.SenderID).
+		UpdateColumn("replied_at", at).Error
+}
+
+func (r *matchRepository) GetMessage(id uuid.UUID) (*models.Message, error) {
+	var message models.Message
+	err := r.db.Preload("Reactions").Preload("Attachment").Where("id = ?", id).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// EditMessage keeps the version edit replaces and saves the new content of message, along
+// with its screening.
+func (r *matchRepository) EditMessage(message *models.Message, edit *models.MessageEdit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(edit).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("id = ?", message.ID).
+			Select("content", "edited_at", "screening_verdict", "screening_score", "screening_flags").
+			Updates(message).Error
+	})
+}
+
+// ReleaseMessage delivers a message held for review, which then opens or answers the
+// conversation of its room as if it was sent at releasedAt. It is gorm.ErrRecordNotFound
+// unless the message is held.
+func (r *matchRepository) ReleaseMessage(id uuid.UUID, releasedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Bumped so that the next sync of the recipient picks it up
+		result := tx.Model(&models.Message{}).Where("id = ? AND held_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"held_at": nil, "updated_at": releasedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var message models.Message
+		if err := tx.Where("id = ?", id).First(&message).Error; err != nil {
+			return err
+		}
+		if err := recordTurn(tx, &message, releasedAt); err != nil {
+			return err
+		}
+		return recordIcebreakers(tx, &message, releasedAt)
+	})
+}
+
+// MarkUnwanted records that the recipient did not want the message, reporting false when
+// they had marked it already.
+func (r *matchRepository) MarkUnwanted(id uuid.UUID, markedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Message{}).Where("id = ? AND unwanted_at IS NULL", id).UpdateColumn("unwanted_at", markedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UnsendMessage leaves a tombstone of message, dropping its content together with its
+// earlier versions, reactions and attachment.
+func (r *matchRepository) UnsendMessage(message *models.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.MessageEdit{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.MessageReaction{}).Error; err != nil {
+			return err
+		}
+		// Its file is removed when attachments are purged
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("id = ?", message.ID).
+			Updates(map[string]interface{}{"content": "", "unsent_at": message.UnsentAt}).Error
+	})
+}
+
+// GetMessageEdits returns the earlier versions of a message, oldest first.
+func (r *matchRepository) GetMessageEdits(messageID uuid.UUID) ([]models.MessageEdit, error) {
+	var edits []models.MessageEdit
+	err := r.db.Where("message_id = ?", messageID).Order("edited_at ASC").Find(&edits).Error
+	return edits, err
+}
+
+// SetReaction adds or replaces the reaction of a participant. The message counts as changed,
+// so it is part of the next sync.
+func (r *matchRepository) SetReaction(reaction *models.MessageReaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "message_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"emoji", "created_at"}),
+		}).Create(reaction).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("id = ?", reaction.MessageID).Update("updated_at", time.Now()).Error
+	})
 }
 
-func (r *matchRepository) GetMessages(matchRoomID uuid.UUID) ([]models.Message, error) {
+// DeleteReaction removes the reaction of a participant, reporting false when there was none.
+func (r *matchRepository) DeleteReaction(messageID, userID uuid.UUID) (bool, error) {
+	deleted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("message_id = ? AND user_id = ?", messageID, userID).Delete(&models.MessageReaction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return tx.Model(&models.Message{}).Where("id = ?", messageID).Update("updated_at", time.Now()).Error
+	})
+	return deleted, err
+}
+
+// GetMessagesBefore returns the messages older than the (before, beforeID) key, newest first.
+// A zero before starts from the latest message. Like the other listings of a room's
+// messages it leaves out the ones held for review.
+func (r *matchRepository) GetMessagesBefore(matchRoomID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]models.Message, error) {
+	query := r.db.Preload("Reactions").Preload("Attachment").Where("match_room_id = ? AND held_at IS NULL", matchRoomID)
+	if !before.IsZero() {
+		query = query.Where("(created_at, id) < (?, ?)", before, beforeID)
+	}
+
+	var messages []models.Message
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// GetMessagesAfter returns the messages newer than the (after, afterID) key, oldest first.
+func (r *matchRepository) GetMessagesAfter(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Preload("Reactions").Preload("Attachment").
+		Where("match_room_id = ? AND held_at IS NULL AND (created_at, id) > (?, ?)", matchRoomID, after, afterID).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetMessageUpdates returns the messages created, edited, unsent, reacted to or deleted, in
+// the order they changed. Pages continue after the (after, afterID) key of the last change
+// served. Deleted messages are included with DeletedAt set.
+func (r *matchRepository) GetMessageUpdates(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error) {
+	const changedAt = "GREATEST(updated_at, COALESCE(deleted_at, updated_at))"
+
 	var messages []models.Message
-	err := r.db.Where("match_room_id = ?", matchRoomID).Find(&messages).Error
+	// Preloads are unscoped too, the attachments of unsent messages have to be left out
+	err := r.db.Unscoped().
+		Preload("Reactions").Preload("Attachment", "deleted_at IS NULL").
+		Where("match_room_id = ? AND held_at IS NULL", matchRoomID).
+		Where("("+changedAt+", id) > (?, ?)", after, afterID).
+		Order(changedAt + " ASC, id ASC").
+		Limit(limit).
+		Find(&messages).Error
 	return messages, err
 }
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type ModerationRepository interface {
+	OpenCase(moderationCase *models.ModerationCase, note *models.CaseNote) (*models.ModerationCase, error)
+	CreateReport(report *models.Report, moderationCase *models.ModerationCase) (*models.ModerationCase, error)
+	MarkProfileHidden(caseID uuid.UUID, hiddenAt time.Time) error
+	GetCase(id uuid.UUID) (*models.ModerationCase, error)
+	GetCases(filter models.CaseFilter, afterDueAt time.Time, afterID uuid.UUID, limit int) ([]models.ModerationCase, error)
+	UpdateCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote) error
+	ResolveCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote, now time.Time) error
+	CreateNote(note *models.CaseNote) error
+	GetMetrics(now, resolvedSince time.Time) (*models.ModerationMetrics, error)
+}
+
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &moderationRepository{db: db}
+}
+
+// OpenCase adds the note to the unresolved case of the user, opening moderationCase when
+// there is none. A flag the case already has, from the same source and with the same
+// summary, is not added again.
+func (r *moderationRepository) OpenCase(moderationCase *models.ModerationCase, note *models.CaseNote) (*models.ModerationCase, error) {
+	var opened *models.ModerationCase
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		opened, err = fileOnCase(tx, moderationCase)
+		if err != nil {
+			return err
+		}
+		if opened.ID != moderationCase.ID {
+			var flagged int64
+			err := tx.Model(&models.CaseNote{}).Where("case_id = ? AND source = ? AND body = ?", opened.ID, note.Source, note.Body).Count(&flagged).Error
+			if err != nil || flagged > 0 {
+				return err
+			}
+		}
+
+		note.CaseID = opened.ID
+		return tx.Create(note).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return opened, nil
+}
+
+// CreateReport files the report on the unresolved case of the reported user, opening
+// moderationCase when there is none, and returns the case with the number of users who
+// reported on it.
+func (r *moderationRepository) CreateReport(report *models.Report, moderationCase *models.ModerationCase) (*models.ModerationCase, error) {
+	var filed *models.ModerationCase
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		filed, err = fileOnCase(tx, moderationCase)
+		if err != nil {
+			return err
+		}
+
+		report.CaseID = filed.ID
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Report{}).
+			Where("case_id = ?", filed.ID).
+			Distinct("reporter_id").
+			Count(&filed.ReporterCount).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filed, nil
+}
+
+// fileOnCase returns the unresolved case of the user of moderationCase, escalated to its
+// priority and due date when those are more pressing, or creates moderationCase.
+func fileOnCase(tx *gorm.DB, moderationCase *models.ModerationCase) (*models.ModerationCase, error) {
+	// Concurrent flags of the same user must not open a case each
+	err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "moderation_case:"+moderationCase.UserID.String()).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.ModerationCase
+	err = tx.Where("user_id = ? AND status <> ?", moderationCase.UserID, models.CaseResolved).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return moderationCase, tx.Create(moderationCase).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if moderationCase.DueAt.Before(existing.DueAt) {
+		existing.DueAt = moderationCase.DueAt
+		existing.Priority = moderationCase.Priority
+		err := tx.Model(&existing).Updates(map[string]interface{}{"due_at": existing.DueAt, "priority": existing.Priority}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &existing, nil
+}
+
+func (r *moderationRepository) MarkProfileHidden(caseID uuid.UUID, hiddenAt time.Time) error {
+	return r.db.Model(&models.ModerationCase{}).Where("id = ?", caseID).Update("profile_hidden_at", hiddenAt).Error
+}
+
+// GetCase returns the case with its reports and notes, the oldest first.
+func (r *moderationRepository) GetCase(id uuid.UUID) (*models.ModerationCase, error) {
+	var moderationCase models.ModerationCase
+	err := r.db.
+		Preload("Reports", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Reports.Messages").
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Where("id = ?", id).
+		First(&moderationCase).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Report{}).Where("case_id = ?", id).Distinct("reporter_id").Count(&moderationCase.ReporterCount).Error
+	if err != nil {
+		return nil, err
+	}
+	return &moderationCase, nil
+}
+
+// GetCases returns the cases matching filter, the soonest due first. Pages continue after
+// the (afterDueAt, afterID) key of the last case served.
+func (r *moderationRepository) GetCases(filter models.CaseFilter, afterDueAt time.Time, afterID uuid.UUID, limit int) ([]models.ModerationCase, error) {
+	query := r.db.Model(&models.ModerationCase{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.AssigneeID != nil {
+		query = query.Where("assignee_id = ?", *filter.AssigneeID)
+	}
+	if filter.Unassigned {
+		query = query.Where("assignee_id IS NULL")
+	}
+	if !filter.OverdueAt.IsZero() {
+		query = query.Where("status <> ? AND due_at < ?", models.CaseResolved, filter.OverdueAt)
+	}
+	if !afterDueAt.IsZero() {
+		query = query.Where("(due_at, id) > (?, ?)", afterDueAt, afterID)
+	}
+
+	var cases []models.ModerationCase
+	err := query.Order("due_at, id").Limit(limit).Find(&cases).Error
+	return cases, err
+}
+
+// UpdateCase saves the workflow fields of the case together with a note, provided the case
+// is still in the from status. Otherwise it is gorm.ErrRecordNotFound.
+func (r *moderationRepository) UpdateCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateCase(tx, moderationCase, from, note)
+	})
+}
+
+// ResolveCase saves the resolved case like UpdateCase and carries its decision out on the
+// user and their profile, all or nothing.
+func (r *moderationRepository) ResolveCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateCase(tx, moderationCase, from, note); err != nil {
+			return err
+		}
+
+		userID := moderationCase.UserID
+		switch *moderationCase.Decision {
+		case models.DecisionWarn:
+			if err := addWarning(tx, userID); err != nil {
+				return err
+			}
+		case models.DecisionRemoveContent:
+			if err := clearContent(tx, userID); err != nil {
+				return err
+			}
+		case models.DecisionSuspend:
+			if err := setSuspendedUntil(tx, userID, moderationCase.SuspendUntil); err != nil {
+				return err
+			}
+			return setHidden(tx, userID, &now)
+		case models.DecisionBan:
+			if err := ban(tx, userID, now); err != nil {
+				return err
+			}
+			return setHidden(tx, userID, &now)
+		}
+
+		// The user keeps using the app, so a profile hidden pending the review shows again,
+		// unless the user is barred or another case keeps it hidden meanwhile
+		if moderationCase.ProfileHiddenAt == nil {
+			return nil
+		}
+		return showProfile(tx, userID, now)
+	})
+}
+
+func updateCase(tx *gorm.DB, moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote) error {
+	result := tx.Model(moderationCase).
+		Where("status = ?", from).
+		Select("status", "assignee_id", "decision", "decided_by_id", "resolved_at", "suspend_until", "updated_at").
+		Updates(moderationCase)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	note.CaseID = moderationCase.ID
+	return tx.Create(note).Error
+}
+
+func (r *moderationRepository) CreateNote(note *models.CaseNote) error {
+	return r.db.Create(note).Error
+}
+
+// GetMetrics sums up the unresolved cases at now and the cases resolved since
+// resolvedSince.
+func (r *moderationRepository) GetMetrics(now, resolvedSince time.Time) (*models.ModerationMetrics, error) {
+	metrics := &models.ModerationMetrics{BySource: map[models.CaseSource]int64{}}
+	unresolved := r.db.Model(&models.ModerationCase{}).Where("status <> ?", models.CaseResolved)
+
+	var queue struct {
+		Open         int64
+		InReview     int64
+		Unassigned   int64
+		Overdue      int64
+		OldestOpenAt *time.Time
+	}
+	err := unresolved.Session(&gorm.Session{}).
+		Select(`COUNT(*) FILTER (WHERE status = ?) AS open,
+			COUNT(*) FILTER (WHERE status = ?) AS in_review,
+			COUNT(*) FILTER (WHERE assignee_id IS NULL) AS unassigned,
+			COUNT(*) FILTER (WHERE due_at < ?) AS overdue,
+			MIN(created_at) AS oldest_open_at`, models.CaseOpen, models.CaseInReview, now).
+		Scan(&queue).Error
+	if err != nil {
+		return nil, err
+	}
+	metrics.Open, metrics.InReview, metrics.Unassigned, metrics.Overdue, metrics.OldestOpenAt = queue.Open, queue.InReview, queue.Unassigned, queue.Overdue, queue.OldestOpenAt
+
+	var bySource []struct {
+		Source models.CaseSource
+		Count  int64
+	}
+	err = unresolved.Session(&gorm.Session{}).Select("source, COUNT(*) AS count").Group("source").Scan(&bySource).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range bySource {
+		metrics.BySource[row.Source] = row.Count
+	}
+
+	var resolved struct {
+		Resolved          int64
+		ResolvedWithinSLA int64
+		AverageSeconds    *float64
+	}
+	err = r.db.Model(&models.ModerationCase{}).
+		Where("status = ? AND resolved_at >= ?", models.CaseResolved, resolvedSince).
+		Select(`COUNT(*) AS resolved,
+			COUNT(*) FILTER (WHERE resolved_at <= due_at) AS resolved_within_sla,
+			AVG(EXTRACT(EPOCH FROM resolved_at - created_at)) AS average_seconds`).
+		Scan(&resolved).Error
+	if err != nil {
+		return nil, err
+	}
+	metrics.Resolved, metrics.ResolvedWithinSLA = resolved.Resolved, resolved.ResolvedWithinSLA
+	if resolved.AverageSeconds != nil {
+		metrics.AverageResolveHours = *resolved.AverageSeconds / 3600
+	}
+
+	return metrics, nil
+}
@@ -1,17 +1,37 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProfileRepository interface {
 	CreateProfile(profile *models.Profile) error
 	GetProfileByID(id uuid.UUID) (*models.Profile, error)
+	GetProfileByUserID(userID uuid.UUID) (*models.Profile, error)
+	GetProfiles() ([]models.Profile, error)
+	GetProfilesByUserIDs(userIDs []uuid.UUID) ([]models.Profile, error)
 	UpdateProfile(profile *models.Profile) error
 	GetProfilesExcluding(excludeIDs []uuid.UUID, limit int) ([]models.Profile, error)
+	SetHidden(userID uuid.UUID, hiddenAt *time.Time) error
+	ShowProfile(userID uuid.UUID, now time.Time) error
+	AdjustRating(userID uuid.UUID, change float64) error
+	TouchLastActive(userID uuid.UUID, at time.Time) error
+	GetActivity(userIDs []uuid.UUID) ([]models.UserActivity, error)
+	GetPreferenceByUserID(userID uuid.UUID) (*models.Preference, error)
+	GetPreferencesByUserIDs(userIDs []uuid.UUID) ([]models.Preference, error)
+	UpsertPreference(preference *models.Preference) error
+	RecordImpressions(viewerID uuid.UUID, profileUserIDs []uuid.UUID, at time.Time) error
+	CountImpressions(profileUserID uuid.UUID, start, end time.Time) (int64, error)
 }
+
+// visible filters out the profiles hidden pending a moderation review.
+const visible = "profiles.hidden_at IS NULL"
+
 type profileRepository struct {
 	db *gorm.DB
 }
@@ -30,14 +50,123 @@ func (r *profileRepository) GetProfileByID(id uuid.UUID) (*models.Profile, error
 	return &profile, err
 }
 
+func (r *profileRepository) GetProfileByUserID(userID uuid.UUID) (*models.Profile, error) {
+	var profile models.Profile
+	err := r.db.First(&profile, "user_id = ?", userID).Error
+	return &profile, err
+}
+
+func (r *profileRepository) GetProfiles() ([]models.Profile, error) {
+	var profiles []models.Profile
+	err := r.db.Find(&profiles).Error
+	return profiles, err
+}
+
+func (r *profileRepository) GetProfilesByUserIDs(userIDs []uuid.UUID) ([]models.Profile, error) {
+	var profiles []models.Profile
+	err := r.db.Where("user_id IN ?", userIDs).Where(visible).Find(&profiles).Error
+	return profiles, err
+}
+
 func (r *profileRepository) UpdateProfile(profile *models.Profile) error {
 	return r.db.Save(profile).Error
 }
 
 func (r *profileRepository) GetProfilesExcluding(excludeIDs []uuid.UUID, limit int) ([]models.Profile, error) {
 	var profiles []models.Profile
-	query := r.db.Where("user_id NOT IN ?", excludeIDs)
+	query := r.db.Where("user_id NOT IN ?", excludeIDs).Where(visible)
 
-	err := query.Limit(limit).Find(&profiles).Error
+	err := query.Order("last_active_at DESC").Limit(limit).Find(&profiles).Error
 	return profiles, err
 }
+
+// SetHidden hides the profile of userID from discovery, or shows it again when hiddenAt is
+// nil.
+func (r *profileRepository) SetHidden(userID uuid.UUID, hiddenAt *time.Time) error {
+	return setHidden(r.db, userID, hiddenAt)
+}
+
+func setHidden(db *gorm.DB, userID uuid.UUID, hiddenAt *time.Time) error {
+	return db.Model(&models.Profile{}).Where("user_id = ?", userID).Update("hidden_at", hiddenAt).Error
+}
+
+func (r *profileRepository) ShowProfile(userID uuid.UUID, now time.Time) error {
+	return showProfile(r.db, userID, now)
+}
+
+// showProfile shows the profile of userID again, unless the user is barred at now or
+// another unresolved case keeps it hidden pending its review.
+func showProfile(db *gorm.DB, userID uuid.UUID, now time.Time) error {
+	return db.Model(&models.Profile{}).
+		Where("user_id = ?", userID).
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = profiles.user_id AND (users.banned_at IS NOT NULL OR users.suspended_until > ?))", now).
+		Where("NOT EXISTS (SELECT 1 FROM moderation_cases WHERE moderation_cases.user_id = profiles.user_id AND moderation_cases.status <> ? AND moderation_cases.profile_hidden_at IS NOT NULL AND moderation_cases.deleted_at IS NULL)", models.CaseResolved).
+		Update("hidden_at", nil).Error
+}
+
+// clearContent removes what the user wrote and uploaded to their profile.
+func clearContent(db *gorm.DB, userID uuid.UUID) error {
+	return db.Model(&models.Profile{}).Where("user_id = ?", userID).Updates(map[string]interface{}{"bio": "", "profile_image": "", "prompts": nil}).Error
+}
+
+func (r *profileRepository) AdjustRating(userID uuid.UUID, change float64) error {
+	return adjustRating(r.db, userID, change)
+}
+
+// adjustRating adds change to the rating of userID in place, so concurrent changes add up.
+func adjustRating(db *gorm.DB, userID uuid.UUID, change float64) error {
+	return db.Model(&models.Profile{}).Where("user_id = ?", userID).Update("rating", gorm.Expr("rating + ?", change)).Error
+}
+
+func (r *profileRepository) TouchLastActive(userID uuid.UUID, at time.Time) error {
+	return r.db.Model(&models.Profile{}).Where("user_id = ?", userID).Update("last_active_at", at).Error
+}
+
+func (r *profileRepository) GetActivity(userIDs []uuid.UUID) ([]models.UserActivity, error) {
+	var activity []models.UserActivity
+	err := r.db.Table("profiles").
+		Select("profiles.user_id, profiles.last_active_at, users.hide_presence").
+		Joins("JOIN users ON users.id = profiles.user_id").
+		Where("profiles.user_id IN ? AND profiles.deleted_at IS NULL", userIDs).
+		Scan(&activity).Error
+	return activity, err
+}
+
+func (r *profileRepository) GetPreferenceByUserID(userID uuid.UUID) (*models.Preference, error) {
+	var preference models.Preference
+	err := r.db.First(&preference, "user_id = ?", userID).Error
+	return &preference, err
+}
+
+func (r *profileRepository) GetPreferencesByUserIDs(userIDs []uuid.UUID) ([]models.Preference, error) {
+	var preferences []models.Preference
+	err := r.db.Where("user_id IN ?", userIDs).Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *profileRepository) UpsertPreference(preference *models.Preference) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"gender", "min_age", "max_age", "max_distance_km", "updated_at"}),
+	}).Create(preference).Error
+}
+
+func (r *profileRepository) RecordImpressions(viewerID uuid.UUID, profileUserIDs []uuid.UUID, at time.Time) error {
+	if len(profileUserIDs) == 0 {
+		return nil
+	}
+
+	impressions := make([]models.ProfileImpression, len(profileUserIDs))
+	for i, profileUserID := range profileUserIDs {
+		impressions[i] = models.ProfileImpression{ViewerID: viewerID, ProfileUserID: profileUserID, ShownAt: at}
+	}
+	return r.db.Create(&impressions).Error
+}
+
+func (r *profileRepository) CountImpressions(profileUserID uuid.UUID, start, end time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ProfileImpression{}).
+		Where("profile_user_id = ? AND shown_at >= ? AND shown_at < ?", profileUserID, start, end).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+type QuotaRepository interface {
+	Increment(userID uuid.UUID, quota string, windowStart time.Time, limit int) (int, bool, error)
+	Decrement(userID uuid.UUID, quota string, windowStart time.Time) error
+	GetCounters(userID uuid.UUID, since time.Time) ([]models.QuotaCounter, error)
+}
+
+type quotaRepository struct {
+	db *gorm.DB
+}
+
+func NewQuotaRepository(db *gorm.DB) QuotaRepository {
+	return &quotaRepository{db: db}
+}
+
+// Increment atomically adds one use to the counter, unless that would take it past limit.
+// It returns the new usage and whether the increment happened. A negative limit never blocks.
+func (r *quotaRepository) Increment(userID uuid.UUID, quota string, windowStart time.Time, limit int) (int, bool, error) {
+	var used []int
+	err := r.db.Raw(`INSERT INTO quota_counters (id, user_id, quota, window_start, used, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, NOW(), NOW())
+		ON CONFLICT (user_id, quota, window_start)
+		DO UPDATE SET used = quota_counters.used + 1, updated_at = NOW()
+		WHERE ? < 0 OR quota_counters.used < ?
+		RETURNING used`, uuid.New(), userID, quota, windowStart, limit, limit).Scan(&used).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if len(used) == 0 {
+		return limit, false, nil
+	}
+	return used[0], true, nil
+}
+
+func (r *quotaRepository) Decrement(userID uuid.UUID, quota string, windowStart time.Time) error {
+	return r.db.Model(&models.QuotaCounter{}).
+		Where("user_id = ? AND quota = ? AND window_start = ? AND used > 0", userID, quota, windowStart).
+		Update("used", gorm.Expr("used - 1")).Error
+}
+
+func (r *quotaRepository) GetCounters(userID uuid.UUID, since time.Time) ([]models.QuotaCounter, error) {
+	var counters []models.QuotaCounter
+	err := r.db.Where("user_id = ? AND window_start >= ?", userID, since).Find(&counters).Error
+	return counters, err
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,11 +10,43 @@ import (
 )
 
 type SwipeRepository interface {
-	GetSwipedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error)
-	CreateSwipe(swipe *models.Swipe) error
+	GetSwipedUsersID(userID uuid.UUID, passedSince time.Time) ([]uuid.UUID, error)
+	GetSwipeHistory(userID uuid.UUID, types []models.SwipeType, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error)
+	CreateSwipe(swipe *models.Swipe) (*models.SwipeResult, error)
 	GetSwipe(userID, targetUserID uuid.UUID) (*models.Swipe, error)
+	GetSwipeByIdempotencyKey(userID uuid.UUID, key string) (*models.Swipe, error)
+	GetSwipesSince(since time.Time) ([]models.Swipe, error)
+	GetSuperLikerIDs(userID uuid.UUID) ([]uuid.UUID, error)
+	GetPendingLikes(userID uuid.UUID, filter LikesFilter, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error)
+	CountPendingLikes(userID uuid.UUID, filter LikesFilter) (int64, error)
+	CountLikesReceived(userID uuid.UUID, start, end time.Time) (int64, error)
+	GetLastSwipe(userID uuid.UUID) (*models.Swipe, error)
+	RewindSwipe(swipe *models.Swipe, matchID *uuid.UUID) error
+	SetRatingChange(id uuid.UUID, change float64) error
 }
 
+// LikesFilter narrows pending likes down to senders matching the receiver's preferences.
+// Zero values leave a criterion out. Like the recommender, senders whose profile lacks the
+// data for a criterion are kept rather than filtered out.
+type LikesFilter struct {
+	Gender string
+	// Senders must be born after BornAfter and on or before BornBefore
+	BornAfter  time.Time
+	BornBefore time.Time
+	// Origin and MaxDistanceKm limit the distance from the receiver
+	Latitude      float64
+	Longitude     float64
+	MaxDistanceKm float64
+}
+
+// unanswered matches swipes the target has not swiped back on.
+const unanswered = "NOT EXISTS (SELECT 1 FROM swipes answer WHERE answer.user_id = swipes.target_user_id AND answer.target_user_id = swipes.user_id AND answer.deleted_at IS NULL)"
+
+// notBlocked matches swipes between users neither of whom blocked the other.
+const notBlocked = `NOT EXISTS (SELECT 1 FROM blocks b WHERE b.deleted_at IS NULL AND (
+	(b.user_id = swipes.user_id AND b.blocked_user_id = swipes.target_user_id) OR
+	(b.user_id = swipes.target_user_id AND b.blocked_user_id = swipes.user_id)))`
+
 type swipeRepository struct {
 	db *gorm.DB
 }
@@ -22,23 +55,144 @@ func NewSwipeRepository(db *gorm.DB) SwipeRepository {
 	return &swipeRepository{db: db}
 }
 
-func (r *swipeRepository) CreateSwipe(swipe *models.Swipe) error {
-	return r.db.Create(swipe).Error
-}
+// CreateSwipe records the swipe and, when it answers a like with a like, the match for both
+// participants in a single transaction. Swipes on the same pair are serialised by an
+// advisory lock, so two users liking each other at once produce exactly one match.
+//
+// A swipe with an idempotency key the user already swiped with is not recorded again, the
+// stored swipe is copied into swipe and the result is marked as replayed.
+func (r *swipeRepository) CreateSwipe(swipe *models.Swipe) (*models.SwipeResult, error) {
+	result := &models.SwipeResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", pairKey(swipe.UserID, swipe.TargetUserID)).Error
+		if err != nil {
+			return err
+		}
 
-func (r *swipeRepository) GetSwipedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error) {
-	var swipes []models.Swipe
-	err := r.db.Select("target_user_id").Where("user_id = ? AND DATE(created_at) = ?", userID, date).Find(&swipes).Error
+		if swipe.IdempotencyKey != "" {
+			var stored models.Swipe
+			err := tx.Where("user_id = ? AND idempotency_key = ?", swipe.UserID, swipe.IdempotencyKey).First(&stored).Error
+			if err == nil {
+				*swipe = stored
+				result.Replayed = true
+				return r.findMatch(tx, swipe, result)
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if err := tx.Create(swipe).Error; err != nil {
+			return err
+		}
+
+		if !swipe.Type.IsLike() {
+			return nil
+		}
+
+		var answer models.Swipe
+		err = tx.Where("user_id = ? AND target_user_id = ?", swipe.TargetUserID, swipe.UserID).
+			Order("created_at desc").
+			First(&answer).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !answer.Type.IsLike()) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result.SuperLikeMatch = swipe.Type == models.SwipeSuperLike || answer.Type == models.SwipeSuperLike
+
+		// The pair may already be matched, e.g. when the like was rewound and swiped again
+		if err := r.findMatch(tx, swipe, result); err != nil || result.Match != nil {
+			return err
+		}
+
+		// A pair that unmatched or blocked one another never matches again
+		if apart, err := separated(tx, swipe.UserID, swipe.TargetUserID); err != nil || apart {
+			return err
+		}
+
+		matchedByID := swipe.UserID
+		match := &models.MatchRoom{
+			ID:           uuid.New(),
+			Participants: []models.RoomParticipant{{UserID: swipe.UserID}, {UserID: swipe.TargetUserID}},
+			MatchedByID:  &matchedByID,
+		}
+		if err := tx.Create(match).Error; err != nil {
+			return err
+		}
+		result.Match = match
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+// findMatch sets an existing match of the swiper with the target on result.
+func (r *swipeRepository) findMatch(tx *gorm.DB, swipe *models.Swipe, result *models.SwipeResult) error {
+	match, err := matchBetween(tx, swipe.UserID, swipe.TargetUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	result.Match = match
+	return nil
+}
+
+// separated reports whether a pair of users unmatched or blocked one another, either way.
+func separated(tx *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var unmatches int64
+	err := tx.Model(&models.Unmatch{}).
+		Where("(user_id = ? AND target_user_id = ?) OR (user_id = ? AND target_user_id = ?)", a, b, b, a).
+		Count(&unmatches).Error
+	if err != nil || unmatches > 0 {
+		return unmatches > 0, err
+	}
+
+	var blocks int64
+	err = tx.Model(&models.Block{}).
+		Where("(user_id = ? AND blocked_user_id = ?) OR (user_id = ? AND blocked_user_id = ?)", a, b, b, a).
+		Count(&blocks).Error
+	return blocks > 0, err
+}
+
+// pairKey identifies an unordered pair of users, it is the same whichever of them swipes.
+func pairKey(a, b uuid.UUID) string {
+	if a.String() > b.String() {
+		a, b = b, a
+	}
+	return a.String() + ":" + b.String()
+}
+
+// GetSwipedUsersID returns the users userID liked at any time, and the users they passed
+// on since passedSince. A zero passedSince includes every pass.
+func (r *swipeRepository) GetSwipedUsersID(userID uuid.UUID, passedSince time.Time) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&models.Swipe{}).
+		Distinct("target_user_id").
+		Where("user_id = ? AND (type IN ? OR created_at >= ?)", userID, []models.SwipeType{models.SwipeLike, models.SwipeSuperLike}, passedSince).
+		Pluck("target_user_id", &userIDs).Error
+	return userIDs, err
+}
 
-	SwipedUserID := make([]uuid.UUID, len(swipes))
-	for i, swipe := range swipes {
-		SwipedUserID[i] = swipe.TargetUserID
+// GetSwipeHistory returns the swipes of userID of the given types, all types when empty,
+// newest first. Pages continue after the (before, beforeID) key of the last swipe served.
+func (r *swipeRepository) GetSwipeHistory(userID uuid.UUID, types []models.SwipeType, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+	if !before.IsZero() {
+		query = query.Where("(created_at, id) < (?, ?)", before, beforeID)
 	}
 
-	return SwipedUserID, nil
+	var swipes []models.Swipe
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&swipes).Error
+	return swipes, err
 }
 
 func (r *swipeRepository) GetSwipe(userID, targetUserID uuid.UUID) (*models.Swipe, error) {
@@ -51,3 +205,132 @@ func (r *swipeRepository) GetSwipe(userID, targetUserID uuid.UUID) (*models.Swip
 	}
 	return &swipe, nil
 }
+
+// GetSwipeByIdempotencyKey returns the swipe userID recorded under the idempotency key.
+func (r *swipeRepository) GetSwipeByIdempotencyKey(userID uuid.UUID, key string) (*models.Swipe, error) {
+	var swipe models.Swipe
+	if err := r.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&swipe).Error; err != nil {
+		return nil, err
+	}
+	return &swipe, nil
+}
+
+func (r *swipeRepository) GetSwipesSince(since time.Time) ([]models.Swipe, error) {
+	var swipes []models.Swipe
+	err := r.db.Where("created_at >= ?", since).Order("created_at asc").Find(&swipes).Error
+	return swipes, err
+}
+
+// GetSuperLikerIDs returns the users who super liked userID and have not been swiped on
+// by userID since.
+func (r *swipeRepository) GetSuperLikerIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	err := r.db.Model(&models.Swipe{}).
+		Where("target_user_id = ? AND type = ?", userID, models.SwipeSuperLike).
+		Where(unanswered).
+		Order("created_at asc").
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// GetPendingLikes returns the likes userID received and has not answered, newest first.
+// Pages continue after the (before, beforeID) key of the last like served, a zero before
+// starts at the newest.
+func (r *swipeRepository) GetPendingLikes(userID uuid.UUID, filter LikesFilter, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error) {
+	query := r.pendingLikes(userID, filter)
+	if !before.IsZero() {
+		query = query.Where("(swipes.created_at, swipes.id) < (?, ?)", before, beforeID)
+	}
+
+	var swipes []models.Swipe
+	err := query.Select("swipes.*").
+		Order("swipes.created_at DESC, swipes.id DESC").
+		Limit(limit).
+		Find(&swipes).Error
+	return swipes, err
+}
+
+func (r *swipeRepository) CountPendingLikes(userID uuid.UUID, filter LikesFilter) (int64, error) {
+	var count int64
+	err := r.pendingLikes(userID, filter).Count(&count).Error
+	return count, err
+}
+
+func (r *swipeRepository) pendingLikes(userID uuid.UUID, filter LikesFilter) *gorm.DB {
+	query := r.db.Model(&models.Swipe{}).
+		Joins("JOIN profiles ON profiles.user_id = swipes.user_id AND profiles.deleted_at IS NULL").
+		Where("swipes.target_user_id = ? AND swipes.type IN ?", userID, []models.SwipeType{models.SwipeLike, models.SwipeSuperLike}).
+		Where(unanswered).
+		Where(visible).
+		Where(notBlocked)
+
+	if filter.Gender != "" {
+		query = query.Where("(profiles.gender = '' OR LOWER(profiles.gender) = LOWER(?))", filter.Gender)
+	}
+	if !filter.BornAfter.IsZero() {
+		query = query.Where("(profiles.birth_date = ? OR profiles.birth_date > ?)", time.Time{}, filter.BornAfter)
+	}
+	if !filter.BornBefore.IsZero() {
+		query = query.Where("(profiles.birth_date = ? OR profiles.birth_date <= ?)", time.Time{}, filter.BornBefore)
+	}
+	if filter.MaxDistanceKm > 0 && (filter.Latitude != 0 || filter.Longitude != 0) {
+		// Haversine distance, see recommender.DistanceKm
+		query = query.Where(`((profiles.latitude = 0 AND profiles.longitude = 0) OR
+			2 * 6371 * ASIN(SQRT(
+				POWER(SIN(RADIANS(profiles.latitude - ?) / 2), 2) +
+				COS(RADIANS(?)) * COS(RADIANS(profiles.latitude)) * POWER(SIN(RADIANS(profiles.longitude - ?) / 2), 2)
+			)) <= ?)`, filter.Latitude, filter.Latitude, filter.Longitude, filter.MaxDistanceKm)
+	}
+
+	return query
+}
+
+func (r *swipeRepository) CountLikesReceived(userID uuid.UUID, start, end time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Swipe{}).
+		Where("target_user_id = ? AND type IN ? AND created_at >= ? AND created_at < ?", userID, []models.SwipeType{models.SwipeLike, models.SwipeSuperLike}, start, end).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *swipeRepository) GetLastSwipe(userID uuid.UUID) (*models.Swipe, error) {
+	var swipe models.Swipe
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at desc").
+		First(&swipe).Error
+	if err != nil {
+		return nil, err
+	}
+	return &swipe, nil
+}
+
+// RewindSwipe deletes the swipe, and the match it made when matchID is set, and takes the
+// rating change of the swipe back, in one transaction. A swipe rewound already is
+// gorm.ErrRecordNotFound.
+func (r *swipeRepository) RewindSwipe(swipe *models.Swipe, matchID *uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", swipe.ID).Delete(&models.Swipe{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if matchID != nil {
+			if err := tx.Where("id = ?", *matchID).Delete(&models.MatchRoom{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Subtracted from the current rating, the target may have been swiped on since
+		if swipe.RatingChange == 0 {
+			return nil
+		}
+		return adjustRating(tx, swipe.TargetUserID, -swipe.RatingChange)
+	})
+}
+
+func (r *swipeRepository) SetRatingChange(id uuid.UUID, change float64) error {
+	return r.db.Model(&models.Swipe{}).Where("id = ?", id).UpdateColumn("rating_change", change).Error
+}
@@ -16,6 +16,8 @@ type UserRepository interface {
 	UpdateUser(user *models.User) error
 	FindAllPremiumUsers() ([]models.User, error)
 	UpdatePremiumStatus(userID uuid.UUID, isPremium bool, expiry time.Time) error
+	SetSuspendedUntil(userID uuid.UUID, until *time.Time) error
+	GetEndedSuspensions(now time.Time) ([]models.User, error)
 }
 
 type userRepository struct {
@@ -64,3 +66,31 @@ func (r *userRepository) FindAllPremiumUsers() ([]models.User, error) {
 func (r *userRepository) UpdatePremiumStatus(userID uuid.UUID, isPremium bool, expiry time.Time) error {
 	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(&models.User{IsPremium: isPremium, PremiumExpiryTime: expiry}).Error
 }
+
+// SetSuspendedUntil suspends the user until the given time, or lifts the suspension when
+// until is nil.
+func (r *userRepository) SetSuspendedUntil(userID uuid.UUID, until *time.Time) error {
+	return setSuspendedUntil(r.db, userID, until)
+}
+
+func setSuspendedUntil(db *gorm.DB, userID uuid.UUID, until *time.Time) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Update("suspended_until", until).Error
+}
+
+// addWarning counts one more warning against the user.
+func addWarning(db *gorm.DB, userID uuid.UUID) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Update("warnings", gorm.Expr("warnings + 1")).Error
+}
+
+// ban bars the user from the app for good as of at.
+func ban(db *gorm.DB, userID uuid.UUID, at time.Time) error {
+	return db.Model(&models.User{}).Where("id = ?", userID).Update("banned_at", at).Error
+}
+
+// GetEndedSuspensions returns the users whose suspension ran out by now and is yet to be
+// lifted.
+func (r *userRepository) GetEndedSuspensions(now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("suspended_until <= ? AND banned_at IS NULL", now).Find(&users).Error
+	return users, err
+}
@@ -4,47 +4,133 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mdzakyabd/dating-app/app/handler"
 	"github.com/mdzakyabd/dating-app/app/middleware"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
 )
 
 type AppRouteHandlers struct {
-	UserHandler    handler.UserHandler
-	ProfileHandler handler.ProfileHandler
-	SwipeHandler   handler.SwipeHandler
-	MatchHandler   handler.MatchHandler
+	UserHandler       handler.UserHandler
+	ProfileHandler    handler.ProfileHandler
+	SwipeHandler      handler.SwipeHandler
+	MatchHandler      handler.MatchHandler
+	QuotaHandler      handler.QuotaHandler
+	BoostHandler      handler.BoostHandler
+	AttachmentHandler handler.AttachmentHandler
+	SafetyHandler     handler.SafetyHandler
+	AdminHandler      handler.AdminHandler
+	ModerationHandler handler.ModerationHandler
+	IcebreakerHandler handler.IcebreakerHandler
+	// RealtimeHandler serves the events of the built-in hub, left nil where there is none
+	RealtimeHandler *handler.RealtimeHandler
 }
 
-func Routes(router *gin.Engine, handlers AppRouteHandlers, jwtSecret string) {
+func Routes(router *gin.Engine, handlers AppRouteHandlers, jwtSecret string, quotaUseCase usecase.QuotaUseCase, presenceUseCase usecase.PresenceUseCase, userUseCase usecase.UserUseCase) {
+	authenticated := []gin.HandlerFunc{middleware.JWTAuth(jwtSecret), middleware.ActiveAccount(userUseCase), middleware.Presence(presenceUseCase), middleware.QuotaHeaders(quotaUseCase)}
+
 	router.POST("/signup", handlers.UserHandler.Register)
 	router.POST("/login", handlers.UserHandler.Login)
 
+	if handlers.RealtimeHandler != nil {
+		// Authenticates itself and turns barred accounts away, as the token may come in the query string
+		router.GET("/ws", handlers.RealtimeHandler.Connect)
+		router.GET("/events/stream", middleware.JWTAuth(jwtSecret), middleware.ActiveAccount(userUseCase), handlers.RealtimeHandler.Stream)
+	}
+
+	// Signed links, authorized by their signature
+	router.GET("/attachments/:id", handlers.AttachmentHandler.Serve)
+	router.GET("/attachments/:id/thumbnail", handlers.AttachmentHandler.ServeThumbnail)
+
 	users := router.Group("/user")
-	users.Use(middleware.JWTAuth(jwtSecret))
+	users.Use(authenticated...)
 	{
 		users.PUT("", handlers.UserHandler.UpdateUser)
 		users.POST("/subscribe", handlers.UserHandler.SubscribePremium)
+		users.GET("/quotas", handlers.QuotaHandler.GetQuotas)
+	}
+
+	otherUsers := router.Group("/users")
+	otherUsers.Use(authenticated...)
+	{
+		otherUsers.POST("/:id/block", handlers.SafetyHandler.Block)
+		otherUsers.POST("/:id/report", handlers.SafetyHandler.Report)
 	}
 
 	profile := router.Group("/profile")
-	profile.Use(middleware.JWTAuth(jwtSecret))
+	profile.Use(authenticated...)
 	{
 		profile.POST("", handlers.ProfileHandler.CreateProfile)
 		profile.GET("", handlers.ProfileHandler.ViewProfiles)
+		profile.GET("/preferences", handlers.ProfileHandler.GetPreference)
+		profile.PUT("/preferences", handlers.ProfileHandler.UpdatePreference)
 		profile.GET("/:id", handlers.ProfileHandler.GetProfileByID)
 		profile.PUT("/:id", handlers.ProfileHandler.UpdateProfile)
 	}
 
 	swipe := router.Group("/swipes")
-	swipe.Use(middleware.JWTAuth(jwtSecret))
+	swipe.Use(authenticated...)
 	{
 		swipe.POST("", handlers.SwipeHandler.Swipe)
+		swipe.POST("/rewind", handlers.SwipeHandler.Rewind)
+		swipe.GET("/likes-received", handlers.SwipeHandler.LikesReceived)
+		swipe.GET("/history", handlers.SwipeHandler.History)
+	}
+
+	boosts := router.Group("/boosts")
+	boosts.Use(authenticated...)
+	{
+		boosts.POST("", handlers.BoostHandler.StartBoost)
+		boosts.GET("/:id/summary", handlers.BoostHandler.GetSummary)
 	}
 
 	chatRoom := router.Group("/chat-rooms")
-	chatRoom.Use(middleware.JWTAuth(jwtSecret))
+	chatRoom.Use(authenticated...)
 	{
 		chatRoom.GET("", handlers.MatchHandler.GetMatchRooms)
 		chatRoom.DELETE("/:id", handlers.MatchHandler.DeleteMatchRoom)
+		chatRoom.POST("/:id/read", handlers.MatchHandler.MarkRead)
+		chatRoom.POST("/:id/typing", handlers.MatchHandler.Typing)
+		chatRoom.POST("/:id/extend", handlers.MatchHandler.ExtendMatch)
+		chatRoom.GET("/:id/icebreakers", handlers.IcebreakerHandler.GetIcebreakers)
 		chatRoom.POST("/messages", handlers.MatchHandler.CreateMessage)
-		chatRoom.GET("/:match_room_id/messages", handlers.MatchHandler.GetMessages)
+		chatRoom.GET("/:id/messages", handlers.MatchHandler.GetMessages)
+		chatRoom.GET("/:id/messages/updates", handlers.MatchHandler.GetMessageUpdates)
+		chatRoom.PATCH("/:id/messages/:message_id", handlers.MatchHandler.EditMessage)
+		chatRoom.DELETE("/:id/messages/:message_id", handlers.MatchHandler.UnsendMessage)
+		chatRoom.GET("/:id/messages/:message_id/edits", handlers.MatchHandler.GetMessageEdits)
+		chatRoom.PUT("/:id/messages/:message_id/reaction", handlers.MatchHandler.React)
+		chatRoom.DELETE("/:id/messages/:message_id/reaction", handlers.MatchHandler.Unreact)
+		chatRoom.POST("/:id/messages/:message_id/unwanted", handlers.MatchHandler.MarkUnwanted)
+		chatRoom.POST("/:id/attachments", handlers.AttachmentHandler.CreateUploadSlot)
+		chatRoom.PUT("/:id/attachments/:attachment_id", handlers.AttachmentHandler.Upload)
+		chatRoom.GET("/:id/attachments/:attachment_id", handlers.AttachmentHandler.GetURLs)
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(middleware.JWTAuth(jwtSecret), middleware.ActiveAccount(userUseCase))
+	{
+		admin.GET("/analytics/unmatch-reasons", middleware.RequireRole(models.RoleAdmin), handlers.AdminHandler.UnmatchReasons)
+	}
+
+	icebreakers := admin.Group("/icebreakers")
+	icebreakers.Use(middleware.RequireRole(models.RoleAdmin))
+	{
+		icebreakers.GET("", handlers.IcebreakerHandler.GetTemplates)
+		icebreakers.POST("", handlers.IcebreakerHandler.CreateTemplate)
+		icebreakers.PUT("/:id", handlers.IcebreakerHandler.UpdateTemplate)
+		icebreakers.DELETE("/:id", handlers.IcebreakerHandler.DeleteTemplate)
+	}
+
+	moderation := admin.Group("/moderation")
+	moderation.Use(middleware.RequireRole(models.RoleAdmin, models.RoleModerator))
+	{
+		moderation.GET("/cases", handlers.ModerationHandler.GetCases)
+		moderation.POST("/cases", handlers.ModerationHandler.OpenCase)
+		moderation.GET("/cases/:id", handlers.ModerationHandler.GetCase)
+		moderation.POST("/cases/:id/assign", handlers.ModerationHandler.Assign)
+		moderation.POST("/cases/:id/release", handlers.ModerationHandler.Release)
+		moderation.POST("/cases/:id/notes", handlers.ModerationHandler.AddNote)
+		moderation.POST("/cases/:id/decision", handlers.ModerationHandler.Decide)
+		moderation.GET("/metrics", handlers.ModerationHandler.GetMetrics)
+		moderation.POST("/messages/:id/release", handlers.ModerationHandler.ReleaseMessage)
 	}
 }
@@ -1,31 +1,94 @@
 package scheduler
 
 import (
+	"log"
 	"time"
 
+	"github.com/mdzakyabd/dating-app/app/realtime"
 	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/app/usecase"
 )
 
 type Scheduler struct {
-	userRepo repository.UserRepository
+	userRepo          repository.UserRepository
+	matchUseCase      usecase.MatchUsecase
+	attachmentUseCase usecase.AttachmentUseCase
+	moderationUseCase usecase.ModerationUseCase
+	publisher         realtime.RealtimePublisher
 }
 
-func NewScheduler(userRepo repository.UserRepository) *Scheduler {
-	return &Scheduler{userRepo}
+func NewScheduler(userRepo repository.UserRepository, matchUseCase usecase.MatchUsecase, attachmentUseCase usecase.AttachmentUseCase, moderationUseCase usecase.ModerationUseCase, publisher realtime.RealtimePublisher) *Scheduler {
+	return &Scheduler{userRepo, matchUseCase, attachmentUseCase, moderationUseCase, publisher}
 }
 
 func (s *Scheduler) Start() {
 	ticker := time.NewTicker(24 * time.Hour)
+	purgeTicker := time.NewTicker(time.Hour)
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
 				s.checkExpiredSubscriptions()
+			case now := <-purgeTicker.C:
+				s.purgeUnmatchedMessages(now)
+				s.purgeAttachments(now)
+				s.liftEndedSuspensions(now)
+				s.expireMatches(now)
+				s.remindExpiringMatches(now)
 			}
 		}
 	}()
 }
 
+// purgeUnmatchedMessages removes the messages of rooms unmatched past their retention.
+func (s *Scheduler) purgeUnmatchedMessages(now time.Time) {
+	if _, err := s.matchUseCase.PurgeUnmatchedMessages(now); err != nil {
+		log.Printf("scheduler: purging unmatched messages failed: %v", err)
+	}
+}
+
+// purgeAttachments removes the files nobody can get at anymore.
+func (s *Scheduler) purgeAttachments(now time.Time) {
+	if _, err := s.attachmentUseCase.PurgeAttachments(now); err != nil {
+		log.Printf("scheduler: purging attachments failed: %v", err)
+	}
+}
+
+// liftEndedSuspensions lets the users whose suspension ran out back in.
+func (s *Scheduler) liftEndedSuspensions(now time.Time) {
+	if _, err := s.moderationUseCase.LiftEndedSuspensions(now); err != nil {
+		log.Printf("scheduler: lifting ended suspensions failed: %v", err)
+	}
+}
+
+// expireMatches closes the matches nobody wrote in, or answered, in time.
+func (s *Scheduler) expireMatches(now time.Time) {
+	expired, err := s.matchUseCase.ExpireMatches(now)
+	for _, expiry := range expired {
+		s.publish(realtime.MatchRoomChannel(expiry.MatchRoomID), realtime.EventMatchExpired, expiry)
+	}
+	if err != nil {
+		log.Printf("scheduler: expiring matches failed: %v", err)
+	}
+}
+
+// remindExpiringMatches lets the participants of the matches about to expire know.
+func (s *Scheduler) remindExpiringMatches(now time.Time) {
+	expiring, err := s.matchUseCase.RemindExpiringMatches(now)
+	for _, expiry := range expiring {
+		s.publish(realtime.MatchRoomChannel(expiry.MatchRoomID), realtime.EventMatchExpiring, expiry)
+	}
+	if err != nil {
+		log.Printf("scheduler: reminding of expiring matches failed: %v", err)
+	}
+}
+
+func (s *Scheduler) publish(channel, event string, data interface{}) {
+	if err := s.publisher.Publish(channel, event, data); err != nil {
+		log.Printf("scheduler: publishing %s on %s failed: %v", event, channel, err)
+	}
+}
+
 func (s *Scheduler) checkExpiredSubscriptions() {
 	users, err := s.userRepo.FindAllPremiumUsers()
 	if err != nil {
@@ -0,0 +1,28 @@
+package screening
+
+import "regexp"
+
+var (
+	// phoneNumber is a run of digits long enough to be a phone number, however separated
+	phoneNumber = regexp.MustCompile(`\+?\d(?:[\s.\-()]*\d){7,14}`)
+	link        = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|me|ly|co|app|xyz|info|biz|link)\b`)
+)
+
+// contactDetector flags phone numbers and links, which take the conversation off the app
+// and out of reach of the other checks.
+type contactDetector struct{}
+
+func NewContactDetector() Stage {
+	return contactDetector{}
+}
+
+func (contactDetector) Screen(content string) []Finding {
+	var findings []Finding
+	if phoneNumber.MatchString(content) {
+		findings = append(findings, Finding{Flag: "phone_number", Score: 3})
+	}
+	if link.MatchString(content) {
+		findings = append(findings, Finding{Flag: "url", Score: 3})
+	}
+	return findings
+}
@@ -0,0 +1,86 @@
+package screening
+
+import "strings"
+
+// DefaultWordlist is the profanity and harassment the wordlist stage looks for. Profanity
+// on its own only nudges, harassment and slurs are held.
+var DefaultWordlist = map[string]Finding{
+	"fuck":          {Flag: "profanity", Score: 3},
+	"fucking":       {Flag: "profanity", Score: 3},
+	"shit":          {Flag: "profanity", Score: 3},
+	"bitch":         {Flag: "profanity", Score: 3},
+	"bastard":       {Flag: "profanity", Score: 3},
+	"asshole":       {Flag: "profanity", Score: 3},
+	"dick":          {Flag: "profanity", Score: 3},
+	"cock":          {Flag: "profanity", Score: 3},
+	"pussy":         {Flag: "profanity", Score: 3},
+	"slut":          {Flag: "harassment", Score: 8},
+	"whore":         {Flag: "harassment", Score: 8},
+	"cunt":          {Flag: "harassment", Score: 8},
+	"retard":        {Flag: "harassment", Score: 8},
+	"faggot":        {Flag: "harassment", Score: 8},
+	"kys":           {Flag: "harassment", Score: 8},
+	"kill yourself": {Flag: "harassment", Score: 8},
+	"send nudes":    {Flag: "harassment", Score: 5},
+}
+
+// DefaultScamKeywords are what romance and investment scams tend to bring up, with how
+// telling each is. Moving the talk elsewhere or money on its own is common enough, together
+// they make for a hold.
+var DefaultScamKeywords = map[string]int{
+	"bitcoin":             3,
+	"crypto":              3,
+	"forex":               3,
+	"investment":          2,
+	"invest":              2,
+	"trading platform":    4,
+	"guaranteed profit":   5,
+	"guaranteed return":   5,
+	"wire transfer":       4,
+	"western union":       4,
+	"moneygram":           4,
+	"gift card":           4,
+	"send money":          4,
+	"cash app":            3,
+	"paypal":              2,
+	"sugar daddy":         4,
+	"verify your account": 4,
+	"verification code":   5,
+	"google voice":        4,
+	"whatsapp":            2,
+	"telegram":            2,
+	"hangouts":            2,
+}
+
+// phraseStage finds words and phrases in the words of a message.
+type phraseStage struct {
+	phrases map[string]Finding
+}
+
+// NewWordlist flags the words and phrases of wordlist.
+func NewWordlist(wordlist map[string]Finding) Stage {
+	return &phraseStage{wordlist}
+}
+
+// NewScamScorer adds up the weights of the scam keywords found, flagged as scam_keyword.
+func NewScamScorer(keywords map[string]int) Stage {
+	phrases := make(map[string]Finding, len(keywords))
+	for keyword, weight := range keywords {
+		phrases[keyword] = Finding{Flag: "scam_keyword", Score: weight}
+	}
+	return &phraseStage{phrases}
+}
+
+// Screen finds each phrase at most once, however often it comes up.
+func (s *phraseStage) Screen(content string) []Finding {
+	// Padded so that phrases only match whole words
+	text := " " + strings.Join(words(content), " ") + " "
+
+	var findings []Finding
+	for phrase, finding := range s.phrases {
+		if strings.Contains(text, " "+phrase+" ") {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
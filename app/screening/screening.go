@@ -0,0 +1,80 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+// Finding is something a stage found in the content of a message.
+type Finding struct {
+	Flag  string
+	Score int
+}
+
+// Stage is one check of the screening pipeline. It returns nothing for content it has no
+// objection to.
+type Stage interface {
+	Screen(content string) []Finding
+}
+
+type Screener interface {
+	// Screen runs content through every stage and decides, from the sum of the scores of
+	// their findings, whether the message is delivered, delivered with a nudge or held.
+	Screen(content string) models.MessageScreening
+}
+
+type pipeline struct {
+	stages []Stage
+	cfg    config.ScreeningConfig
+}
+
+func NewPipeline(cfg config.ScreeningConfig, stages ...Stage) Screener {
+	return &pipeline{stages, cfg}
+}
+
+// NewScreener is the pipeline of the built-in stages: the wordlist, contact details and
+// scam keywords.
+func NewScreener(cfg config.ScreeningConfig) Screener {
+	return NewPipeline(cfg, NewWordlist(DefaultWordlist), NewContactDetector(), NewScamScorer(DefaultScamKeywords))
+}
+
+func (p *pipeline) Screen(content string) models.MessageScreening {
+	screening := models.MessageScreening{Verdict: models.ScreeningDeliver}
+
+	flagged := make(map[string]bool)
+	for _, stage := range p.stages {
+		for _, finding := range stage.Screen(content) {
+			screening.Score += finding.Score
+			if !flagged[finding.Flag] {
+				flagged[finding.Flag] = true
+				screening.Flags = append(screening.Flags, finding.Flag)
+			}
+		}
+	}
+
+	sort.Strings(screening.Flags)
+
+	switch {
+	case p.cfg.HoldScore > 0 && screening.Score >= p.cfg.HoldScore:
+		screening.Verdict = models.ScreeningHold
+	case p.cfg.NudgeScore > 0 && screening.Score >= p.cfg.NudgeScore:
+		screening.Verdict = models.ScreeningNudge
+	}
+	return screening
+}
+
+// lookalikes undoes the usual character swaps that get words past a filter.
+var lookalikes = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// words splits content into lowercase words, with lookalike characters read as the
+// letters they stand in for.
+func words(content string) []string {
+	normalized := lookalikes.Replace(strings.ToLower(content))
+	return strings.FieldsFunc(normalized, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps the files of the app by key.
+type BlobStore interface {
+	Put(key string, r io.Reader) error
+	// Open returns ErrBlobNotFound when nothing is stored under key
+	Open(key string) (io.ReadCloser, error)
+	// Delete succeeds when nothing is stored under key
+	Delete(key string) error
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+)
+
+var ErrInvalidReportPeriod = errors.New("report period must start before it ends")
+
+// defaultReportPeriod is how far back a report goes when it is not given a start
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+// AnalyticsUseCase reports on the app for admins.
+type AnalyticsUseCase interface {
+	UnmatchReasons(from, to time.Time) (*models.UnmatchReasonReport, error)
+}
+
+type analyticsUseCase struct {
+	matchRepo repository.MatchRepository
+}
+
+func NewAnalyticsUseCase(matchRepo repository.MatchRepository) AnalyticsUseCase {
+	return &analyticsUseCase{matchRepo: matchRepo}
+}
+
+// UnmatchReasons breaks the unmatches from from up to to down by reason. A zero to is now
+// and a zero from is the default report period before to.
+func (uc *analyticsUseCase) UnmatchReasons(from, to time.Time) (*models.UnmatchReasonReport, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultReportPeriod)
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidReportPeriod
+	}
+
+	counts, err := uc.matchRepo.CountUnmatchReasons(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.UnmatchReasonReport{From: from, To: to, Reasons: []models.UnmatchReasonCount{}}
+	for _, count := range counts {
+		report.Reasons = append(report.Reasons, count)
+		report.Total += count.Count
+	}
+	return report, nil
+}
@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/media"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/app/storage"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrInvalidAttachmentKind = errors.New("attachment kind must be image or voice")
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrUnsupportedAttachment = errors.New("attachment format is not supported")
+	ErrVoiceNoteTooLong      = errors.New("voice note is too long")
+	ErrUploadSlotExpired     = errors.New("upload slot expired")
+	ErrAttachmentUploaded    = errors.New("attachment was already uploaded")
+	ErrInvalidAttachmentLink = errors.New("attachment link is invalid or expired")
+)
+
+const (
+	// AttachmentOriginal and AttachmentThumbnail are the files an attachment link points to
+	AttachmentOriginal  = "original"
+	AttachmentThumbnail = "thumbnail"
+
+	// unsentAttachmentRetention is how long after its slot expired an attachment that was
+	// never sent is kept
+	unsentAttachmentRetention = 24 * time.Hour
+	purgeBatchSize            = 100
+)
+
+type AttachmentUseCase interface {
+	CreateUploadSlot(matchRoomID, userID uuid.UUID, kind string) (*models.UploadSlot, error)
+	Upload(matchRoomID, attachmentID, userID uuid.UUID, file io.Reader) (*models.Attachment, error)
+	GetAttachmentURLs(matchRoomID, attachmentID, userID uuid.UUID) (*models.AttachmentURLs, error)
+	// OpenAttachment checks a link made by GetAttachmentURLs and opens the file it points to.
+	OpenAttachment(attachmentID uuid.UUID, variant, expires, signature string) (io.ReadCloser, string, error)
+	PurgeAttachments(now time.Time) (int, error)
+}
+
+type attachmentUseCase struct {
+	attachmentRepo repository.AttachmentRepository
+	matchRepo      repository.MatchRepository
+	blobStore      storage.BlobStore
+	cfg            config.AttachmentConfig
+}
+
+func NewAttachmentUseCase(attachmentRepo repository.AttachmentRepository, matchRepo repository.MatchRepository, blobStore storage.BlobStore, cfg config.AttachmentConfig) AttachmentUseCase {
+	return &attachmentUseCase{attachmentRepo, matchRepo, blobStore, cfg}
+}
+
+// CreateUploadSlot reserves an attachment of kind in a match room userID takes part in.
+func (uc *attachmentUseCase) CreateUploadSlot(matchRoomID, userID uuid.UUID, kind string) (*models.UploadSlot, error) {
+	maxSize, err := uc.maxSize(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		MatchRoomID:     matchRoomID,
+		UploaderID:      userID,
+		Kind:            kind,
+		UploadExpiresAt: time.Now().Add(uc.cfg.UploadWindow),
+	}
+	if err := uc.attachmentRepo.CreateAttachment(attachment); err != nil {
+		return nil, err
+	}
+
+	return &models.UploadSlot{
+		AttachmentID:    attachment.ID,
+		UploadURL:       fmt.Sprintf("/chat-rooms/%s/attachments/%s", matchRoomID, attachment.ID),
+		MaxSize:         maxSize,
+		UploadExpiresAt: attachment.UploadExpiresAt,
+	}, nil
+}
+
+// Upload validates the file of a slot userID created and stores it, with a thumbnail for
+// images. The type is taken from the content, whatever the client says it is.
+func (uc *attachmentUseCase) Upload(matchRoomID, attachmentID, userID uuid.UUID, file io.Reader) (*models.Attachment, error) {
+	attachment, err := uc.getAttachment(matchRoomID, attachmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.UploaderID != userID {
+		return nil, ErrAttachmentNotFound
+	}
+	if attachment.UploadedAt != nil {
+		return nil, ErrAttachmentUploaded
+	}
+	now := time.Now()
+	if now.After(attachment.UploadExpiresAt) {
+		return nil, ErrUploadSlotExpired
+	}
+
+	maxSize, err := uc.maxSize(attachment.Kind)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	var thumbnail []byte
+	switch attachment.Kind {
+	case models.AttachmentImage:
+		img, err := media.DecodeImage(data)
+		if errors.Is(err, media.ErrImageTooLarge) {
+			return nil, ErrAttachmentTooLarge
+		}
+		if err != nil {
+			return nil, ErrUnsupportedAttachment
+		}
+		if thumbnail, err = media.Thumbnail(img.Image, uc.cfg.ThumbnailSize); err != nil {
+			return nil, err
+		}
+		attachment.ContentType = img.ContentType
+		attachment.Width = img.Width
+		attachment.Height = img.Height
+	case models.AttachmentVoice:
+		audio, err := media.ParseAudio(data)
+		if err != nil {
+			return nil, ErrUnsupportedAttachment
+		}
+		if audio.Duration > uc.cfg.MaxVoiceDuration {
+			return nil, ErrVoiceNoteTooLong
+		}
+		attachment.ContentType = audio.ContentType
+		attachment.DurationMs = audio.Duration.Milliseconds()
+	}
+	attachment.Size = int64(len(data))
+	attachment.UploadedAt = &now
+
+	if err := uc.blobStore.Put(attachmentKey(attachment.ID, AttachmentOriginal), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if thumbnail != nil {
+		if err := uc.blobStore.Put(attachmentKey(attachment.ID, AttachmentThumbnail), bytes.NewReader(thumbnail)); err != nil {
+			return nil, err
+		}
+	}
+
+	uploaded, err := uc.attachmentRepo.MarkUploaded(attachment)
+	if err != nil {
+		return nil, err
+	}
+	if !uploaded {
+		return nil, ErrAttachmentUploaded
+	}
+	return attachment, nil
+}
+
+// GetAttachmentURLs signs short-lived links to an attachment for a participant of its room.
+// Until it is sent, only the uploader can get them.
+func (uc *attachmentUseCase) GetAttachmentURLs(matchRoomID, attachmentID, userID uuid.UUID) (*models.AttachmentURLs, error) {
+	attachment, err := uc.getAttachment(matchRoomID, attachmentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.UploadedAt == nil || (attachment.MessageID == nil && attachment.UploaderID != userID) {
+		return nil, ErrAttachmentNotFound
+	}
+
+	expiresAt := time.Now().Add(uc.cfg.URLLifetime).Truncate(time.Second)
+	urls := &models.AttachmentURLs{
+		URL:       uc.signURL(attachment.ID, AttachmentOriginal, expiresAt),
+		ExpiresAt: expiresAt,
+	}
+	if attachment.HasThumbnail() {
+		urls.ThumbnailURL = uc.signURL(attachment.ID, AttachmentThumbnail, expiresAt)
+	}
+	return urls, nil
+}
+
+func (uc *attachmentUseCase) OpenAttachment(attachmentID uuid.UUID, variant, expires, signature string) (io.ReadCloser, string, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, "", ErrInvalidAttachmentLink
+	}
+	if !utils.VerifySignature(uc.cfg.SigningKey, signature, attachmentID.String(), variant, expires) {
+		return nil, "", ErrInvalidAttachmentLink
+	}
+
+	// The link outlives an unsend, the attachment does not
+	attachment, err := uc.attachmentRepo.GetAttachment(attachmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := attachment.ContentType
+	if variant == AttachmentThumbnail {
+		contentType = "image/jpeg"
+	}
+
+	file, err := uc.blobStore.Open(attachmentKey(attachment.ID, variant))
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, "", ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return file, contentType, nil
+}
+
+// PurgeAttachments removes the files of unsent messages and of attachments that were never
+// sent, returning how many attachments were removed.
+func (uc *attachmentUseCase) PurgeAttachments(now time.Time) (int, error) {
+	purged := 0
+	for {
+		attachments, err := uc.attachmentRepo.GetPurgeableAttachments(now.Add(-unsentAttachmentRetention), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, attachment := range attachments {
+			for _, variant := range []string{AttachmentOriginal, AttachmentThumbnail} {
+				if err := uc.blobStore.Delete(attachmentKey(attachment.ID, variant)); err != nil {
+					return purged, err
+				}
+			}
+			if err := uc.attachmentRepo.DeleteAttachment(attachment.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(attachments) < purgeBatchSize {
+			if purged > 0 {
+				log.Printf("attachments: purged %d", purged)
+			}
+			return purged, nil
+		}
+	}
+}
+
+// getAttachment returns an attachment of a match room userID takes part in.
+func (uc *attachmentUseCase) getAttachment(matchRoomID, attachmentID, userID uuid.UUID) (*models.Attachment, error) {
+	if err := uc.checkMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := uc.attachmentRepo.GetAttachment(attachmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attachment.MatchRoomID != matchRoomID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+func (uc *attachmentUseCase) checkMember(matchRoomID, userID uuid.UUID) error {
+	_, err := uc.matchRepo.GetMatchRoom(matchRoomID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMatchRoomNotFound
+	}
+	return err
+}
+
+func (uc *attachmentUseCase) maxSize(kind string) (int64, error) {
+	switch kind {
+	case models.AttachmentImage:
+		return uc.cfg.MaxImageSize, nil
+	case models.AttachmentVoice:
+		return uc.cfg.MaxVoiceSize, nil
+	default:
+		return 0, ErrInvalidAttachmentKind
+	}
+}
+
+// signURL links to variant of an attachment until expiresAt, see OpenAttachment.
+func (uc *attachmentUseCase) signURL(attachmentID uuid.UUID, variant string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	signature := utils.Sign(uc.cfg.SigningKey, attachmentID.String(), variant, expires)
+
+	path := "/attachments/" + attachmentID.String()
+	if variant == AttachmentThumbnail {
+		path += "/thumbnail"
+	}
+	return path + "?expires=" + expires + "&signature=" + signature
+}
+
+func attachmentKey(attachmentID uuid.UUID, variant string) string {
+	return "attachments/" + attachmentID.String() + "/" + variant
+}
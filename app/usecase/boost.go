@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBoostActive   = errors.New("a boost is already running")
+	ErrBoostNotFound = errors.New("boost not found")
+)
+
+type BoostUseCase interface {
+	StartBoost(userID uuid.UUID) (*models.Boost, error)
+	GetSummary(userID, boostID uuid.UUID) (*models.BoostSummary, error)
+}
+
+type boostUseCase struct {
+	boostRepo    repository.BoostRepository
+	profileRepo  repository.ProfileRepository
+	swipeRepo    repository.SwipeRepository
+	quotaUseCase QuotaUseCase
+	cfg          config.BoostConfig
+}
+
+func NewBoostUseCase(boostRepo repository.BoostRepository, profileRepo repository.ProfileRepository, swipeRepo repository.SwipeRepository, quotaUseCase QuotaUseCase, cfg config.BoostConfig) BoostUseCase {
+	return &boostUseCase{boostRepo, profileRepo, swipeRepo, quotaUseCase, cfg}
+}
+
+func (uc *boostUseCase) StartBoost(userID uuid.UUID) (*models.Boost, error) {
+	now := time.Now()
+
+	_, err := uc.boostRepo.GetActiveBoost(userID, now)
+	if err == nil {
+		return nil, ErrBoostActive
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if _, err := uc.quotaUseCase.Consume(userID, models.QuotaBoosts); err != nil {
+		return nil, err
+	}
+
+	boost := &models.Boost{
+		UserID:   userID,
+		StartsAt: now,
+		EndsAt:   now.Add(uc.cfg.Duration),
+	}
+	// Another request may have started a boost since the check, its credit is given back
+	created, err := uc.boostRepo.CreateBoost(boost)
+	if err != nil || !created {
+		uc.quotaUseCase.Release(userID, models.QuotaBoosts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrBoostActive
+	}
+
+	return boost, nil
+}
+
+// GetSummary reports the views and likes the user got during the boost, and how many of
+// them are extra compared to their usual rate over the baseline window before it.
+func (uc *boostUseCase) GetSummary(userID, boostID uuid.UUID) (*models.BoostSummary, error) {
+	boost, err := uc.boostRepo.GetBoost(boostID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBoostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if boost.UserID != userID {
+		return nil, ErrBoostNotFound
+	}
+
+	now := time.Now()
+	end := boost.EndsAt
+	if now.Before(end) {
+		end = now
+	}
+	baselineStart := boost.StartsAt.Add(-uc.cfg.BaselineWindow)
+
+	summary := &models.BoostSummary{
+		BoostID:  boost.ID,
+		StartsAt: boost.StartsAt,
+		EndsAt:   boost.EndsAt,
+		Active:   now.Before(boost.EndsAt),
+	}
+
+	if summary.Views, err = uc.profileRepo.CountImpressions(userID, boost.StartsAt, end); err != nil {
+		return nil, err
+	}
+	baselineViews, err := uc.profileRepo.CountImpressions(userID, baselineStart, boost.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.Likes, err = uc.swipeRepo.CountLikesReceived(userID, boost.StartsAt, end); err != nil {
+		return nil, err
+	}
+	baselineLikes, err := uc.swipeRepo.CountLikesReceived(userID, baselineStart, boost.StartsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scale the baseline down to the length of the boost
+	ratio := float64(end.Sub(boost.StartsAt)) / float64(uc.cfg.BaselineWindow)
+	summary.ExtraViews = extraCount(summary.Views, float64(baselineViews)*ratio)
+	summary.ExtraLikes = extraCount(summary.Likes, float64(baselineLikes)*ratio)
+
+	return summary, nil
+}
+
+func extraCount(actual int64, expected float64) int64 {
+	extra := actual - int64(expected+0.5)
+	if extra < 0 {
+		return 0
+	}
+	return extra
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/utils"
+)
+
+// encodeKeyCursor returns the cursor of the page after the row with the given
+// (created_at, id) key, for lists ordered by that key.
+func encodeKeyCursor(createdAt time.Time, id uuid.UUID) string {
+	return utils.EncodeCursor(strconv.FormatInt(createdAt.UnixMicro(), 10), id.String())
+}
+
+func decodeKeyCursor(cursor string) (time.Time, uuid.UUID, error) {
+	if cursor == "" {
+		return time.Time{}, uuid.Nil, nil
+	}
+
+	values, err := utils.DecodeCursor(cursor, 2)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	micros, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, utils.ErrInvalidCursor
+	}
+	id, err := uuid.Parse(values[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, utils.ErrInvalidCursor
+	}
+
+	return time.UnixMicro(micros), id, nil
+}
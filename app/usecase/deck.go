@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"gorm.io/gorm"
+)
+
+// getDeck returns the user's current deck, generating a new one when there is none
+// yet or the previous one has expired.
+func (uc *profileUseCase) getDeck(userID uuid.UUID) (*models.Deck, error) {
+	deck, err := uc.deckRepo.GetDeck(userID)
+	if err == nil && time.Now().Before(deck.ExpiresAt) {
+		return deck, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return uc.generateDeck(userID)
+}
+
+func (uc *profileUseCase) generateDeck(userID uuid.UUID) (*models.Deck, error) {
+	excludedUserID, err := uc.excludedUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := uc.recommender.Recommend(userID, excludedUserID, uc.deckConfig.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whoever super liked the user goes to the front, ahead of the ranked profiles
+	superLikerIDs, err := uc.swipeRepo.GetSuperLikerIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Followed by the profiles with a running boost
+	boostedIDs, err := uc.boostedIDs(userID, excludedUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidateIDs := make([]uuid.UUID, 0, len(superLikerIDs)+len(boostedIDs)+len(profiles))
+	for _, superLikerID := range superLikerIDs {
+		if !utils.ContainsUUID(excludedUserID, superLikerID) {
+			candidateIDs = append(candidateIDs, superLikerID)
+		}
+	}
+	candidateIDs = append(candidateIDs, boostedIDs...)
+	for _, profile := range profiles {
+		candidateIDs = append(candidateIDs, profile.UserID)
+	}
+	candidateIDs = utils.DistinctUUIDs(candidateIDs)
+
+	// Postgres keeps microseconds, truncate so the version survives a round trip
+	now := time.Now().Truncate(time.Microsecond)
+	deck := &models.Deck{
+		UserID:      userID,
+		GeneratedAt: now,
+		ExpiresAt:   now.Add(uc.deckConfig.Period),
+	}
+	if err := uc.deckRepo.ReplaceDeck(deck, candidateIDs); err != nil {
+		return nil, err
+	}
+
+	return deck, nil
+}
+
+// boostedIDs returns the best ranked profiles with a running boost for userID, best first,
+// leaving out the excluded ones. They are put in front when the deck is generated or
+// refilled, with the exclusions computed for that anyway.
+func (uc *profileUseCase) boostedIDs(userID uuid.UUID, excludedUserID []uuid.UUID) ([]uuid.UUID, error) {
+	if uc.deckConfig.BoostSlots <= 0 {
+		return nil, nil
+	}
+
+	boosted, err := uc.recommender.Boosted(userID, uc.deckConfig.BoostSlots)
+	if err != nil {
+		return nil, err
+	}
+
+	var boostedIDs []uuid.UUID
+	for _, profile := range boosted {
+		if !utils.ContainsUUID(excludedUserID, profile.UserID) {
+			boostedIDs = append(boostedIDs, profile.UserID)
+		}
+	}
+	return boostedIDs, nil
+}
+
+// refillDeckIfLow tops the deck up in the background once fewer than the configured
+// threshold of entries are left. Only one refill runs per user at a time.
+func (uc *profileUseCase) refillDeckIfLow(userID uuid.UUID) {
+	remaining, err := uc.deckRepo.CountEntries(userID)
+	if err != nil || remaining >= int64(uc.deckConfig.RefillThreshold) {
+		return
+	}
+
+	if _, running := uc.refilling.LoadOrStore(userID, true); running {
+		return
+	}
+
+	go func() {
+		defer uc.refilling.Delete(userID)
+		if err := uc.refillDeck(userID); err != nil {
+			log.Printf("deck: refilling the deck of %s failed: %v", userID, err)
+		}
+	}()
+}
+
+func (uc *profileUseCase) refillDeck(userID uuid.UUID) error {
+	excludedUserID, err := uc.excludedUserIDs(userID)
+	if err != nil {
+		return err
+	}
+
+	queued, err := uc.deckRepo.GetCandidateIDs(userID)
+	if err != nil {
+		return err
+	}
+	excludedUserID = utils.DistinctUUIDs(append(excludedUserID, queued...))
+
+	// Boosts started since the deck was generated go to the front, pushed in reverse so the
+	// best ranked one ends up first
+	boostedIDs, err := uc.boostedIDs(userID, excludedUserID)
+	if err != nil {
+		return err
+	}
+	for i := len(boostedIDs) - 1; i >= 0; i-- {
+		if err := uc.deckRepo.PushFront(userID, boostedIDs[i]); err != nil {
+			return err
+		}
+	}
+	excludedUserID = append(excludedUserID, boostedIDs...)
+
+	// A deck already holding its size, e.g. after super likes were pushed onto it, is left be
+	missing := uc.deckConfig.Size - len(queued) - len(boostedIDs)
+	if missing <= 0 {
+		return nil
+	}
+
+	profiles, err := uc.recommender.Recommend(userID, excludedUserID, missing)
+	if err != nil {
+		return err
+	}
+
+	candidateIDs := make([]uuid.UUID, len(profiles))
+	for i, profile := range profiles {
+		candidateIDs[i] = profile.UserID
+	}
+
+	return uc.deckRepo.AppendEntries(userID, candidateIDs)
+}
+
+func (uc *profileUseCase) excludedUserIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	// Passed profiles are recycled after a while, liked and matched ones never come back
+	var passedSince time.Time
+	if uc.deckConfig.PassRecycleAfter > 0 {
+		passedSince = time.Now().Add(-uc.deckConfig.PassRecycleAfter)
+	}
+
+	excludedUserID, err := uc.swipeRepo.GetSwipedUsersID(userID, passedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	matchedProfileIDs, err := uc.matchRepo.GetMatchedUsersID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nor do the users a match was closed with, whoever closed it
+	unmatchedUserIDs, err := uc.matchRepo.GetUnmatchedUsersID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks hide both users from each other
+	blockedUserIDs, err := uc.blockRepo.GetBlockedUsersID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedUserID = append(excludedUserID, matchedProfileIDs...)
+	excludedUserID = append(excludedUserID, unmatchedUserIDs...)
+	excludedUserID = append(excludedUserID, blockedUserIDs...)
+	excludedUserID = append(excludedUserID, userID)
+
+	return utils.DistinctUUIDs(excludedUserID), nil
+}
+
+// deckStart is the position before the first entry of a deck. Entries pushed to the
+// front get positions below 1, so the first page has to start lower than that.
+const deckStart = math.MinInt32
+
+// A deck cursor holds the deck version and the position of the last entry served.
+// Cursors from an older version of the deck start again from the top.
+func encodeDeckCursor(deck *models.Deck, position int) string {
+	return utils.EncodeCursor(strconv.FormatInt(deck.GeneratedAt.UnixMicro(), 10), strconv.Itoa(position))
+}
+
+func decodeDeckCursor(cursor string, deck *models.Deck) (int, error) {
+	if cursor == "" {
+		return deckStart, nil
+	}
+
+	values, err := utils.DecodeCursor(cursor, 2)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0, utils.ErrInvalidCursor
+	}
+	position, err := strconv.Atoi(values[1])
+	if err != nil {
+		return 0, utils.ErrInvalidCursor
+	}
+
+	if version != deck.GeneratedAt.UnixMicro() {
+		return deckStart, nil
+	}
+
+	return position, nil
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+var (
+	ErrNotYourTurn     = errors.New("the other participant sends the first message")
+	ErrMatchExpired    = errors.New("match expired")
+	ErrCannotExtend    = errors.New("match cannot be extended")
+	ErrPremiumRequired = errors.New("premium subscription required")
+)
+
+// ExtendMatch gives the first message of a match room userID takes part in more time,
+// once per room and only for premium users within their match_extensions quota, returning
+// when the room now expires.
+func (u *matchUsecase) ExtendMatch(matchRoomID, userID uuid.UUID) (*time.Time, error) {
+	room, err := u.matchRoom(matchRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := u.expiresAt(room)
+	if expiresAt == nil || room.FirstMessageAt != nil || room.Extended || u.cfg.MatchExtension == 0 {
+		return nil, ErrCannotExtend
+	}
+	if !now.Before(*expiresAt) {
+		return nil, ErrMatchExpired
+	}
+
+	user, err := u.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.HasPremium(now) {
+		return nil, ErrPremiumRequired
+	}
+
+	if _, err := u.quotaUseCase.Consume(userID, models.QuotaMatchExtensions); err != nil {
+		return nil, err
+	}
+
+	extended, err := u.matchRepo.ExtendMatch(matchRoomID)
+	if err != nil || !extended {
+		u.quotaUseCase.Release(userID, models.QuotaMatchExtensions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The other participant extended it, or wrote, in the meantime
+	if !extended {
+		return nil, ErrCannotExtend
+	}
+
+	room.Extended = true
+	return u.expiresAt(room), nil
+}
+
+// ExpireMatches closes the match rooms whose conversation did not start, or went
+// unanswered, in time, returning the rooms closed.
+func (u *matchUsecase) ExpireMatches(now time.Time) ([]models.MatchExpiry, error) {
+	cutoffs := u.cutoffs(now)
+
+	var expired []models.MatchExpiry
+	for {
+		rooms, err := u.matchRepo.GetMatchesDue(cutoffs, purgeBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for i := range rooms {
+			// Left open when someone wrote since
+			closed, err := u.matchRepo.ExpireMatch(rooms[i].ID, cutoffs, now)
+			if err != nil {
+				return expired, err
+			}
+			if closed {
+				expired = append(expired, models.MatchExpiry{MatchRoomID: rooms[i].ID, ExpiresAt: *u.expiresAt(&rooms[i])})
+			}
+		}
+
+		if len(rooms) < purgeBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RemindExpiringMatches picks the match rooms expiring within the reminder period whose
+// participants were not reminded yet, returning them to remind.
+func (u *matchUsecase) RemindExpiringMatches(now time.Time) ([]models.MatchExpiry, error) {
+	if u.cfg.ExpiryReminder == 0 {
+		return nil, nil
+	}
+	cutoffs := u.cutoffs(now.Add(u.cfg.ExpiryReminder))
+
+	var expiring []models.MatchExpiry
+	for {
+		rooms, err := u.matchRepo.GetMatchesToRemind(cutoffs, purgeBatchSize)
+		if err != nil {
+			return expiring, err
+		}
+
+		for i := range rooms {
+			reminded, err := u.matchRepo.MarkReminded(rooms[i].ID, now)
+			if err != nil {
+				return expiring, err
+			}
+			if reminded {
+				expiring = append(expiring, models.MatchExpiry{MatchRoomID: rooms[i].ID, ExpiresAt: *u.expiresAt(&rooms[i])})
+			}
+		}
+
+		if len(rooms) < purgeBatchSize {
+			return expiring, nil
+		}
+	}
+}
+
+// checkTurn checks that senderID may write in room at now: the room has not expired, and
+// before the first message the policy lets them open the conversation.
+func (u *matchUsecase) checkTurn(room *models.MatchRoom, senderID uuid.UUID, now time.Time) error {
+	if expiresAt := u.expiresAt(room); expiresAt != nil && !now.Before(*expiresAt) {
+		return ErrMatchExpired
+	}
+	if room.MatchedByID == nil || room.FirstMessageAt != nil {
+		return nil
+	}
+
+	switch u.cfg.FirstMessagePolicy {
+	case config.FirstMessageLiker:
+		if senderID == *room.MatchedByID {
+			return ErrNotYourTurn
+		}
+	case config.FirstMessageMatcher:
+		if senderID != *room.MatchedByID {
+			return ErrNotYourTurn
+		}
+	}
+	return nil
+}
+
+// expiresAt is when room expires unless the conversation moves on, nil if it does not.
+func (u *matchUsecase) expiresAt(room *models.MatchRoom) *time.Time {
+	if room.MatchedByID == nil || room.RepliedAt != nil {
+		return nil
+	}
+
+	var at time.Time
+	if room.FirstMessageAt == nil {
+		if u.cfg.FirstMessageWindow == 0 {
+			return nil
+		}
+		at = room.CreatedAt.Add(u.cfg.FirstMessageWindow)
+		if room.Extended {
+			at = at.Add(u.cfg.MatchExtension)
+		}
+	} else {
+		if u.cfg.ReplyWindow == 0 {
+			return nil
+		}
+		at = room.FirstMessageAt.Add(u.cfg.ReplyWindow)
+	}
+	return &at
+}
+
+// cutoffs picks the match rooms that expire by at.
+func (u *matchUsecase) cutoffs(at time.Time) models.MatchCutoffs {
+	var cutoffs models.MatchCutoffs
+	if u.cfg.FirstMessageWindow > 0 {
+		cutoffs.FirstMessage = at.Add(-u.cfg.FirstMessageWindow)
+		cutoffs.Extended = at.Add(-u.cfg.FirstMessageWindow - u.cfg.MatchExtension)
+	}
+	if u.cfg.ReplyWindow > 0 {
+		cutoffs.Reply = at.Add(-u.cfg.ReplyWindow)
+	}
+	return cutoffs
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+)
+
+// History lists the profiles the user swiped on, newest first, optionally only the swipes
+// of the given types. Swipes that were rewound are not part of the history.
+func (uc *swipeUseCase) History(userID uuid.UUID, types []models.SwipeType, cursor string) (*models.SwipeHistoryPage, error) {
+	for _, swipeType := range types {
+		if !swipeType.Valid() {
+			return nil, ErrInvalidSwipeType
+		}
+	}
+
+	before, beforeID, err := decodeKeyCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	swipes, err := uc.swipeRepo.GetSwipeHistory(userID, types, before, beforeID, uc.cfg.HistoryPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SwipeHistoryPage{Swipes: []models.SwipedProfile{}}
+	if len(swipes) == 0 {
+		return page, nil
+	}
+
+	targetIDs := make([]uuid.UUID, len(swipes))
+	for i, swipe := range swipes {
+		targetIDs[i] = swipe.TargetUserID
+	}
+
+	profiles, err := uc.profileRepo.GetProfilesByUserIDs(targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := setDistances(uc.profileRepo, userID, profiles); err != nil {
+		return nil, err
+	}
+
+	profileByUser := make(map[uuid.UUID]models.Profile, len(profiles))
+	for _, profile := range profiles {
+		profileByUser[profile.UserID] = profile
+	}
+	for _, swipe := range swipes {
+		if profile, ok := profileByUser[swipe.TargetUserID]; ok {
+			page.Swipes = append(page.Swipes, models.SwipedProfile{Profile: profile, Type: swipe.Type, SwipedAt: swipe.CreatedAt})
+		}
+	}
+
+	if len(swipes) == uc.cfg.HistoryPageSize {
+		page.NextCursor = encodeKeyCursor(swipes[len(swipes)-1].CreatedAt, swipes[len(swipes)-1].ID)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("icebreaker template not found")
+	ErrInvalidTemplate  = errors.New("invalid icebreaker template")
+)
+
+const (
+	// icebreakerCount is how many icebreakers are suggested at a time
+	icebreakerCount = 3
+	// bioExcerptLength is how much of a bio a template is filled with at most
+	bioExcerptLength = 60
+)
+
+type IcebreakerUseCase interface {
+	GetIcebreakers(matchRoomID, userID uuid.UUID) ([]models.IcebreakerSuggestion, error)
+	GetTemplates() ([]models.IcebreakerTemplate, error)
+	CreateTemplate(template *models.IcebreakerTemplate) error
+	UpdateTemplate(template *models.IcebreakerTemplate) error
+	DeleteTemplate(id uuid.UUID) error
+}
+
+type icebreakerUseCase struct {
+	icebreakerRepo repository.IcebreakerRepository
+	matchRepo      repository.MatchRepository
+	profileRepo    repository.ProfileRepository
+}
+
+func NewIcebreakerUseCase(icebreakerRepo repository.IcebreakerRepository, matchRepo repository.MatchRepository, profileRepo repository.ProfileRepository) IcebreakerUseCase {
+	return &icebreakerUseCase{icebreakerRepo, matchRepo, profileRepo}
+}
+
+// GetIcebreakers suggests openers for userID in a match room they take part in, the
+// active templates filled with what the pair share and what the other user wrote on their
+// profile. The templates that get answered most often are suggested first, one suggestion
+// per template, and each suggestion is recorded to rank them by. A suggestion the user got
+// before and has not sent is handed out again rather than recorded anew.
+func (u *icebreakerUseCase) GetIcebreakers(matchRoomID, userID uuid.UUID) ([]models.IcebreakerSuggestion, error) {
+	room, err := u.matchRepo.GetMatchRoom(matchRoomID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMatchRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var targetUserID uuid.UUID
+	for _, participant := range room.Participants {
+		if participant.UserID != userID {
+			targetUserID = participant.UserID
+		}
+	}
+
+	profiles, err := u.profileRepo.GetProfilesByUserIDs([]uuid.UUID{userID, targetUserID})
+	if err != nil {
+		return nil, err
+	}
+	// A hidden profile leaves only the templates that need nothing of it
+	var mine, theirs models.Profile
+	for _, profile := range profiles {
+		if profile.UserID == userID {
+			mine = profile
+		} else {
+			theirs = profile
+		}
+	}
+
+	templates, err := u.icebreakerRepo.GetTemplates(true)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(templates, func(i, j int) bool {
+		return icebreakerScore(templates[i]) > icebreakerScore(templates[j])
+	})
+
+	fills := map[models.IcebreakerSource][]*strings.Replacer{
+		models.IcebreakerGeneric: {strings.NewReplacer()},
+	}
+	for _, interest := range sharedInterests(mine.Interests, theirs.Interests) {
+		fills[models.IcebreakerInterest] = append(fills[models.IcebreakerInterest], strings.NewReplacer("{interest}", interest))
+	}
+	for _, prompt := range theirs.Prompts {
+		if strings.TrimSpace(prompt.Answer) != "" {
+			fills[models.IcebreakerPrompt] = append(fills[models.IcebreakerPrompt], strings.NewReplacer("{prompt}", prompt.Prompt, "{answer}", prompt.Answer))
+		}
+	}
+	if bio := bioExcerpt(theirs.Bio); bio != "" {
+		fills[models.IcebreakerBio] = append(fills[models.IcebreakerBio], strings.NewReplacer("{bio}", bio))
+	}
+
+	unsent, err := u.icebreakerRepo.GetUnsentSuggestions(matchRoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	suggested := make(map[string]models.IcebreakerSuggestion, len(unsent))
+	for _, suggestion := range unsent {
+		suggested[suggestion.TemplateID.String()+suggestion.Text] = suggestion
+	}
+
+	suggestions := []models.IcebreakerSuggestion{}
+	var fresh []models.IcebreakerSuggestion
+	used := make(map[models.IcebreakerSource]int)
+	for _, template := range templates {
+		if len(suggestions) == icebreakerCount {
+			break
+		}
+		// Templates of the same source take turns with its data
+		if used[template.Source] == len(fills[template.Source]) {
+			continue
+		}
+		if theirs.Name == "" && strings.Contains(template.Text, "{name}") {
+			continue
+		}
+
+		text := fills[template.Source][used[template.Source]].Replace(template.Text)
+		text = strings.ReplaceAll(text, "{name}", theirs.Name)
+		used[template.Source]++
+		if suggestion, ok := suggested[template.ID.String()+text]; ok {
+			suggestions = append(suggestions, suggestion)
+			continue
+		}
+
+		suggestion := models.IcebreakerSuggestion{
+			ID:          uuid.New(),
+			TemplateID:  template.ID,
+			MatchRoomID: matchRoomID,
+			UserID:      userID,
+			Source:      template.Source,
+			Text:        text,
+		}
+		suggestions = append(suggestions, suggestion)
+		fresh = append(fresh, suggestion)
+	}
+
+	if err := u.icebreakerRepo.CreateSuggestions(fresh); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// GetTemplates lists the icebreaker templates with their stats, the oldest first.
+func (u *icebreakerUseCase) GetTemplates() ([]models.IcebreakerTemplate, error) {
+	return u.icebreakerRepo.GetTemplates(false)
+}
+
+func (u *icebreakerUseCase) CreateTemplate(template *models.IcebreakerTemplate) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	return u.icebreakerRepo.CreateTemplate(template)
+}
+
+// UpdateTemplate changes the source, text or whether the template is active. Its stats are
+// kept, so a template reworded beyond its meaning had better be made anew.
+func (u *icebreakerUseCase) UpdateTemplate(template *models.IcebreakerTemplate) error {
+	if err := validateTemplate(template); err != nil {
+		return err
+	}
+	if _, err := u.icebreakerRepo.GetTemplate(template.ID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTemplateNotFound
+	} else if err != nil {
+		return err
+	}
+	return u.icebreakerRepo.UpdateTemplate(template)
+}
+
+func (u *icebreakerUseCase) DeleteTemplate(id uuid.UUID) error {
+	err := u.icebreakerRepo.DeleteTemplate(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTemplateNotFound
+	}
+	return err
+}
+
+// validateTemplate checks that the template has text with a placeholder of its source.
+func validateTemplate(template *models.IcebreakerTemplate) error {
+	template.Text = strings.TrimSpace(template.Text)
+	if !template.Source.Valid() || template.Text == "" {
+		return ErrInvalidTemplate
+	}
+
+	placeholders := template.Source.Placeholders()
+	for _, placeholder := range placeholders {
+		if strings.Contains(template.Text, placeholder) {
+			return nil
+		}
+	}
+	if len(placeholders) > 0 {
+		return ErrInvalidTemplate
+	}
+	return nil
+}
+
+// icebreakerScore is the share of a template's suggestions that got an answer, starting
+// new templates at a half so they get suggested until their stats tell otherwise.
+func icebreakerScore(template models.IcebreakerTemplate) float64 {
+	return float64(template.Stats.Replied+1) / float64(template.Stats.Suggested+2)
+}
+
+// sharedInterests returns the interests of theirs that mine has too, whatever the case.
+func sharedInterests(mine, theirs []string) []string {
+	set := make(map[string]bool, len(mine))
+	for _, interest := range mine {
+		set[strings.ToLower(strings.TrimSpace(interest))] = true
+	}
+
+	var shared []string
+	for _, interest := range theirs {
+		key := strings.ToLower(strings.TrimSpace(interest))
+		if set[key] {
+			shared = append(shared, strings.TrimSpace(interest))
+			delete(set, key)
+		}
+	}
+	return shared
+}
+
+// bioExcerpt returns the first sentence of a bio, cut short at a word.
+func bioExcerpt(bio string) string {
+	bio = strings.TrimSpace(bio)
+	if end := strings.IndexAny(bio, ".!?\n"); end >= 0 {
+		bio = strings.TrimSpace(bio[:end])
+	}
+
+	runes := []rune(bio)
+	if len(runes) <= bioExcerptLength {
+		return bio
+	}
+	cut := string(runes[:bioExcerptLength])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return cut + "…"
+}
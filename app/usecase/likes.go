@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"gorm.io/gorm"
+)
+
+// LikesReceived lists the pending likes of the user, filtered by their preferences.
+// Premium users get the profiles page by page, free users only how many there are.
+// Liking someone back goes through Swipe like any other like.
+func (uc *swipeUseCase) LikesReceived(userID uuid.UUID, cursor string) (*models.LikesPage, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := uc.likesFilter(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := uc.swipeRepo.CountPendingLikes(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.LikesPage{Count: count, Likes: []models.ReceivedLike{}}
+	if !user.HasPremium(time.Now()) {
+		page.Blurred = true
+		return page, nil
+	}
+
+	before, beforeID, err := decodeKeyCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	swipes, err := uc.swipeRepo.GetPendingLikes(userID, filter, before, beforeID, uc.cfg.LikesPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(swipes) == 0 {
+		return page, nil
+	}
+
+	senderIDs := make([]uuid.UUID, len(swipes))
+	for i, swipe := range swipes {
+		senderIDs[i] = swipe.UserID
+	}
+
+	profiles, err := uc.profileRepo.GetProfilesByUserIDs(senderIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := setDistances(uc.profileRepo, userID, profiles); err != nil {
+		return nil, err
+	}
+
+	profileByUser := make(map[uuid.UUID]models.Profile, len(profiles))
+	for _, profile := range profiles {
+		profileByUser[profile.UserID] = profile
+	}
+	for _, swipe := range swipes {
+		if profile, ok := profileByUser[swipe.UserID]; ok {
+			page.Likes = append(page.Likes, models.ReceivedLike{Profile: profile, Type: swipe.Type, LikedAt: swipe.CreatedAt})
+		}
+	}
+
+	if len(swipes) == uc.cfg.LikesPageSize {
+		page.NextCursor = encodeKeyCursor(swipes[len(swipes)-1].CreatedAt, swipes[len(swipes)-1].ID)
+	}
+
+	return page, nil
+}
+
+// likesFilter turns the user's preferences into a filter on the senders of likes.
+func (uc *swipeUseCase) likesFilter(userID uuid.UUID) (repository.LikesFilter, error) {
+	var filter repository.LikesFilter
+
+	preference, err := uc.profileRepo.GetPreferenceByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return filter, nil
+	}
+	if err != nil {
+		return filter, err
+	}
+
+	now := time.Now()
+	filter.Gender = preference.Gender
+	if preference.MinAge > 0 {
+		filter.BornBefore = now.AddDate(-preference.MinAge, 0, 0)
+	}
+	if preference.MaxAge > 0 {
+		filter.BornAfter = now.AddDate(-preference.MaxAge-1, 0, 0)
+	}
+
+	if preference.MaxDistanceKm > 0 {
+		profile, err := uc.profileRepo.GetProfileByUserID(userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return filter, err
+		}
+		if err == nil {
+			filter.Latitude = profile.Latitude
+			filter.Longitude = profile.Longitude
+			filter.MaxDistanceKm = preference.MaxDistanceKm
+		}
+	}
+
+	return filter, nil
+}
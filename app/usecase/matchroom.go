@@ -1,38 +1,336 @@
 package usecase
 
 import (
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
 	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/app/screening"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMatchRoomNotFound    = errors.New("match room not found")
+	ErrMessageNotFound      = errors.New("message not found")
+	ErrInvalidMessageQuery  = errors.New("invalid message query")
+	ErrInvalidUnmatchReason = errors.New("invalid unmatch reason")
 )
 
+// previewLength is how many characters of the last message a room list shows
+const previewLength = 100
+
 type MatchUsecase interface {
-	GetMatchRooms(userID uuid.UUID) ([]models.MatchRoom, error)
-	DeleteMatchRoom(id, userID uuid.UUID) error
+	GetMatchRooms(userID uuid.UUID) ([]models.MatchRoomSummary, error)
+	Unmatch(matchRoomID, userID uuid.UUID, reason models.UnmatchReason) (*models.Unmatch, error)
+	PurgeUnmatchedMessages(now time.Time) (int, error)
+	ExtendMatch(matchRoomID, userID uuid.UUID) (*time.Time, error)
+	ExpireMatches(now time.Time) ([]models.MatchExpiry, error)
+	RemindExpiringMatches(now time.Time) ([]models.MatchExpiry, error)
 	CreateMessage(matchRoom *models.Message) error
-	GetMessages(matchRoomID uuid.UUID) ([]models.Message, error)
+	GetMessages(matchRoomID, userID uuid.UUID, query models.MessageQuery) (*models.MessagePage, error)
+	GetMessageUpdates(matchRoomID, userID uuid.UUID, since string) (*models.MessageUpdates, error)
+	MarkRead(matchRoomID, userID, messageID uuid.UUID) (*models.ReadReceipt, error)
+	EditMessage(matchRoomID, messageID, userID uuid.UUID, content string) (*models.Message, error)
+	MarkUnwanted(matchRoomID, messageID, userID uuid.UUID) error
+	UnsendMessage(matchRoomID, messageID, userID uuid.UUID) (*models.Message, error)
+	GetMessageEdits(matchRoomID, messageID, userID uuid.UUID) ([]models.MessageEdit, error)
+	React(matchRoomID, messageID, userID uuid.UUID, emoji string) (*models.MessageReaction, error)
+	Unreact(matchRoomID, messageID, userID uuid.UUID) (bool, error)
+	// CheckMember returns ErrMatchRoomNotFound unless userID takes part in the match room.
+	CheckMember(matchRoomID, userID uuid.UUID) error
 }
 
 type matchUsecase struct {
-	matchRepo repository.MatchRepository
+	matchRepo         repository.MatchRepository
+	userRepo          repository.UserRepository
+	presenceUseCase   PresenceUseCase
+	screener          screening.Screener
+	moderationUseCase ModerationUseCase
+	quotaUseCase      QuotaUseCase
+	cfg               config.ChatConfig
+}
+
+func NewMatchUsecase(repo repository.MatchRepository, userRepo repository.UserRepository, presenceUseCase PresenceUseCase, screener screening.Screener, moderationUseCase ModerationUseCase, quotaUseCase QuotaUseCase, cfg config.ChatConfig) MatchUsecase {
+	return &matchUsecase{repo, userRepo, presenceUseCase, screener, moderationUseCase, quotaUseCase, cfg}
 }
 
-func NewMatchUsecase(repo repository.MatchRepository) MatchUsecase {
-	return &matchUsecase{repo}
+// GetMatchRooms lists the match rooms of userID, the most recently active first. Rooms
+// that expired are left out before the scheduler gets round to closing them.
+func (u *matchUsecase) GetMatchRooms(userID uuid.UUID) ([]models.MatchRoomSummary, error) {
+	rooms, err := u.matchRepo.GetMatchRoomSummaries(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summaries := make([]models.MatchRoomSummary, 0, len(rooms))
+	for _, summary := range rooms {
+		summary.ExpiresAt = u.expiresAt(&summary.MatchRoom)
+		if summary.ExpiresAt != nil && !now.Before(*summary.ExpiresAt) {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	targetIDs := make([]uuid.UUID, len(summaries))
+	for i, summary := range summaries {
+		targetIDs[i] = summary.TargetUserID
+	}
+	presence, err := u.presenceUseCase.GetPresence(targetIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range summaries {
+		summaries[i].Presence = presence[summaries[i].TargetUserID]
+
+		if summaries[i].LastMessage == nil {
+			continue
+		}
+		if content := []rune(summaries[i].LastMessage.Content); len(content) > previewLength {
+			summaries[i].LastMessage.Content = string(content[:previewLength]) + "…"
+		}
+	}
+
+	return summaries, nil
 }
 
-func (u *matchUsecase) GetMatchRooms(userID uuid.UUID) ([]models.MatchRoom, error) {
-	return u.matchRepo.GetMatchRooms(userID)
+// Unmatch closes a match room userID takes part in for both participants, giving one of
+// the unmatch reasons, unspecified when empty. The pair never matches again.
+func (u *matchUsecase) Unmatch(matchRoomID, userID uuid.UUID, reason models.UnmatchReason) (*models.Unmatch, error) {
+	if reason == "" {
+		reason = models.UnmatchUnspecified
+	}
+	if !reason.Valid() {
+		return nil, ErrInvalidUnmatchReason
+	}
+
+	unmatch := &models.Unmatch{MatchRoomID: matchRoomID, UserID: userID, Reason: reason, UnmatchedAt: time.Now()}
+	err := u.matchRepo.Unmatch(unmatch)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMatchRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmatch, nil
 }
 
-func (u *matchUsecase) DeleteMatchRoom(id, userID uuid.UUID) error {
-	return u.matchRepo.DeleteMatchRoom(id, userID)
+// PurgeUnmatchedMessages deletes the messages of the rooms unmatched longer than the
+// retention period ago, returning how many rooms were purged.
+func (u *matchUsecase) PurgeUnmatchedMessages(now time.Time) (int, error) {
+	purged := 0
+	for {
+		unmatches, err := u.matchRepo.GetUnmatchesToPurge(now.Add(-u.cfg.UnmatchRetention), purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for i := range unmatches {
+			if err := u.matchRepo.PurgeMessages(&unmatches[i], now); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(unmatches) < purgeBatchSize {
+			return purged, nil
+		}
+	}
 }
 
+// CreateMessage sends a message to an open match room the sender takes part in, so not
+// once the room was unmatched or either side blocked the other, and only as the
+// conversation rules allow. It needs content unless it carries an attachment the sender
+// uploaded to the room. The content is screened first, a message screening holds is
+// stored with HeldAt set and put before a moderator.
 func (u *matchUsecase) CreateMessage(matchRoom *models.Message) error {
-	return u.matchRepo.CreateMessage(matchRoom)
+	if matchRoom.Content == "" && matchRoom.Attachment == nil {
+		return ErrInvalidMessage
+	}
+	room, err := u.matchRoom(matchRoom.MatchRoomID, matchRoom.SenderID)
+	if err != nil {
+		return err
+	}
+	if err := u.checkTurn(room, matchRoom.SenderID, time.Now()); err != nil {
+		return err
+	}
+
+	matchRoom.Screening = u.screen(matchRoom.Content)
+	if matchRoom.Screening.Verdict == models.ScreeningHold {
+		now := time.Now()
+		matchRoom.HeldAt = &now
+	}
+
+	err = u.matchRepo.CreateMessage(matchRoom)
+	if matchRoom.Attachment != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAttachmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if matchRoom.HeldAt != nil {
+		return u.flagContent(matchRoom, "Held message", matchRoom.Content)
+	}
+	return nil
+}
+
+// GetMessages lists a page of the messages of a match room userID takes part in.
+func (u *matchUsecase) GetMessages(matchRoomID, userID uuid.UUID, query models.MessageQuery) (*models.MessagePage, error) {
+	if query.Before != "" && query.After != "" {
+		return nil, ErrInvalidMessageQuery
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = u.cfg.MessagePageSize
+	}
+	if limit < 1 || limit > u.cfg.MaxMessagePageSize {
+		return nil, ErrInvalidMessageQuery
+	}
+
+	if err := u.CheckMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	var messages []models.Message
+	if query.After != "" {
+		after, afterID, err := decodeKeyCursor(query.After)
+		if err != nil {
+			return nil, err
+		}
+		messages, err = u.matchRepo.GetMessagesAfter(matchRoomID, after, afterID, limit)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		before, beforeID, err := decodeKeyCursor(query.Before)
+		if err != nil {
+			return nil, err
+		}
+		messages, err = u.matchRepo.GetMessagesBefore(matchRoomID, before, beforeID, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := &models.MessagePage{Messages: messages}
+	if page.Messages == nil {
+		page.Messages = []models.Message{}
+	}
+	if len(messages) == limit {
+		last := messages[len(messages)-1]
+		page.NextCursor = encodeKeyCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// GetMessageUpdates returns the changes to a match room's messages since the last sync of
+// userID, at most the maximum page size at a time. since is the next_since cursor of that
+// sync, or for a first sync the RFC 3339 time the client loaded the messages at.
+func (u *matchUsecase) GetMessageUpdates(matchRoomID, userID uuid.UUID, since string) (*models.MessageUpdates, error) {
+	after, afterID, err := decodeSince(since)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.CheckMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	limit := u.cfg.MaxMessagePageSize
+	messages, err := u.matchRepo.GetMessageUpdates(matchRoomID, after, afterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := &models.MessageUpdates{Messages: []models.Message{}, Deleted: []uuid.UUID{}}
+	if len(messages) > limit {
+		updates.HasMore = true
+		messages = messages[:limit]
+	}
+
+	for _, message := range messages {
+		if message.DeletedAt.Valid {
+			updates.Deleted = append(updates.Deleted, message.ID)
+		} else {
+			updates.Messages = append(updates.Messages, message)
+		}
+	}
+	// Changes at the same instant are told apart by their ID, so none is skipped
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		after, afterID = last.ChangedAt(), last.ID
+	}
+	updates.NextSince = encodeKeyCursor(after, afterID)
+
+	return updates, nil
+}
+
+// decodeSince reads the point a sync continues from, either a cursor or a time. A time
+// includes the changes made at that instant.
+func decodeSince(since string) (time.Time, uuid.UUID, error) {
+	if since == "" {
+		return time.Time{}, uuid.Nil, utils.ErrInvalidCursor
+	}
+	if at, err := time.Parse(time.RFC3339Nano, since); err == nil {
+		return at, uuid.Nil, nil
+	}
+	return decodeKeyCursor(since)
+}
+
+// MarkRead moves the read cursor of userID up to the message, or the latest message of the
+// room when messageID is nil. The receipt is nil when the user had already read that far.
+func (u *matchUsecase) MarkRead(matchRoomID, userID, messageID uuid.UUID) (*models.ReadReceipt, error) {
+	if err := u.CheckMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	var message *models.Message
+	if messageID == uuid.Nil {
+		latest, err := u.matchRepo.GetMessagesBefore(matchRoomID, time.Time{}, uuid.Nil, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) == 0 {
+			return nil, nil
+		}
+		message = &latest[0]
+	} else {
+		var err error
+		message, err = u.matchRepo.GetMessage(messageID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (message.MatchRoomID != matchRoomID || message.HeldAt != nil)) {
+			return nil, ErrMessageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	readAt := time.Now()
+	moved, err := u.matchRepo.MarkRead(matchRoomID, userID, message, readAt)
+	if err != nil || !moved {
+		return nil, err
+	}
+
+	return &models.ReadReceipt{MatchRoomID: matchRoomID, UserID: userID, MessageID: message.ID, ReadAt: readAt}, nil
+}
+
+func (u *matchUsecase) CheckMember(matchRoomID, userID uuid.UUID) error {
+	_, err := u.matchRoom(matchRoomID, userID)
+	return err
 }
 
-func (u *matchUsecase) GetMessages(matchRoomID uuid.UUID) ([]models.Message, error) {
-	return u.matchRepo.GetMessages(matchRoomID)
+// matchRoom returns the open match room if userID takes part in it.
+func (u *matchUsecase) matchRoom(matchRoomID, userID uuid.UUID) (*models.MatchRoom, error) {
+	room, err := u.matchRepo.GetMatchRoom(matchRoomID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMatchRoomNotFound
+	}
+	return room, err
 }
@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMessage   = errors.New("message content is required")
+	ErrNotMessageSender = errors.New("only the sender can change a message")
+	ErrEditWindowClosed = errors.New("message can no longer be edited")
+	ErrMessageUnsent    = errors.New("message was unsent")
+	ErrInvalidReaction  = errors.New("reaction must be a single emoji")
+	ErrEditHeld         = errors.New("edit was held back for review")
+	ErrOwnMessage       = errors.New("only the recipient can mark a message as unwanted")
+)
+
+// maxReactionLength allows for emoji made of several code points, like flags and skin tones
+const maxReactionLength = 8
+
+// EditMessage replaces the content of a message userID sent, within the edit window. The
+// version it replaces is kept in the edit history.
+func (u *matchUsecase) EditMessage(matchRoomID, messageID, userID uuid.UUID, content string) (*models.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrInvalidMessage
+	}
+
+	message, err := u.getSentMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(message.CreatedAt) > u.cfg.EditWindow {
+		return nil, ErrEditWindowClosed
+	}
+	if content == message.Content {
+		return message, nil
+	}
+
+	// An edit is screened like a new message, but one to hold is not made at all
+	screening := u.screen(content)
+	if screening.Verdict == models.ScreeningHold {
+		attempt := *message
+		attempt.Screening = screening
+		if err := u.flagContent(&attempt, "Held edit of message", content); err != nil {
+			return nil, err
+		}
+		return nil, ErrEditHeld
+	}
+
+	edit := &models.MessageEdit{MessageID: message.ID, Content: message.Content, EditedAt: now}
+	message.Content = content
+	message.EditedAt = &now
+	message.Screening = screening
+	if err := u.matchRepo.EditMessage(message, edit); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// UnsendMessage removes a message userID sent for both participants, leaving a tombstone.
+func (u *matchUsecase) UnsendMessage(matchRoomID, messageID, userID uuid.UUID) (*models.Message, error) {
+	message, err := u.getSentMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	message.Content = ""
+	message.UnsentAt = &now
+	message.Reactions = nil
+	if err := u.matchRepo.UnsendMessage(message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// MarkUnwanted records that userID did not want a message the other participant sent them,
+// and puts it before a moderator the first time. Marking a message with a photo flags the
+// photo.
+func (u *matchUsecase) MarkUnwanted(matchRoomID, messageID, userID uuid.UUID) error {
+	message, err := u.getMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		return err
+	}
+	if message.SenderID == userID {
+		return ErrOwnMessage
+	}
+	if message.UnsentAt != nil {
+		return ErrMessageUnsent
+	}
+
+	marked, err := u.matchRepo.MarkUnwanted(messageID, time.Now())
+	if err != nil || !marked {
+		return err
+	}
+
+	// A photo the recipient flagged goes to the photo queue
+	if message.Attachment != nil && message.Attachment.Kind == models.AttachmentImage {
+		summary := fmt.Sprintf("Photo %s in message %s flagged by %s: %s", message.Attachment.ID, message.ID, userID, excerpt(message.Content))
+		_, err = u.moderationUseCase.OpenCase(message.SenderID, models.CaseSourcePhoto, summary)
+		return err
+	}
+
+	summary := fmt.Sprintf("Message %s marked as unwanted by %s: %s", message.ID, userID, excerpt(message.Content))
+	_, err = u.moderationUseCase.OpenCase(message.SenderID, models.CaseSourceUnwanted, summary)
+	return err
+}
+
+// GetMessageEdits returns the earlier versions of a message, oldest first.
+func (u *matchUsecase) GetMessageEdits(matchRoomID, messageID, userID uuid.UUID) ([]models.MessageEdit, error) {
+	if _, err := u.getMessage(matchRoomID, messageID, userID); err != nil {
+		return nil, err
+	}
+	return u.matchRepo.GetMessageEdits(messageID)
+}
+
+// React sets the reaction of userID to a message, replacing the one they had.
+func (u *matchUsecase) React(matchRoomID, messageID, userID uuid.UUID, emoji string) (*models.MessageReaction, error) {
+	if !validReaction(emoji) {
+		return nil, ErrInvalidReaction
+	}
+
+	message, err := u.getMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.UnsentAt != nil {
+		return nil, ErrMessageUnsent
+	}
+
+	reaction := &models.MessageReaction{MessageID: messageID, UserID: userID, Emoji: emoji, CreatedAt: time.Now()}
+	if err := u.matchRepo.SetReaction(reaction); err != nil {
+		return nil, err
+	}
+	return reaction, nil
+}
+
+// Unreact removes the reaction of userID to a message, reporting false if they had none.
+func (u *matchUsecase) Unreact(matchRoomID, messageID, userID uuid.UUID) (bool, error) {
+	if _, err := u.getMessage(matchRoomID, messageID, userID); err != nil {
+		return false, err
+	}
+	return u.matchRepo.DeleteReaction(messageID, userID)
+}
+
+// getMessage returns a message of a match room userID takes part in.
+func (u *matchUsecase) getMessage(matchRoomID, messageID, userID uuid.UUID) (*models.Message, error) {
+	if err := u.CheckMember(matchRoomID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := u.matchRepo.GetMessage(messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Only the sender has a message held for review
+	if message.MatchRoomID != matchRoomID || (message.HeldAt != nil && message.SenderID != userID) {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+// screen runs content through the screening pipeline, captions of attachments included.
+func (u *matchUsecase) screen(content string) models.MessageScreening {
+	if content == "" {
+		return models.MessageScreening{Verdict: models.ScreeningDeliver}
+	}
+	return u.screener.Screen(content)
+}
+
+// flagContent opens a text filter case on the sender of message for content, what is held
+// back by screening.
+func (u *matchUsecase) flagContent(message *models.Message, what, content string) error {
+	summary := fmt.Sprintf("%s %s (score %d, %s): %s", what, message.ID, message.Screening.Score, strings.Join(message.Screening.Flags, ", "), excerpt(content))
+	_, err := u.moderationUseCase.OpenCase(message.SenderID, models.CaseSourceTextFilter, summary)
+	return err
+}
+
+// excerpt shortens content to what a moderator needs to see in a case note.
+func excerpt(content string) string {
+	if runes := []rune(content); len(runes) > previewLength {
+		return string(runes[:previewLength]) + "…"
+	}
+	return content
+}
+
+// getSentMessage returns a message userID sent that has not been unsent.
+func (u *matchUsecase) getSentMessage(matchRoomID, messageID, userID uuid.UUID) (*models.Message, error) {
+	message, err := u.getMessage(matchRoomID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.SenderID != userID {
+		return nil, ErrNotMessageSender
+	}
+	if message.UnsentAt != nil {
+		return nil, ErrMessageUnsent
+	}
+	return message, nil
+}
+
+// validReaction accepts a short run of symbols, rejecting text.
+func validReaction(emoji string) bool {
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxReactionLength {
+		return false
+	}
+	for _, r := range emoji {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCaseNotFound      = errors.New("moderation case not found")
+	ErrCaseResolved      = errors.New("moderation case is already resolved")
+	ErrCaseNotInReview   = errors.New("moderation case is not assigned")
+	ErrCaseChanged       = errors.New("moderation case was changed meanwhile, reload it")
+	ErrInvalidCaseSource = errors.New("invalid case source")
+	ErrInvalidAssignee   = errors.New("cases can only be assigned to moderators")
+	ErrInvalidNote       = errors.New("note must not be empty")
+	ErrInvalidDecision   = errors.New("invalid decision")
+	ErrMessageNotHeld    = errors.New("message is not held for review")
+)
+
+const (
+	// casePageSize is how many cases a page of the moderation queue holds
+	casePageSize = 50
+	// metricsWindow is how far back the resolution metrics of the queue go
+	metricsWindow = 7 * 24 * time.Hour
+	// maxSuspension is the longest a user can be suspended, longer is a ban
+	maxSuspension = 365 * 24 * time.Hour
+)
+
+// DecisionRequest is how a moderator decides a case.
+type DecisionRequest struct {
+	Decision models.CaseDecision
+	Note     string
+	// SuspendFor is how long a suspension lasts
+	SuspendFor time.Duration
+}
+
+// ModerationUseCase runs the moderation queue: reports and flags open cases, moderators
+// take them, and their decision is applied to the user and their profile.
+type ModerationUseCase interface {
+	// OpenCase flags userID for review, on their unresolved case if they have one.
+	OpenCase(userID uuid.UUID, source models.CaseSource, summary string) (*models.ModerationCase, error)
+	FileReport(report *models.Report) (*models.ModerationCase, error)
+	GetCases(filter models.CaseFilter, cursor string) (*models.CasePage, error)
+	GetCase(id uuid.UUID) (*models.ModerationCase, error)
+	Assign(caseID, moderatorID, assigneeID uuid.UUID) (*models.ModerationCase, error)
+	Release(caseID, moderatorID uuid.UUID) (*models.ModerationCase, error)
+	AddNote(caseID, authorID uuid.UUID, body string) (*models.CaseNote, error)
+	Decide(caseID, moderatorID uuid.UUID, request DecisionRequest) (*models.ModerationCase, error)
+	GetMetrics(now time.Time) (*models.ModerationMetrics, error)
+	LiftEndedSuspensions(now time.Time) (int, error)
+	// ReleaseMessage delivers a message screening held, once a moderator found it fine.
+	ReleaseMessage(messageID uuid.UUID) (*models.Message, error)
+}
+
+type moderationUseCase struct {
+	moderationRepo repository.ModerationRepository
+	userRepo       repository.UserRepository
+	profileRepo    repository.ProfileRepository
+	matchRepo      repository.MatchRepository
+	cfg            config.ModerationConfig
+}
+
+func NewModerationUseCase(moderationRepo repository.ModerationRepository, userRepo repository.UserRepository, profileRepo repository.ProfileRepository, matchRepo repository.MatchRepository, cfg config.ModerationConfig) ModerationUseCase {
+	return &moderationUseCase{moderationRepo, userRepo, profileRepo, matchRepo, cfg}
+}
+
+// newCase is the case opened for userID at now, due within the SLA of its priority.
+func (uc *moderationUseCase) newCase(userID uuid.UUID, source models.CaseSource, priority models.CasePriority, now time.Time) *models.ModerationCase {
+	sla := uc.cfg.SLA
+	if priority == models.CasePriorityUrgent {
+		sla = uc.cfg.UrgentSLA
+	}
+	return &models.ModerationCase{UserID: userID, Source: source, Status: models.CaseOpen, Priority: priority, DueAt: now.Add(sla)}
+}
+
+func (uc *moderationUseCase) OpenCase(userID uuid.UUID, source models.CaseSource, summary string) (*models.ModerationCase, error) {
+	if !source.Valid() {
+		return nil, ErrInvalidCaseSource
+	}
+	if strings.TrimSpace(summary) == "" {
+		return nil, ErrInvalidNote
+	}
+
+	note := &models.CaseNote{Source: source, Body: summary}
+	return uc.moderationRepo.OpenCase(uc.newCase(userID, source, models.CasePriorityNormal, time.Now()), note)
+}
+
+// FileReport files a report on the case of the reported user. Once enough users reported
+// them, their profile is hidden from discovery until the case is decided.
+func (uc *moderationUseCase) FileReport(report *models.Report) (*models.ModerationCase, error) {
+	priority := models.CasePriorityNormal
+	if report.Category.Urgent() {
+		priority = models.CasePriorityUrgent
+	}
+
+	moderationCase, err := uc.moderationRepo.CreateReport(report, uc.newCase(report.ReportedUserID, models.CaseSourceReport, priority, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	if uc.cfg.AutoHideReports > 0 && moderationCase.ReporterCount >= int64(uc.cfg.AutoHideReports) && moderationCase.ProfileHiddenAt == nil {
+		hiddenAt := time.Now()
+		if err := uc.profileRepo.SetHidden(report.ReportedUserID, &hiddenAt); err != nil {
+			return nil, err
+		}
+		if err := uc.moderationRepo.MarkProfileHidden(moderationCase.ID, hiddenAt); err != nil {
+			return nil, err
+		}
+		moderationCase.ProfileHiddenAt = &hiddenAt
+	}
+
+	return moderationCase, nil
+}
+
+// GetCases lists a page of the cases matching filter, the soonest due first.
+func (uc *moderationUseCase) GetCases(filter models.CaseFilter, cursor string) (*models.CasePage, error) {
+	afterDueAt, afterID, err := decodeKeyCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := uc.moderationRepo.GetCases(filter, afterDueAt, afterID, casePageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.CasePage{Cases: cases}
+	if page.Cases == nil {
+		page.Cases = []models.ModerationCase{}
+	}
+	if len(cases) == casePageSize {
+		last := cases[len(cases)-1]
+		page.NextCursor = encodeKeyCursor(last.DueAt, last.ID)
+	}
+	return page, nil
+}
+
+func (uc *moderationUseCase) GetCase(id uuid.UUID) (*models.ModerationCase, error) {
+	moderationCase, err := uc.moderationRepo.GetCase(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCaseNotFound
+	}
+	return moderationCase, err
+}
+
+// Assign puts an unresolved case in review with a moderator, who may be the one assigning.
+func (uc *moderationUseCase) Assign(caseID, moderatorID, assigneeID uuid.UUID) (*models.ModerationCase, error) {
+	moderationCase, err := uc.GetCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	if moderationCase.Status == models.CaseResolved {
+		return nil, ErrCaseResolved
+	}
+
+	assignee, err := uc.userRepo.GetUserByID(assigneeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !assignee.HasRole(models.RoleModerator, models.RoleAdmin)) {
+		return nil, ErrInvalidAssignee
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	from := moderationCase.Status
+	moderationCase.Status = models.CaseInReview
+	moderationCase.AssigneeID = &assigneeID
+	note := &models.CaseNote{AuthorID: &moderatorID, Body: fmt.Sprintf("Assigned to %s", assigneeID)}
+	return moderationCase, uc.updateCase(moderationCase, from, note)
+}
+
+// Release puts a case in review back in the queue for anyone to take.
+func (uc *moderationUseCase) Release(caseID, moderatorID uuid.UUID) (*models.ModerationCase, error) {
+	moderationCase, err := uc.GetCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	if moderationCase.Status != models.CaseInReview {
+		return nil, ErrCaseNotInReview
+	}
+
+	moderationCase.Status = models.CaseOpen
+	moderationCase.AssigneeID = nil
+	note := &models.CaseNote{AuthorID: &moderatorID, Body: "Released to the queue"}
+	return moderationCase, uc.updateCase(moderationCase, models.CaseInReview, note)
+}
+
+func (uc *moderationUseCase) AddNote(caseID, authorID uuid.UUID, body string) (*models.CaseNote, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrInvalidNote
+	}
+	if _, err := uc.GetCase(caseID); err != nil {
+		return nil, err
+	}
+
+	note := &models.CaseNote{CaseID: caseID, AuthorID: &authorID, Body: body}
+	if err := uc.moderationRepo.CreateNote(note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Decide resolves a case and applies the decision to the user and their profile together,
+// so that a decision is applied exactly once.
+func (uc *moderationUseCase) Decide(caseID, moderatorID uuid.UUID, request DecisionRequest) (*models.ModerationCase, error) {
+	if !request.Decision.Valid() {
+		return nil, ErrInvalidDecision
+	}
+	if request.Decision == models.DecisionSuspend && (request.SuspendFor <= 0 || request.SuspendFor > maxSuspension) {
+		return nil, ErrInvalidDecision
+	}
+
+	moderationCase, err := uc.GetCase(caseID)
+	if err != nil {
+		return nil, err
+	}
+	if moderationCase.Status == models.CaseResolved {
+		return nil, ErrCaseResolved
+	}
+
+	now := time.Now()
+	from := moderationCase.Status
+	moderationCase.Status = models.CaseResolved
+	moderationCase.Decision = &request.Decision
+	moderationCase.DecidedByID = &moderatorID
+	moderationCase.ResolvedAt = &now
+	if request.Decision == models.DecisionSuspend {
+		until := now.Add(request.SuspendFor)
+		moderationCase.SuspendUntil = &until
+	}
+
+	body := "Decided: " + string(request.Decision)
+	if request.Note != "" {
+		body += "\n" + request.Note
+	}
+	err = uc.moderationRepo.ResolveCase(moderationCase, from, &models.CaseNote{AuthorID: &moderatorID, Body: body}, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCaseChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	return moderationCase, nil
+}
+
+func (uc *moderationUseCase) updateCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote) error {
+	err := uc.moderationRepo.UpdateCase(moderationCase, from, note)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCaseChanged
+	}
+	return err
+}
+
+// GetMetrics sums up the queue at now and the cases resolved over the last week.
+func (uc *moderationUseCase) GetMetrics(now time.Time) (*models.ModerationMetrics, error) {
+	return uc.moderationRepo.GetMetrics(now, now.Add(-metricsWindow))
+}
+
+// LiftEndedSuspensions lets the users whose suspension ran out back in and shows their
+// profiles again, unless an open case keeps them hidden, returning how many were let back.
+func (uc *moderationUseCase) LiftEndedSuspensions(now time.Time) (int, error) {
+	users, err := uc.userRepo.GetEndedSuspensions(now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, user := range users {
+		if err := uc.userRepo.SetSuspendedUntil(user.ID, nil); err != nil {
+			return i, err
+		}
+		if err := uc.profileRepo.ShowProfile(user.ID, now); err != nil {
+			return i, err
+		}
+	}
+	return len(users), nil
+}
+
+func (uc *moderationUseCase) ReleaseMessage(messageID uuid.UUID) (*models.Message, error) {
+	err := uc.matchRepo.ReleaseMessage(messageID, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return uc.matchRepo.GetMessage(messageID)
+}
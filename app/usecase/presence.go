@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+type PresenceUseCase interface {
+	// Touch records that userID is active at now, from an API request or a realtime
+	// heartbeat. Writes are spaced by the touch interval.
+	Touch(userID uuid.UUID, now time.Time) error
+	// GetPresence returns the presence of each user who lets others see it.
+	GetPresence(userIDs []uuid.UUID, now time.Time) (map[uuid.UUID]*models.Presence, error)
+}
+
+type presenceUseCase struct {
+	profileRepo repository.ProfileRepository
+	cfg         config.PresenceConfig
+	mu          sync.Mutex
+	// touched is when the activity of each user was last written by this instance
+	touched   map[uuid.UUID]time.Time
+	lastPrune time.Time
+}
+
+func NewPresenceUseCase(profileRepo repository.ProfileRepository, cfg config.PresenceConfig) PresenceUseCase {
+	return &presenceUseCase{profileRepo: profileRepo, cfg: cfg, touched: make(map[uuid.UUID]time.Time)}
+}
+
+func (uc *presenceUseCase) Touch(userID uuid.UUID, now time.Time) error {
+	uc.mu.Lock()
+	if now.Sub(uc.touched[userID]) < uc.cfg.TouchInterval {
+		uc.mu.Unlock()
+		return nil
+	}
+	uc.touched[userID] = now
+
+	// Forget the users whose last write no longer holds anything back
+	if now.Sub(uc.lastPrune) >= uc.cfg.TouchInterval {
+		uc.lastPrune = now
+		for id, at := range uc.touched {
+			if now.Sub(at) >= uc.cfg.TouchInterval {
+				delete(uc.touched, id)
+			}
+		}
+	}
+	uc.mu.Unlock()
+
+	return uc.profileRepo.TouchLastActive(userID, now)
+}
+
+func (uc *presenceUseCase) GetPresence(userIDs []uuid.UUID, now time.Time) (map[uuid.UUID]*models.Presence, error) {
+	presence := make(map[uuid.UUID]*models.Presence, len(userIDs))
+	if len(userIDs) == 0 {
+		return presence, nil
+	}
+
+	activity, err := uc.profileRepo.GetActivity(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range activity {
+		if a.HidePresence || a.LastActiveAt.IsZero() {
+			continue
+		}
+		presence[a.UserID] = &models.Presence{
+			// Writes are spaced by the touch interval, so allow for it
+			Online:       now.Sub(a.LastActiveAt) < uc.cfg.OnlineWindow+uc.cfg.TouchInterval,
+			LastActiveAt: a.LastActiveAt,
+		}
+	}
+
+	return presence, nil
+}
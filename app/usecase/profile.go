@@ -2,30 +2,56 @@ package usecase
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/recommender"
 	"github.com/mdzakyabd/dating-app/app/repository"
 	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
 )
 
 type ProfileUseCase interface {
 	CreateProfile(profile *models.Profile) error
 	GetProfileByID(id uuid.UUID) (*models.Profile, error)
 	UpdateProfile(profile *models.Profile) error
-	ViewProfiles(userID uuid.UUID) ([]models.Profile, error)
+	ViewProfiles(userID uuid.UUID, cursor string) (*models.ProfilePage, error)
+	GetPreference(userID uuid.UUID) (*models.Preference, error)
+	UpdatePreference(preference *models.Preference) error
 }
 
 type profileUseCase struct {
-	profileRepo repository.ProfileRepository
-	userRepo    repository.UserRepository
-	swipeRepo   repository.SwipeRepository
-	matchRepo   repository.MatchRepository
+	profileRepo     repository.ProfileRepository
+	userRepo        repository.UserRepository
+	swipeRepo       repository.SwipeRepository
+	matchRepo       repository.MatchRepository
+	blockRepo       repository.BlockRepository
+	deckRepo        repository.DeckRepository
+	recommender     recommender.Recommender
+	quotaUseCase    QuotaUseCase
+	presenceUseCase PresenceUseCase
+	deckConfig      config.DeckConfig
+	refilling       sync.Map
 }
 
-func NewProfileUseCase(profileRepo repository.ProfileRepository, userRepo repository.UserRepository, swipeRepo repository.SwipeRepository, matchRepo repository.MatchRepository) ProfileUseCase {
-	return &profileUseCase{profileRepo, userRepo, swipeRepo, matchRepo}
+func NewProfileUseCase(profileRepo repository.ProfileRepository, userRepo repository.UserRepository, swipeRepo repository.SwipeRepository, matchRepo repository.MatchRepository, blockRepo repository.BlockRepository, deckRepo repository.DeckRepository, recommender recommender.Recommender, quotaUseCase QuotaUseCase, presenceUseCase PresenceUseCase, deckConfig config.DeckConfig) ProfileUseCase {
+	return &profileUseCase{
+		profileRepo:     profileRepo,
+		userRepo:        userRepo,
+		swipeRepo:       swipeRepo,
+		matchRepo:       matchRepo,
+		blockRepo:       blockRepo,
+		deckRepo:        deckRepo,
+		recommender:     recommender,
+		quotaUseCase:    quotaUseCase,
+		presenceUseCase: presenceUseCase,
+		deckConfig:      deckConfig,
+	}
 }
 
 func (uc *profileUseCase) CreateProfile(profile *models.Profile) error {
@@ -33,47 +59,145 @@ func (uc *profileUseCase) CreateProfile(profile *models.Profile) error {
 }
 
 func (uc *profileUseCase) GetProfileByID(id uuid.UUID) (*models.Profile, error) {
-	return uc.profileRepo.GetProfileByID(id)
+	profile, err := uc.profileRepo.GetProfileByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	presence, err := uc.presenceUseCase.GetPresence([]uuid.UUID{profile.UserID}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	profile.Presence = presence[profile.UserID]
+
+	return profile, nil
 }
 
 func (uc *profileUseCase) UpdateProfile(profile *models.Profile) error {
 	return uc.profileRepo.UpdateProfile(profile)
 }
 
-func (uc *profileUseCase) ViewProfiles(userID uuid.UUID) ([]models.Profile, error) {
-	user, err := uc.userRepo.GetUserByID(userID)
+func (uc *profileUseCase) ViewProfiles(userID uuid.UUID, cursor string) (*models.ProfilePage, error) {
+	swipes, err := uc.quotaUseCase.GetQuota(userID, models.QuotaDailySwipes)
 	if err != nil {
 		return nil, err
 	}
 
-	excludedUserID, err := uc.swipeRepo.GetSwipedUsersID(userID, time.Now())
+	if !swipes.Unlimited && swipes.Remaining <= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrQuotaExceeded, models.QuotaDailySwipes)
+	}
+
+	if err := uc.profileRepo.TouchLastActive(userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	deck, err := uc.getDeck(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	afterPosition, err := decodeDeckCursor(cursor, deck)
 	if err != nil {
 		return nil, err
 	}
 
-	if !user.IsPremium && len(excludedUserID) >= 10 {
-		return nil, errors.New("daily profile view limit reached")
+	// There is no point in showing more profiles than the user can still swipe on today
+	limit := uc.deckConfig.PageSize
+	if !swipes.Unlimited && swipes.Remaining < limit {
+		limit = swipes.Remaining
 	}
 
-	matchedProfileIDs, err := uc.matchRepo.GetMatchedUsersID(userID, time.Now())
+	entries, err := uc.deckRepo.GetEntries(userID, afterPosition, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	excludedUserID = append(excludedUserID, matchedProfileIDs...)
-	excludedUserID = append(excludedUserID, userID)
+	page := &models.ProfilePage{Profiles: []models.Profile{}}
+	if len(entries) == 0 {
+		uc.refillDeckIfLow(userID)
+		return page, nil
+	}
 
-	excludedUserID = utils.DistinctUUIDs(excludedUserID)
+	candidateIDs := make([]uuid.UUID, len(entries))
+	for i, entry := range entries {
+		candidateIDs[i] = entry.CandidateUserID
+	}
 
-	limit := 10
-	if user.IsPremium {
-		limit = 50 // Set a high limit for premium users to represent unlimited
+	profiles, err := uc.profileRepo.GetProfilesByUserIDs(candidateIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	profiles, err := uc.profileRepo.GetProfilesExcluding(excludedUserID, limit)
+	// Keep the deck order, the profiles come back in arbitrary order
+	superLikerIDs, err := uc.swipeRepo.GetSuperLikerIDs(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return profiles, nil
+	if err := setDistances(uc.profileRepo, userID, profiles); err != nil {
+		return nil, err
+	}
+
+	profileByUser := make(map[uuid.UUID]models.Profile, len(profiles))
+	for _, profile := range profiles {
+		profile.SuperLikedYou = utils.ContainsUUID(superLikerIDs, profile.UserID)
+		profileByUser[profile.UserID] = profile
+	}
+	for _, candidateID := range candidateIDs {
+		if profile, ok := profileByUser[candidateID]; ok {
+			page.Profiles = append(page.Profiles, profile)
+		}
+	}
+
+	shownIDs := make([]uuid.UUID, len(page.Profiles))
+	for i, profile := range page.Profiles {
+		shownIDs[i] = profile.UserID
+	}
+	if err := uc.profileRepo.RecordImpressions(userID, shownIDs, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == limit {
+		page.NextCursor = encodeDeckCursor(deck, entries[len(entries)-1].Position)
+	}
+
+	uc.refillDeckIfLow(userID)
+
+	return page, nil
+}
+
+// setDistances tells how far each of profiles is from the viewer. The distance is rounded up
+// to whole kilometres, so it gives no exact location away.
+func setDistances(profileRepo repository.ProfileRepository, viewerID uuid.UUID, profiles []models.Profile) error {
+	viewer, err := profileRepo.GetProfileByUserID(viewerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range profiles {
+		if distance, ok := recommender.DistanceKm(viewer, &profiles[i]); ok {
+			km := int(math.Max(1, math.Ceil(distance)))
+			profiles[i].DistanceKm = &km
+		}
+	}
+	return nil
+}
+
+func (uc *profileUseCase) GetPreference(userID uuid.UUID) (*models.Preference, error) {
+	return uc.profileRepo.GetPreferenceByUserID(userID)
+}
+
+func (uc *profileUseCase) UpdatePreference(preference *models.Preference) error {
+	if preference.MinAge > 0 && preference.MaxAge > 0 && preference.MinAge > preference.MaxAge {
+		return errors.New("min age must not be greater than max age")
+	}
+	if err := uc.profileRepo.UpsertPreference(preference); err != nil {
+		return err
+	}
+
+	// The deck was ranked for the old preferences, build a new one on the next view
+	return uc.deckRepo.DeleteDeck(preference.UserID)
 }
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type QuotaUseCase interface {
+	Consume(userID uuid.UUID, quota string) (*models.QuotaStatus, error)
+	Release(userID uuid.UUID, quota string) error
+	GetQuota(userID uuid.UUID, quota string) (*models.QuotaStatus, error)
+	GetQuotas(userID uuid.UUID) ([]models.QuotaStatus, error)
+}
+
+type quotaUseCase struct {
+	quotaRepo repository.QuotaRepository
+	userRepo  repository.UserRepository
+	cfg       config.QuotaConfig
+}
+
+func NewQuotaUseCase(quotaRepo repository.QuotaRepository, userRepo repository.UserRepository, cfg config.QuotaConfig) QuotaUseCase {
+	return &quotaUseCase{quotaRepo, userRepo, cfg}
+}
+
+func (uc *quotaUseCase) Consume(userID uuid.UUID, quota string) (*models.QuotaStatus, error) {
+	user, plan, limit, err := uc.limitFor(userID, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := quotaWindow(limit.Period, userNow(user))
+	status := newQuotaStatus(quota, plan, limit, end)
+
+	if limit.Limit == 0 {
+		return status, fmt.Errorf("%w: %s", ErrQuotaExceeded, quota)
+	}
+
+	used, ok, err := uc.quotaRepo.Increment(userID, quota, start, limit.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	setQuotaUsed(status, used)
+	if !ok {
+		return status, fmt.Errorf("%w: %s", ErrQuotaExceeded, quota)
+	}
+
+	return status, nil
+}
+
+// Release gives back one use of a quota that was consumed for an action which then failed.
+func (uc *quotaUseCase) Release(userID uuid.UUID, quota string) error {
+	user, _, limit, err := uc.limitFor(userID, quota)
+	if err != nil {
+		return err
+	}
+
+	start, _ := quotaWindow(limit.Period, userNow(user))
+	return uc.quotaRepo.Decrement(userID, quota, start)
+}
+
+func (uc *quotaUseCase) GetQuota(userID uuid.UUID, quota string) (*models.QuotaStatus, error) {
+	statuses, err := uc.GetQuotas(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statuses {
+		if statuses[i].Name == quota {
+			return &statuses[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown quota %q", quota)
+}
+
+func (uc *quotaUseCase) GetQuotas(userID uuid.UUID) ([]models.QuotaStatus, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planFor(user)
+	now := userNow(user)
+	limits := uc.cfg.Plans[plan]
+
+	// Monthly windows start the earliest, so this covers every current counter
+	earliest, _ := quotaWindow(config.QuotaPeriodMonth, now)
+	if weekStart, _ := quotaWindow(config.QuotaPeriodWeek, now); weekStart.Before(earliest) {
+		earliest = weekStart
+	}
+
+	counters, err := uc.quotaRepo.GetCounters(userID, earliest)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.QuotaStatus, 0, len(limits))
+	for name, limit := range limits {
+		start, end := quotaWindow(limit.Period, now)
+		status := newQuotaStatus(name, plan, limit, end)
+
+		used := 0
+		for _, counter := range counters {
+			if counter.Quota == name && counter.WindowStart.Equal(start) {
+				used = counter.Used
+			}
+		}
+		setQuotaUsed(status, used)
+
+		statuses = append(statuses, *status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses, nil
+}
+
+func (uc *quotaUseCase) limitFor(userID uuid.UUID, quota string) (*models.User, string, config.QuotaLimit, error) {
+	user, err := uc.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, "", config.QuotaLimit{}, err
+	}
+
+	plan := planFor(user)
+	limit, ok := uc.cfg.Plans[plan][quota]
+	if !ok {
+		return nil, "", config.QuotaLimit{}, fmt.Errorf("unknown quota %q", quota)
+	}
+
+	return user, plan, limit, nil
+}
+
+func planFor(user *models.User) string {
+	if user.HasPremium(time.Now()) {
+		return config.PlanPremium
+	}
+	return config.PlanFree
+}
+
+// userNow is the current time in the user's own timezone, so quota windows reset at
+// their local midnight rather than the server's.
+func userNow(user *models.User) time.Time {
+	return time.Now().In(utils.LoadLocation(user.Timezone))
+}
+
+func newQuotaStatus(name, plan string, limit config.QuotaLimit, resetsAt time.Time) *models.QuotaStatus {
+	return &models.QuotaStatus{
+		Name:      name,
+		Plan:      plan,
+		Limit:     limit.Limit,
+		Unlimited: limit.Limit < 0,
+		ResetsAt:  resetsAt,
+	}
+}
+
+func setQuotaUsed(status *models.QuotaStatus, used int) {
+	status.Used = used
+	if status.Unlimited {
+		status.Remaining = -1
+		return
+	}
+
+	status.Remaining = status.Limit - used
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+}
+
+// quotaWindow returns the [start, end) window of the given period that contains now,
+// in now's location. Weeks start on Monday.
+func quotaWindow(period string, now time.Time) (time.Time, time.Time) {
+	year, month, day := now.Date()
+	loc := now.Location()
+
+	switch period {
+	case config.QuotaPeriodWeek:
+		offset := (int(now.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc), time.Date(year, month, day-offset+7, 0, 0, 0, 0, loc)
+	case config.QuotaPeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc), time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	default:
+		return utils.DayWindow(now, loc)
+	}
+}
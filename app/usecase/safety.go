@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrSelfBlock      = errors.New("users cannot block or report themselves")
+	ErrInvalidReport  = errors.New("invalid report")
+	ErrReportMessages = errors.New("reported messages must have been sent to the reporter by the reported user")
+)
+
+const (
+	// maxReportDetails is how many characters of free text a report takes
+	maxReportDetails = 2000
+	// maxReportMessages is how many messages a report can refer to
+	maxReportMessages = 20
+)
+
+// ReportRequest is a report as a user files it.
+type ReportRequest struct {
+	Category   models.ReportCategory
+	Details    string
+	MessageIDs []uuid.UUID
+}
+
+// SafetyUseCase lets users block and report one another.
+type SafetyUseCase interface {
+	// Block returns the unmatch of the room the users shared, nil if they had none.
+	Block(userID, blockedUserID uuid.UUID) (*models.Unmatch, error)
+	Report(reporterID, reportedUserID uuid.UUID, request ReportRequest) (*models.Report, error)
+}
+
+type safetyUseCase struct {
+	blockRepo         repository.BlockRepository
+	matchRepo         repository.MatchRepository
+	deckRepo          repository.DeckRepository
+	userRepo          repository.UserRepository
+	moderationUseCase ModerationUseCase
+}
+
+func NewSafetyUseCase(blockRepo repository.BlockRepository, matchRepo repository.MatchRepository, deckRepo repository.DeckRepository, userRepo repository.UserRepository, moderationUseCase ModerationUseCase) SafetyUseCase {
+	return &safetyUseCase{blockRepo, matchRepo, deckRepo, userRepo, moderationUseCase}
+}
+
+// Block keeps blockedUserID away from userID for good: the two drop out of each other's
+// discovery and the room they shared is closed, which stops any further messages.
+func (uc *safetyUseCase) Block(userID, blockedUserID uuid.UUID) (*models.Unmatch, error) {
+	if userID == blockedUserID {
+		return nil, ErrSelfBlock
+	}
+	if err := uc.checkUser(blockedUserID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.blockRepo.CreateBlock(&models.Block{UserID: userID, BlockedUserID: blockedUserID}); err != nil {
+		return nil, err
+	}
+
+	// Profiles already queued in either deck are not filtered again
+	if err := uc.deckRepo.ConsumeEntry(userID, blockedUserID); err != nil {
+		return nil, err
+	}
+	if err := uc.deckRepo.ConsumeEntry(blockedUserID, userID); err != nil {
+		return nil, err
+	}
+
+	matchRoom, err := uc.matchRepo.GetMatchBetween(userID, blockedUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	unmatch := &models.Unmatch{MatchRoomID: matchRoom.ID, UserID: userID, Reason: models.UnmatchBlocked, UnmatchedAt: time.Now()}
+	if err := uc.matchRepo.Unmatch(unmatch); err != nil {
+		return nil, err
+	}
+	return unmatch, nil
+}
+
+// Report files a report of reportedUserID on their moderation case. The messages it refers
+// to must have been sent by the reported user in a room the reporter took part in, closed
+// rooms included.
+func (uc *safetyUseCase) Report(reporterID, reportedUserID uuid.UUID, request ReportRequest) (*models.Report, error) {
+	if reporterID == reportedUserID {
+		return nil, ErrSelfBlock
+	}
+	if !request.Category.Valid() || len([]rune(request.Details)) > maxReportDetails || len(request.MessageIDs) > maxReportMessages {
+		return nil, ErrInvalidReport
+	}
+	if err := uc.checkUser(reportedUserID); err != nil {
+		return nil, err
+	}
+
+	report := &models.Report{
+		ReporterID:     reporterID,
+		ReportedUserID: reportedUserID,
+		Category:       request.Category,
+		Details:        request.Details,
+		Messages:       []models.ReportedMessage{},
+	}
+	for _, messageID := range request.MessageIDs {
+		message, err := uc.reportedMessage(reporterID, reportedUserID, messageID)
+		if err != nil {
+			return nil, err
+		}
+		report.Messages = append(report.Messages, *message)
+	}
+
+	if _, err := uc.moderationUseCase.FileReport(report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// reportedMessage copies a message the reported user sent to the reporter.
+func (uc *safetyUseCase) reportedMessage(reporterID, reportedUserID, messageID uuid.UUID) (*models.ReportedMessage, error) {
+	message, err := uc.matchRepo.GetMessage(messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && message.SenderID != reportedUserID) {
+		return nil, ErrReportMessages
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	participants, err := uc.matchRepo.GetParticipants(message.MatchRoomID)
+	if err != nil {
+		return nil, err
+	}
+	for _, participant := range participants {
+		if participant.UserID == reporterID {
+			return &models.ReportedMessage{
+				MessageID:   message.ID,
+				MatchRoomID: message.MatchRoomID,
+				SenderID:    message.SenderID,
+				Content:     message.Content,
+				SentAt:      message.CreatedAt,
+			}, nil
+		}
+	}
+	return nil, ErrReportMessages
+}
+
+func (uc *safetyUseCase) checkUser(userID uuid.UUID) error {
+	_, err := uc.userRepo.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
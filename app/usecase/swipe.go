@@ -1,49 +1,253 @@
 package usecase
 
 import (
+	"errors"
+	"log"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/antibot"
 	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/recommender"
 	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSwipeType = errors.New("invalid swipe type")
+	ErrNothingToRewind  = errors.New("no swipe to rewind")
+	ErrRewindMatched    = errors.New("swipe created a match and cannot be rewound")
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different swipe
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different swipe")
 )
 
 type SwipeUseCase interface {
-	Swipe(swipe *models.Swipe) error
+	Swipe(swipe *models.Swipe) (*models.SwipeResult, error)
+	Rewind(userID uuid.UUID) (*models.Swipe, error)
+	LikesReceived(userID uuid.UUID, cursor string) (*models.LikesPage, error)
+	History(userID uuid.UUID, types []models.SwipeType, cursor string) (*models.SwipeHistoryPage, error)
 }
 
 type swipeUseCase struct {
-	swipeRepo repository.SwipeRepository
-	matchRepo repository.MatchRepository
+	swipeRepo         repository.SwipeRepository
+	matchRepo         repository.MatchRepository
+	deckRepo          repository.DeckRepository
+	profileRepo       repository.ProfileRepository
+	userRepo          repository.UserRepository
+	recommender       recommender.Recommender
+	quotaUseCase      QuotaUseCase
+	detector          antibot.Detector
+	moderationUseCase ModerationUseCase
+	cfg               config.SwipeConfig
+}
+
+func NewSwipeUseCase(swipeRepo repository.SwipeRepository, matchRepo repository.MatchRepository, deckRepo repository.DeckRepository, profileRepo repository.ProfileRepository, userRepo repository.UserRepository, recommender recommender.Recommender, quotaUseCase QuotaUseCase, detector antibot.Detector, moderationUseCase ModerationUseCase, cfg config.SwipeConfig) SwipeUseCase {
+	return &swipeUseCase{swipeRepo, matchRepo, deckRepo, profileRepo, userRepo, recommender, quotaUseCase, detector, moderationUseCase, cfg}
+}
+
+func (uc *swipeUseCase) Swipe(swipe *models.Swipe) (*models.SwipeResult, error) {
+	if !swipe.Type.Valid() {
+		return nil, ErrInvalidSwipeType
+	}
+
+	// A retry is answered with the stored swipe before anything is counted again
+	if result, err := uc.replay(swipe); result != nil || err != nil {
+		return result, err
+	}
+
+	// Users suspected of automated swiping are slowed down before anything is counted
+	if err := uc.detector.Allow(swipe.UserID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// A super like is a swipe as well, so it counts against both quotas
+	quotas := []string{models.QuotaDailySwipes}
+	if swipe.Type == models.SwipeSuperLike {
+		quotas = append(quotas, models.QuotaSuperLikes)
+	}
+	for i, quota := range quotas {
+		if _, err := uc.quotaUseCase.Consume(swipe.UserID, quota); err != nil {
+			uc.releaseQuotas(swipe.UserID, quotas[:i])
+			return nil, err
+		}
+	}
+
+	targetUserID, swipeType := swipe.TargetUserID, swipe.Type
+	result, err := uc.swipeRepo.CreateSwipe(swipe)
+	if err != nil {
+		uc.releaseQuotas(swipe.UserID, quotas)
+		return nil, err
+	}
+
+	// A concurrent retry was recorded in between, it was counted and acted on already
+	if result.Replayed {
+		uc.releaseQuotas(swipe.UserID, quotas)
+		if swipe.TargetUserID != targetUserID || swipe.Type != swipeType {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return result, nil
+	}
+
+	// The swipe is stored, what follows is bookkeeping that must not fail it
+	uc.afterSwipe(swipe, result)
+	return result, nil
 }
 
-func NewSwipeUseCase(swipeRepo repository.SwipeRepository, matchRepo repository.MatchRepository) SwipeUseCase {
-	return &swipeUseCase{swipeRepo, matchRepo}
+// replay returns the result of the swipe the user already recorded under the idempotency
+// key of swipe, copying the stored swipe into it. It returns nil for a swipe not seen yet.
+func (uc *swipeUseCase) replay(swipe *models.Swipe) (*models.SwipeResult, error) {
+	if swipe.IdempotencyKey == "" {
+		return nil, nil
+	}
+
+	stored, err := uc.swipeRepo.GetSwipeByIdempotencyKey(swipe.UserID, swipe.IdempotencyKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.TargetUserID != swipe.TargetUserID || stored.Type != swipe.Type {
+		return nil, ErrIdempotencyKeyReused
+	}
+	*swipe = *stored
+
+	result := &models.SwipeResult{Replayed: true}
+	if swipe.Type.IsLike() {
+		match, err := uc.matchRepo.GetMatchBetween(swipe.UserID, swipe.TargetUserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		result.Match = match
+	}
+	return result, nil
+}
+
+// afterSwipe updates the antibot detector, the decks and the ratings with a recorded swipe.
+// Failures are logged, the swipe itself stands either way.
+func (uc *swipeUseCase) afterSwipe(swipe *models.Swipe, result *models.SwipeResult) {
+	level, err := uc.detector.Observe(swipe, time.Now())
+	if err != nil {
+		log.Printf("swipe: observing the swipe of %s failed: %v", swipe.UserID, err)
+	}
+	// Swiping at that pace is left to a moderator to look into
+	if level == models.BotLevelFlag {
+		if _, err := uc.moderationUseCase.OpenCase(swipe.UserID, models.CaseSourceBot, "Flagged for automated swiping"); err != nil {
+			log.Printf("swipe: flagging %s for automated swiping failed: %v", swipe.UserID, err)
+		}
+	}
+
+	if err := uc.deckRepo.ConsumeEntry(swipe.UserID, swipe.TargetUserID); err != nil {
+		log.Printf("swipe: removing %s from the deck of %s failed: %v", swipe.TargetUserID, swipe.UserID, err)
+	}
+
+	if err := uc.recommender.RecordSwipe(swipe); err != nil {
+		log.Printf("swipe: recording the swipe %s for the ratings failed: %v", swipe.ID, err)
+	} else if swipe.RatingChange != 0 {
+		// Kept for a rewind to take back
+		if err := uc.swipeRepo.SetRatingChange(swipe.ID, swipe.RatingChange); err != nil {
+			log.Printf("swipe: saving the rating change of %s failed: %v", swipe.ID, err)
+		}
+	}
+
+	// An unanswered super like puts the sender at the front of the recipient's deck
+	if swipe.Type == models.SwipeSuperLike && result.Match == nil {
+		if err := uc.deckRepo.PushFront(swipe.TargetUserID, swipe.UserID); err != nil {
+			log.Printf("swipe: pushing %s onto the deck of %s failed: %v", swipe.UserID, swipe.TargetUserID, err)
+		}
+	}
 }
 
-func (uc *swipeUseCase) Swipe(swipe *models.Swipe) error {
+// Rewind undoes the user's most recent swipe if it is still within the rewind window, along
+// with its effect on the target's rating, and puts the profile back at the front of their
+// deck.
+func (uc *swipeUseCase) Rewind(userID uuid.UUID) (*models.Swipe, error) {
+	swipe, err := uc.swipeRepo.GetLastSwipe(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNothingToRewind
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(swipe.CreatedAt) > uc.cfg.RewindWindow {
+		return nil, ErrNothingToRewind
+	}
+
+	var match *models.MatchRoom
+	if swipe.Type.IsLike() {
+		match, err = uc.rewindableMatch(swipe)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if err := uc.swipeRepo.CreateSwipe(swipe); err != nil {
-		return err
+	if _, err := uc.quotaUseCase.Consume(userID, models.QuotaRewinds); err != nil {
+		return nil, err
 	}
 
-	if swipe.Liked {
-		// Check if there is a mutual like
-		target, err := uc.swipeRepo.GetSwipe(swipe.TargetUserID, swipe.UserID)
-		if err == nil && target.Liked {
-			matchID := uuid.New()
-			match := &models.MatchRoom{
-				ID:           matchID,
-				UserID:       swipe.UserID,
-				TargetUserID: swipe.TargetUserID,
-			}
-			uc.matchRepo.CreateMatch(match)
+	var matchID *uuid.UUID
+	if match != nil {
+		matchID = &match.ID
+	}
+	err = uc.swipeRepo.RewindSwipe(swipe, matchID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Rewound by a concurrent request
+		uc.quotaUseCase.Release(userID, models.QuotaRewinds)
+		return nil, ErrNothingToRewind
+	}
+	if err != nil {
+		uc.quotaUseCase.Release(userID, models.QuotaRewinds)
+		return nil, err
+	}
 
-			match2 := &models.MatchRoom{
-				ID:           matchID,
-				UserID:       swipe.TargetUserID,
-				TargetUserID: swipe.UserID,
-			}
-			uc.matchRepo.CreateMatch(match2)
+	// The swipe is rewound, the decks catch up as well as they can
+	if swipe.Type == models.SwipeSuperLike {
+		// A rewound super like no longer jumps the recipient's queue
+		if err := uc.deckRepo.ConsumeEntry(swipe.TargetUserID, userID); err != nil {
+			log.Printf("swipe: taking %s off the deck of %s failed: %v", userID, swipe.TargetUserID, err)
 		}
 	}
-	return nil
+	if err := uc.deckRepo.PushFront(userID, swipe.TargetUserID); err != nil {
+		log.Printf("swipe: pushing %s back onto the deck of %s failed: %v", swipe.TargetUserID, userID, err)
+	}
+
+	return swipe, nil
+}
+
+// rewindableMatch returns the match the swipe created, if any, when the configured policy
+// allows unwinding it. A match that already has messages is never unwound, nor is one the
+// swipe did not make: one from before it, or one the later like of the target made.
+func (uc *swipeUseCase) rewindableMatch(swipe *models.Swipe) (*models.MatchRoom, error) {
+	match, err := uc.matchRepo.GetMatchBetween(swipe.UserID, swipe.TargetUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if uc.cfg.RewindMatchPolicy != config.RewindMatchUnwind {
+		return nil, ErrRewindMatched
+	}
+	madeBySwipe := match.MatchedByID != nil && *match.MatchedByID == swipe.UserID && !match.CreatedAt.Before(swipe.CreatedAt)
+	if !madeBySwipe {
+		return nil, ErrRewindMatched
+	}
+
+	messages, err := uc.matchRepo.CountMessages(match.ID)
+	if err != nil {
+		return nil, err
+	}
+	if messages > 0 {
+		return nil, ErrRewindMatched
+	}
+
+	return match, nil
+}
+
+func (uc *swipeUseCase) releaseQuotas(userID uuid.UUID, quotas []string) {
+	for _, quota := range quotas {
+		uc.quotaUseCase.Release(userID, quota)
+	}
 }
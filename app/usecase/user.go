@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +11,11 @@ import (
 	"github.com/mdzakyabd/dating-app/app/utils"
 )
 
+var (
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	ErrAccountBarred   = errors.New("account is banned or suspended")
+)
+
 type UserUseCase interface {
 	Register(user *models.User) error
 	Login(email, password string) (*models.User, error)
@@ -27,6 +33,10 @@ func NewUserUseCase(userRepository repository.UserRepository) UserUseCase {
 }
 
 func (uc *userUseCase) Register(data *models.User) error {
+	if err := validateTimezone(data.Timezone); err != nil {
+		return err
+	}
+
 	user, err := uc.userRepository.GetUserByEmail(data.Email)
 	if err != nil {
 		return err
@@ -52,10 +62,16 @@ func (uc *userUseCase) Login(email, password string) (*models.User, error) {
 	if !utils.CheckPasswordHash(password, user.Password) {
 		return nil, errors.New("invalid credentials")
 	}
+	if user.Barred(time.Now()) {
+		return nil, ErrAccountBarred
+	}
 	return user, nil
 }
 
 func (uc *userUseCase) UpdateUser(user *models.User) error {
+	if err := validateTimezone(user.Timezone); err != nil {
+		return err
+	}
 	return uc.userRepository.UpdateUser(user)
 }
 
@@ -66,3 +82,14 @@ func (uc *userUseCase) GetUserByID(id uuid.UUID) (*models.User, error) {
 func (uc *userUseCase) SubscribePremium(userID uuid.UUID, expiry time.Time) error {
 	return uc.userRepository.UpdatePremiumStatus(userID, true, expiry)
 }
+
+// validateTimezone accepts an empty name, which leaves the user on UTC, or any IANA zone.
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimezone, name)
+	}
+	return nil
+}
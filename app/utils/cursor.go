@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const cursorSeparator = "|"
+
+// EncodeCursor packs values into an opaque, URL safe pagination cursor.
+func EncodeCursor(values ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(values, cursorSeparator)))
+}
+
+// DecodeCursor unpacks a cursor created by EncodeCursor, expecting exactly n values.
+func DecodeCursor(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	values := strings.Split(string(raw), cursorSeparator)
+	if len(values) != n {
+		return nil, ErrInvalidCursor
+	}
+
+	return values, nil
+}
@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 )
 
+var ErrInvalidToken = errors.New("invalid token")
+
 func GenerateJWT(userID, secret string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
@@ -19,3 +23,33 @@ func GenerateJWT(userID, secret string) (string, error) {
 
 	return tokenString, nil
 }
+
+// ParseJWT validates a token created by GenerateJWT and returns the user it was issued to.
+func ParseJWT(tokenString, secret string) (uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	// Claims are decoded from JSON, so the ID is a string
+	subject, ok := claims["user_id"].(string)
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return userID, nil
+}
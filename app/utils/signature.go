@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Sign returns a URL safe signature of values, for links that are checked without a session.
+func Sign(secret string, values ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(values, cursorSeparator)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is what Sign returns for values.
+func VerifySignature(secret, signature string, values ...string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, values...)))
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"time"
+
+	// Embed the IANA database so user timezones resolve on hosts without zoneinfo
+	_ "time/tzdata"
+)
+
+// LoadLocation resolves an IANA timezone name, falling back to UTC for empty or unknown names.
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// DayWindow returns the [start, end) range of the calendar day containing t in loc.
+// Days are not always 24 hours long: around DST transitions they can be 23 or 25.
+func DayWindow(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := t.In(loc)
+	year, month, day := local.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return start, time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+}
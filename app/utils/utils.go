@@ -18,3 +18,13 @@ func DistinctUUIDs(input []uuid.UUID) []uuid.UUID {
 
 	return uniqueList
 }
+
+// ContainsUUID reports whether id is in the slice.
+func ContainsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/recommender"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/config"
+)
+
+// Replays historical swipes through the recommender scoring and reports how much
+// more often the top ranked profiles were liked compared to everything swiped.
+func main() {
+	days := flag.Int("days", 30, "number of days of swipe history to replay")
+	k := flag.Int("k", 5, "number of top ranked profiles per session to measure")
+	flag.Parse()
+
+	db, err := config.ConfigDB()
+	if err != nil {
+		panic(err)
+	}
+
+	recommenderConfig, err := config.ConfigRecommender()
+	if err != nil {
+		panic(err)
+	}
+
+	profileRepo := repository.NewProfileRepository(db)
+	swipeRepo := repository.NewSwipeRepository(db)
+
+	profiles, err := profileRepo.GetProfiles()
+	if err != nil {
+		panic(err)
+	}
+
+	userIDs := make([]uuid.UUID, len(profiles))
+	for i, profile := range profiles {
+		userIDs[i] = profile.UserID
+	}
+
+	preferences, err := profileRepo.GetPreferencesByUserIDs(userIDs)
+	if err != nil {
+		panic(err)
+	}
+
+	swipes, err := swipeRepo.GetSwipesSince(time.Now().AddDate(0, 0, -*days))
+	if err != nil {
+		panic(err)
+	}
+
+	result := recommender.Evaluate(profiles, preferences, swipes, recommenderConfig, *k)
+
+	fmt.Printf("sessions: %d\n", result.Sessions)
+	fmt.Printf("swipes replayed: %d (skipped %d without profile data)\n", result.Swipes, result.SkippedNoData)
+	fmt.Printf("baseline like-rate: %.4f\n", result.BaselineRate)
+	fmt.Printf("top-%d like-rate: %.4f\n", result.TopK, result.TopKLikeRate)
+	fmt.Printf("lift: %.3f\n", result.Lift)
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/mdzakyabd/dating-app/app/antibot"
 	"github.com/mdzakyabd/dating-app/app/handler"
-	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/middleware"
+	"github.com/mdzakyabd/dating-app/app/migrations"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/recommender"
 	"github.com/mdzakyabd/dating-app/app/repository"
 	"github.com/mdzakyabd/dating-app/app/routes"
 	"github.com/mdzakyabd/dating-app/app/scheduler"
+	"github.com/mdzakyabd/dating-app/app/screening"
+	"github.com/mdzakyabd/dating-app/app/storage"
 	"github.com/mdzakyabd/dating-app/app/usecase"
 	"github.com/mdzakyabd/dating-app/config"
 )
@@ -20,9 +28,11 @@ func main() {
 	}
 
 	// Migrate the schema
-	db.AutoMigrate(&models.User{}, &models.Profile{}, &models.MatchRoom{}, &models.Message{}, &models.Swipe{})
+	if err := migrations.Run(db); err != nil {
+		panic(err)
+	}
 
-	pusherClient, err := config.ConfigPusher()
+	realtimeConfig, err := config.ConfigRealtime()
 	if err != nil {
 		panic(err)
 	}
@@ -32,35 +42,156 @@ func main() {
 		panic(err)
 	}
 
+	recommenderConfig, err := config.ConfigRecommender()
+	if err != nil {
+		panic(err)
+	}
+
+	deckConfig, err := config.ConfigDeck()
+	if err != nil {
+		panic(err)
+	}
+
+	quotaConfig, err := config.ConfigQuota()
+	if err != nil {
+		panic(err)
+	}
+
+	swipeConfig, err := config.ConfigSwipe()
+	if err != nil {
+		panic(err)
+	}
+
+	boostConfig, err := config.ConfigBoost()
+	if err != nil {
+		panic(err)
+	}
+
+	antiBotConfig, err := config.ConfigAntiBot()
+	if err != nil {
+		panic(err)
+	}
+
+	chatConfig, err := config.ConfigChat()
+	if err != nil {
+		panic(err)
+	}
+
+	moderationConfig, err := config.ConfigModeration()
+	if err != nil {
+		panic(err)
+	}
+
+	screeningConfig, err := config.ConfigScreening()
+	if err != nil {
+		panic(err)
+	}
+
+	presenceConfig, err := config.ConfigPresence()
+	if err != nil {
+		panic(err)
+	}
+
+	attachmentConfig, err := config.ConfigAttachment()
+	if err != nil {
+		panic(err)
+	}
+
+	blobStore, err := storage.NewLocalStore(attachmentConfig.StorageDir)
+	if err != nil {
+		panic(err)
+	}
+
 	userRepo := repository.NewUserRepository(db)
 	userUC := usecase.NewUserUseCase(userRepo)
-	userHandler := handler.NewUserHandler(userUC)
+	userHandler := handler.NewUserHandler(userUC, jwtSecret)
+
+	quotaRepo := repository.NewQuotaRepository(db)
+	quotaUC := usecase.NewQuotaUseCase(quotaRepo, userRepo, quotaConfig)
+	quotaHandler := handler.NewQuotaHandler(quotaUC)
 
 	matchRepo := repository.NewMatchRepository(db)
-	matchUC := usecase.NewMatchUsecase(matchRepo)
-	matchHandler := handler.NewMatchHandler(matchUC, pusherClient)
+	profileRepo := repository.NewProfileRepository(db)
+	presenceUC := usecase.NewPresenceUseCase(profileRepo, presenceConfig)
+
+	// The hub serves the WebSocket and event stream endpoints whichever provider is set
+	backplane := realtime.NewPostgresBackplane(db, realtimeConfig.DSN, realtimeConfig.NotifyChannel)
+	hub := realtime.NewHub(matchRepo, backplane, presenceUC, realtimeConfig)
+	go hub.Run(context.Background())
+	realtimeHandler := handler.NewRealtimeHandler(hub, userUC, jwtSecret)
+
+	var publisher realtime.RealtimePublisher = hub
+	if realtimeConfig.Provider == config.RealtimePusher {
+		pusherClient, err := config.ConfigPusher()
+		if err != nil {
+			panic(err)
+		}
+		publisher = realtime.NewMultiPublisher(realtime.NewPusherPublisher(pusherClient), hub)
+	}
+
+	moderationRepo := repository.NewModerationRepository(db)
+	moderationUC := usecase.NewModerationUseCase(moderationRepo, userRepo, profileRepo, matchRepo, moderationConfig)
+	moderationHandler := handler.NewModerationHandler(moderationUC, publisher)
+
+	matchUC := usecase.NewMatchUsecase(matchRepo, userRepo, presenceUC, screening.NewScreener(screeningConfig), moderationUC, quotaUC, chatConfig)
+	matchHandler := handler.NewMatchHandler(matchUC, publisher)
+
+	icebreakerRepo := repository.NewIcebreakerRepository(db)
+	icebreakerUC := usecase.NewIcebreakerUseCase(icebreakerRepo, matchRepo, profileRepo)
+	icebreakerHandler := handler.NewIcebreakerHandler(icebreakerUC)
+
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	attachmentUC := usecase.NewAttachmentUseCase(attachmentRepo, matchRepo, blobStore, attachmentConfig)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentUC)
+
+	analyticsUC := usecase.NewAnalyticsUseCase(matchRepo)
+	adminHandler := handler.NewAdminHandler(analyticsUC)
+
+	boostRepo := repository.NewBoostRepository(db)
+	profileRecommender := recommender.NewRecommender(profileRepo, boostRepo, recommenderConfig)
+
+	deckRepo := repository.NewDeckRepository(db)
+	blockRepo := repository.NewBlockRepository(db)
+
+	botSignalRepo := repository.NewBotSignalRepository(db)
+	swipeDetector := antibot.NewDetector(botSignalRepo, antiBotConfig)
 
 	swipeRepo := repository.NewSwipeRepository(db)
-	swipeUC := usecase.NewSwipeUseCase(swipeRepo, matchRepo)
-	swipeHandler := handler.NewSwipeHandler(swipeUC)
+	swipeUC := usecase.NewSwipeUseCase(swipeRepo, matchRepo, deckRepo, profileRepo, userRepo, profileRecommender, quotaUC, swipeDetector, moderationUC, swipeConfig)
+	swipeHandler := handler.NewSwipeHandler(swipeUC, publisher)
 
-	profileRepo := repository.NewProfileRepository(db)
-	profileUC := usecase.NewProfileUseCase(profileRepo, userRepo, swipeRepo, matchRepo)
+	profileUC := usecase.NewProfileUseCase(profileRepo, userRepo, swipeRepo, matchRepo, blockRepo, deckRepo, profileRecommender, quotaUC, presenceUC, deckConfig)
 	profileHandler := handler.NewProfileHandler(profileUC)
 
+	safetyUC := usecase.NewSafetyUseCase(blockRepo, matchRepo, deckRepo, userRepo, moderationUC)
+	safetyHandler := handler.NewSafetyHandler(safetyUC, publisher)
+
+	boostUC := usecase.NewBoostUseCase(boostRepo, profileRepo, swipeRepo, quotaUC, boostConfig)
+	boostHandler := handler.NewBoostHandler(boostUC)
+
 	routeHandler := routes.AppRouteHandlers{
-		UserHandler:    *userHandler,
-		ProfileHandler: *profileHandler,
-		SwipeHandler:   *swipeHandler,
-		MatchHandler:   *matchHandler,
+		UserHandler:       *userHandler,
+		ProfileHandler:    *profileHandler,
+		SwipeHandler:      *swipeHandler,
+		MatchHandler:      *matchHandler,
+		QuotaHandler:      *quotaHandler,
+		BoostHandler:      *boostHandler,
+		AttachmentHandler: *attachmentHandler,
+		SafetyHandler:     *safetyHandler,
+		AdminHandler:      *adminHandler,
+		ModerationHandler: *moderationHandler,
+		IcebreakerHandler: *icebreakerHandler,
+		RealtimeHandler:   realtimeHandler,
 	}
 
-	r := gin.Default()
+	// gin's default logger would write the tokens of WebSocket connections to the log
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 	r.Use(cors.Default())
-	routes.Routes(r, routeHandler, jwtSecret)
+	routes.Routes(r, routeHandler, jwtSecret, quotaUC, presenceUC, userUC)
 
 	// Start the scheduler
-	checkExpiredScheduler := scheduler.NewScheduler(userRepo)
+	checkExpiredScheduler := scheduler.NewScheduler(userRepo, matchUC, attachmentUC, moderationUC, publisher)
 	checkExpiredScheduler.Start()
 
 	r.Run()
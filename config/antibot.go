@@ -0,0 +1,67 @@
+package config
+
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// AntiBotConfig holds the thresholds of the swipe velocity detector. Each swipe that
+// crosses a threshold is a strike, and the strikes within StrikeWindow decide how hard
+// the user is held back: friction first, then throttling, then a flag for moderation.
+type AntiBotConfig struct {
+	// Window is the sliding window MaxSwipes is counted over
+	Window    time.Duration
+	MaxSwipes int
+	// MaxLikeRatio is the highest share of likes allowed over the last LikeRatioSample swipes
+	LikeRatioSample int
+	MaxLikeRatio    float64
+	// Over the last TimingSample swipes the mean time between swipes must be at least
+	// MinInterval, and its coefficient of variation at least MinJitter, as people are not
+	// as regular as a script
+	TimingSample int
+	MinInterval  time.Duration
+	MinJitter    float64
+
+	StrikeWindow    time.Duration
+	FrictionStrikes int
+	ThrottleStrikes int
+	FlagStrikes     int
+	// FrictionInterval is the time a user under friction has to wait between swipes
+	FrictionInterval time.Duration
+	// ThrottleDuration is how long swiping is paused once a user is throttled
+	ThrottleDuration time.Duration
+}
+
+func ConfigAntiBot() (AntiBotConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return AntiBotConfig{}, err
+	}
+
+	cfg := AntiBotConfig{
+		Window:           time.Duration(getEnvInt("ANTIBOT_WINDOW_SECONDS", 60)) * time.Second,
+		MaxSwipes:        getEnvInt("ANTIBOT_MAX_SWIPES", 40),
+		LikeRatioSample:  getEnvInt("ANTIBOT_LIKE_RATIO_SAMPLE", 50),
+		MaxLikeRatio:     getEnvFloat("ANTIBOT_MAX_LIKE_RATIO", 0.95),
+		TimingSample:     getEnvInt("ANTIBOT_TIMING_SAMPLE", 20),
+		MinInterval:      time.Duration(getEnvInt("ANTIBOT_MIN_INTERVAL_MS", 500)) * time.Millisecond,
+		MinJitter:        getEnvFloat("ANTIBOT_MIN_JITTER", 0.15),
+		StrikeWindow:     time.Duration(getEnvInt("ANTIBOT_STRIKE_WINDOW_MINUTES", 60)) * time.Minute,
+		FrictionStrikes:  getEnvInt("ANTIBOT_FRICTION_STRIKES", 1),
+		ThrottleStrikes:  getEnvInt("ANTIBOT_THROTTLE_STRIKES", 5),
+		FlagStrikes:      getEnvInt("ANTIBOT_FLAG_STRIKES", 15),
+		FrictionInterval: time.Duration(getEnvInt("ANTIBOT_FRICTION_INTERVAL_SECONDS", 3)) * time.Second,
+		ThrottleDuration: time.Duration(getEnvInt("ANTIBOT_THROTTLE_MINUTES", 15)) * time.Minute,
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type AttachmentConfig struct {
+	// StorageDir is where the local blob store keeps the files
+	StorageDir string
+	// SigningKey signs the URLs attachments are served from
+	SigningKey string
+	// MaxImageSize and MaxVoiceSize are in bytes
+	MaxImageSize     int64
+	MaxVoiceSize     int64
+	MaxVoiceDuration time.Duration
+	// ThumbnailSize is the longest side of image thumbnails, in pixels
+	ThumbnailSize int
+	// UploadWindow is how long an upload slot accepts its file
+	UploadWindow time.Duration
+	// URLLifetime is how long a signed URL of an attachment stays valid
+	URLLifetime time.Duration
+}
+
+// ConfigAttachment reads ATTACHMENT_STORAGE_DIR, ATTACHMENT_SIGNING_KEY, ATTACHMENT_IMAGE_MAX_KB,
+// ATTACHMENT_VOICE_MAX_KB, ATTACHMENT_VOICE_MAX_SECONDS, ATTACHMENT_THUMBNAIL_SIZE,
+// ATTACHMENT_UPLOAD_WINDOW_MINUTES and ATTACHMENT_URL_TTL_SECONDS.
+func ConfigAttachment() (AttachmentConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return AttachmentConfig{}, err
+	}
+
+	cfg := AttachmentConfig{
+		StorageDir:       os.Getenv("ATTACHMENT_STORAGE_DIR"),
+		SigningKey:       os.Getenv("ATTACHMENT_SIGNING_KEY"),
+		MaxImageSize:     int64(getEnvInt("ATTACHMENT_IMAGE_MAX_KB", 10240)) << 10,
+		MaxVoiceSize:     int64(getEnvInt("ATTACHMENT_VOICE_MAX_KB", 5120)) << 10,
+		MaxVoiceDuration: time.Duration(getEnvInt("ATTACHMENT_VOICE_MAX_SECONDS", 300)) * time.Second,
+		ThumbnailSize:    getEnvInt("ATTACHMENT_THUMBNAIL_SIZE", 320),
+		UploadWindow:     time.Duration(getEnvInt("ATTACHMENT_UPLOAD_WINDOW_MINUTES", 15)) * time.Minute,
+		URLLifetime:      time.Duration(getEnvInt("ATTACHMENT_URL_TTL_SECONDS", 300)) * time.Second,
+	}
+
+	if cfg.StorageDir == "" {
+		cfg.StorageDir = "storage/attachments"
+	}
+	if cfg.SigningKey == "" {
+		return AttachmentConfig{}, fmt.Errorf("ATTACHMENT_SIGNING_KEY is required")
+	}
+
+	return cfg, nil
+}
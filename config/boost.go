@@ -0,0 +1,35 @@
+package config
+
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type BoostConfig struct {
+	Duration time.Duration
+	// BaselineWindow is how far back before a boost the usual views and likes are measured
+	BaselineWindow time.Duration
+}
+
+func ConfigBoost() (BoostConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return BoostConfig{}, err
+	}
+
+	cfg := BoostConfig{
+		Duration:       time.Duration(getEnvInt("BOOST_DURATION_MINUTES", 30)) * time.Minute,
+		BaselineWindow: time.Duration(getEnvInt("BOOST_BASELINE_DAYS", 7)) * 24 * time.Hour,
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// FirstMessageAnyone lets either participant open the conversation
+	FirstMessageAnyone = "anyone"
+	// FirstMessageLiker leaves the first message to the participant who liked first
+	FirstMessageLiker = "liker"
+	// FirstMessageMatcher leaves the first message to the participant whose like made the match
+	FirstMessageMatcher = "matcher"
+)
+
+type ChatConfig struct {
+	// MessagePageSize is how many messages are listed when the client gives no limit
+	MessagePageSize int
+	// MaxMessagePageSize caps the limit a client may ask for, and the changes returned per sync
+	MaxMessagePageSize int
+	// EditWindow is how long after sending a message its sender may edit it
+	EditWindow time.Duration
+	// UnmatchRetention is how long the messages of an unmatched room are kept for moderation
+	UnmatchRetention time.Duration
+	// FirstMessagePolicy is who may open the conversation of a match
+	FirstMessagePolicy string
+	// FirstMessageWindow is how long after matching the first message has to be sent, and
+	// MatchExtension how much longer a premium participant can give it once
+	FirstMessageWindow time.Duration
+	MatchExtension     time.Duration
+	// ReplyWindow is how long after the first message an answer has to come
+	ReplyWindow time.Duration
+	// ExpiryReminder is how long before a match expires its participants are reminded
+	ExpiryReminder time.Duration
+}
+
+// ConfigChat reads CHAT_MESSAGE_PAGE_SIZE, CHAT_MESSAGE_PAGE_SIZE_MAX, CHAT_EDIT_WINDOW_MINUTES,
+// CHAT_UNMATCH_RETENTION_DAYS, CHAT_FIRST_MESSAGE_POLICY, CHAT_FIRST_MESSAGE_WINDOW_HOURS,
+// CHAT_MATCH_EXTENSION_HOURS, CHAT_UNANSWERED_EXPIRY_DAYS and CHAT_EXPIRY_REMINDER_HOURS.
+// A window of 0 turns its expiry off.
+func ConfigChat() (ChatConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return ChatConfig{}, err
+	}
+
+	cfg := ChatConfig{
+		MessagePageSize:    getEnvInt("CHAT_MESSAGE_PAGE_SIZE", 50),
+		MaxMessagePageSize: getEnvInt("CHAT_MESSAGE_PAGE_SIZE_MAX", 100),
+		EditWindow:         time.Duration(getEnvInt("CHAT_EDIT_WINDOW_MINUTES", 15)) * time.Minute,
+		UnmatchRetention:   time.Duration(getEnvInt("CHAT_UNMATCH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		FirstMessagePolicy: os.Getenv("CHAT_FIRST_MESSAGE_POLICY"),
+		FirstMessageWindow: time.Duration(getEnvInt("CHAT_FIRST_MESSAGE_WINDOW_HOURS", 24)) * time.Hour,
+		MatchExtension:     time.Duration(getEnvInt("CHAT_MATCH_EXTENSION_HOURS", 24)) * time.Hour,
+		ReplyWindow:        time.Duration(getEnvInt("CHAT_UNANSWERED_EXPIRY_DAYS", 7)) * 24 * time.Hour,
+		ExpiryReminder:     time.Duration(getEnvInt("CHAT_EXPIRY_REMINDER_HOURS", 3)) * time.Hour,
+	}
+
+	if cfg.MessagePageSize < 1 || cfg.MessagePageSize > cfg.MaxMessagePageSize {
+		return ChatConfig{}, fmt.Errorf("CHAT_MESSAGE_PAGE_SIZE must be between 1 and CHAT_MESSAGE_PAGE_SIZE_MAX")
+	}
+
+	switch cfg.FirstMessagePolicy {
+	case "":
+		cfg.FirstMessagePolicy = FirstMessageAnyone
+	case FirstMessageAnyone, FirstMessageLiker, FirstMessageMatcher:
+	default:
+		return ChatConfig{}, fmt.Errorf("invalid CHAT_FIRST_MESSAGE_POLICY %q", cfg.FirstMessagePolicy)
+	}
+
+	if cfg.FirstMessageWindow < 0 || cfg.MatchExtension < 0 || cfg.ReplyWindow < 0 || cfg.ExpiryReminder < 0 {
+		return ChatConfig{}, fmt.Errorf("CHAT_FIRST_MESSAGE_WINDOW_HOURS, CHAT_MATCH_EXTENSION_HOURS, CHAT_UNANSWERED_EXPIRY_DAYS and CHAT_EXPIRY_REMINDER_HOURS must not be negative")
+	}
+
+	return cfg, nil
+}
@@ -30,15 +30,7 @@ func ConfigDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable timezone=Asia/Jakarta",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(postgres.Open(databaseDSN()), &gorm.Config{
 		Logger:      logger.Default.LogMode(logger.Info),
 		PrepareStmt: false,
 	})
@@ -49,3 +41,16 @@ func ConfigDB() (*gorm.DB, error) {
 
 	return db, nil
 }
+
+// databaseDSN builds the connection string from the DB_* env variables, which must
+// already be loaded.
+func databaseDSN() string {
+	dbHost := os.Getenv("DB_HOST")
+	dbPort := os.Getenv("DB_PORT")
+	dbUser := os.Getenv("DB_USER")
+	dbPassword := os.Getenv("DB_PASSWORD")
+	dbName := os.Getenv("DB_NAME")
+
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
+		dbHost, dbPort, dbUser, dbPassword, dbName)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type DeckConfig struct {
+	Size            int
+	Period          time.Duration
+	RefillThreshold int
+	PageSize        int
+	// BoostSlots is how many boosted profiles are put in front of a deck when it is generated
+	// or refilled
+	BoostSlots int
+	// PassRecycleAfter is how long a passed profile stays out of discovery, zero keeps it
+	// out for good. Liked profiles never come back.
+	PassRecycleAfter time.Duration
+}
+
+func ConfigDeck() (DeckConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return DeckConfig{}, err
+	}
+
+	cfg := DeckConfig{
+		Size:             getEnvInt("DECK_SIZE", 100),
+		Period:           time.Duration(getEnvInt("DECK_PERIOD_HOURS", 24)) * time.Hour,
+		RefillThreshold:  getEnvInt("DECK_REFILL_THRESHOLD", 20),
+		PageSize:         getEnvInt("DECK_PAGE_SIZE", 50),
+		BoostSlots:       getEnvInt("DECK_BOOST_SLOTS", 3),
+		PassRecycleAfter: time.Duration(getEnvInt("DECK_PASS_RECYCLE_DAYS", 30)) * 24 * time.Hour,
+	}
+
+	if cfg.Size < 1 {
+		return DeckConfig{}, fmt.Errorf("DECK_SIZE must be at least 1")
+	}
+	// A threshold of the deck size or more would refill a full deck on every page
+	if cfg.RefillThreshold < 0 || cfg.RefillThreshold >= cfg.Size {
+		return DeckConfig{}, fmt.Errorf("DECK_REFILL_THRESHOLD must be between 0 and DECK_SIZE - 1")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type ModerationConfig struct {
+	// AutoHideReports is how many users must report a user before their profile is hidden
+	// pending review, 0 never hides it
+	AutoHideReports int
+	// SLA is how long a case may wait for a decision
+	SLA time.Duration
+	// UrgentSLA is the SLA of cases about the safety of users, such as harassment
+	UrgentSLA time.Duration
+}
+
+// ConfigModeration reads MODERATION_AUTO_HIDE_REPORTS, MODERATION_SLA_HOURS and
+// MODERATION_URGENT_SLA_HOURS.
+func ConfigModeration() (ModerationConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return ModerationConfig{}, err
+	}
+
+	cfg := ModerationConfig{
+		AutoHideReports: getEnvInt("MODERATION_AUTO_HIDE_REPORTS", 3),
+		SLA:             time.Duration(getEnvInt("MODERATION_SLA_HOURS", 24)) * time.Hour,
+		UrgentSLA:       time.Duration(getEnvInt("MODERATION_URGENT_SLA_HOURS", 4)) * time.Hour,
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type PresenceConfig struct {
+	// OnlineWindow is how recently a user must have been active to show as online
+	OnlineWindow time.Duration
+	// TouchInterval is how often the activity of a user is written, at most
+	TouchInterval time.Duration
+}
+
+// ConfigPresence reads PRESENCE_ONLINE_SECONDS and PRESENCE_TOUCH_SECONDS.
+func ConfigPresence() (PresenceConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return PresenceConfig{}, err
+	}
+
+	cfg := PresenceConfig{
+		OnlineWindow:  time.Duration(getEnvInt("PRESENCE_ONLINE_SECONDS", 300)) * time.Second,
+		TouchInterval: time.Duration(getEnvInt("PRESENCE_TOUCH_SECONDS", 60)) * time.Second,
+	}
+
+	return cfg, nil
+}
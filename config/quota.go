@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	PlanFree    = "free"
+	PlanPremium = "premium"
+
+	QuotaPeriodDay   = "day"
+	QuotaPeriodWeek  = "week"
+	QuotaPeriodMonth = "month"
+)
+
+// Quotas are the quotas every plan has to set a limit for, the Quota names of the models.
+var Quotas = []string{"daily_swipes", "super_likes", "boosts", "rewinds", "match_extensions"}
+
+// QuotaLimit is how much of a quota may be used per period. A negative limit is unlimited.
+type QuotaLimit struct {
+	Limit  int
+	Period string
+}
+
+type QuotaConfig struct {
+	Plans map[string]map[string]QuotaLimit
+}
+
+const (
+	defaultFreePlan    = "daily_swipes=10/day,super_likes=1/day,boosts=0/month,rewinds=0/day,match_extensions=0/day"
+	defaultPremiumPlan = "daily_swipes=unlimited/day,super_likes=5/day,boosts=1/month,rewinds=10/day,match_extensions=3/day"
+)
+
+// ConfigQuota reads the plans from QUOTA_PLAN_FREE and QUOTA_PLAN_PREMIUM. Each plan is a
+// comma separated list of quota=limit/period entries, e.g. "daily_swipes=10/day", with an
+// entry for each of Quotas.
+func ConfigQuota() (QuotaConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return QuotaConfig{}, err
+	}
+
+	cfg := QuotaConfig{Plans: map[string]map[string]QuotaLimit{}}
+	for plan, fallback := range map[string]string{PlanFree: defaultFreePlan, PlanPremium: defaultPremiumPlan} {
+		value := os.Getenv("QUOTA_PLAN_" + strings.ToUpper(plan))
+		if value == "" {
+			value = fallback
+		}
+
+		limits, err := ParseQuotaPlan(value)
+		if err != nil {
+			return QuotaConfig{}, fmt.Errorf("QUOTA_PLAN_%s: %w", strings.ToUpper(plan), err)
+		}
+		cfg.Plans[plan] = limits
+	}
+
+	return cfg, nil
+}
+
+func ParseQuotaPlan(value string) (map[string]QuotaLimit, error) {
+	limits := map[string]QuotaLimit{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid quota %q", entry)
+		}
+		amount, period, ok := strings.Cut(rest, "/")
+		if !ok {
+			return nil, fmt.Errorf("missing period in quota %q", entry)
+		}
+
+		switch period {
+		case QuotaPeriodDay, QuotaPeriodWeek, QuotaPeriodMonth:
+		default:
+			return nil, fmt.Errorf("invalid period %q in quota %q", period, entry)
+		}
+
+		limit := -1
+		if amount != "unlimited" {
+			parsed, err := strconv.Atoi(amount)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("invalid limit %q in quota %q", amount, entry)
+			}
+			limit = parsed
+		}
+
+		limits[strings.TrimSpace(name)] = QuotaLimit{Limit: limit, Period: period}
+	}
+
+	// A quota left out would fail every request using it rather than the startup
+	for _, name := range Quotas {
+		if _, ok := limits[name]; !ok {
+			return nil, fmt.Errorf("missing quota %q", name)
+		}
+	}
+
+	return limits, nil
+}
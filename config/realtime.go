@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// RealtimeWebSocket serves events from the built-in WebSocket hub
+	RealtimeWebSocket = "websocket"
+	// RealtimePusher sends events through Pusher Channels as well, see ConfigPusher. The
+	// built-in hub still serves them to WebSocket connections and event streams.
+	RealtimePusher = "pusher"
+)
+
+type RealtimeConfig struct {
+	Provider string
+	// DSN is the connection the WebSocket hub listens on for events from other instances
+	DSN string
+	// NotifyChannel is the Postgres channel the instances share events on
+	NotifyChannel string
+	// ReplayBufferSize is how many events per user are kept for event streams to resume from
+	ReplayBufferSize int
+	// ReplayRetention is how long the events of a user are kept after their last stream closed
+	ReplayRetention time.Duration
+}
+
+// ConfigRealtime reads REALTIME_PROVIDER, REALTIME_NOTIFY_CHANNEL, REALTIME_REPLAY_BUFFER and
+// REALTIME_REPLAY_RETENTION_SECONDS. The hub listens with the same database credentials as ConfigDB.
+func ConfigRealtime() (RealtimeConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return RealtimeConfig{}, err
+	}
+
+	cfg := RealtimeConfig{
+		Provider:         os.Getenv("REALTIME_PROVIDER"),
+		DSN:              databaseDSN(),
+		NotifyChannel:    os.Getenv("REALTIME_NOTIFY_CHANNEL"),
+		ReplayBufferSize: getEnvInt("REALTIME_REPLAY_BUFFER", 100),
+		ReplayRetention:  time.Duration(getEnvInt("REALTIME_REPLAY_RETENTION_SECONDS", 120)) * time.Second,
+	}
+
+	switch cfg.Provider {
+	case "":
+		cfg.Provider = RealtimeWebSocket
+	case RealtimeWebSocket, RealtimePusher:
+	default:
+		return RealtimeConfig{}, fmt.Errorf("invalid REALTIME_PROVIDER %q", cfg.Provider)
+	}
+
+	if cfg.NotifyChannel == "" {
+		cfg.NotifyChannel = "realtime_events"
+	}
+
+	if cfg.ReplayBufferSize < 1 {
+		return RealtimeConfig{}, fmt.Errorf("REALTIME_REPLAY_BUFFER must be at least 1")
+	}
+	if cfg.ReplayRetention < 0 {
+		return RealtimeConfig{}, fmt.Errorf("REALTIME_REPLAY_RETENTION_SECONDS must not be negative")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/joho/godotenv"
+)
+
+type RecommenderWeights struct {
+	Preference   float64
+	Distance     float64
+	Activity     float64
+	Completeness float64
+	Interests    float64
+	Desirability float64
+}
+
+type RecommenderConfig struct {
+	Weights               RecommenderWeights
+	CandidatePoolSize     int
+	DistanceScaleKm       float64
+	ActivityHalfLifeHours float64
+	EloK                  float64
+	BoostMultiplier       float64
+}
+
+func ConfigRecommender() (RecommenderConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return RecommenderConfig{}, err
+	}
+
+	cfg := RecommenderConfig{
+		Weights: RecommenderWeights{
+			Preference:   getEnvFloat("RECOMMENDER_WEIGHT_PREFERENCE", 3),
+			Distance:     getEnvFloat("RECOMMENDER_WEIGHT_DISTANCE", 2),
+			Activity:     getEnvFloat("RECOMMENDER_WEIGHT_ACTIVITY", 1.5),
+			Completeness: getEnvFloat("RECOMMENDER_WEIGHT_COMPLETENESS", 1),
+			Interests:    getEnvFloat("RECOMMENDER_WEIGHT_INTERESTS", 1.5),
+			Desirability: getEnvFloat("RECOMMENDER_WEIGHT_DESIRABILITY", 1),
+		},
+		CandidatePoolSize:     getEnvInt("RECOMMENDER_CANDIDATE_POOL_SIZE", 500),
+		DistanceScaleKm:       getEnvFloat("RECOMMENDER_DISTANCE_SCALE_KM", 25),
+		ActivityHalfLifeHours: getEnvFloat("RECOMMENDER_ACTIVITY_HALF_LIFE_HOURS", 72),
+		EloK:                  getEnvFloat("RECOMMENDER_ELO_K", 32),
+		BoostMultiplier:       getEnvFloat("RECOMMENDER_BOOST_MULTIPLIER", 3),
+	}
+
+	// Both divide in the scores, zero would make every score NaN
+	if cfg.DistanceScaleKm <= 0 || cfg.ActivityHalfLifeHours <= 0 {
+		return RecommenderConfig{}, fmt.Errorf("RECOMMENDER_DISTANCE_SCALE_KM and RECOMMENDER_ACTIVITY_HALF_LIFE_HOURS must be positive")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"github.com/joho/godotenv"
+)
+
+// ScreeningConfig holds the thresholds the score of a message is held to. Each stage of
+// the screening pipeline adds to the score for what it finds in the content.
+type ScreeningConfig struct {
+	// NudgeScore is the score from which the sender is asked whether they meant to send it
+	NudgeScore int
+	// HoldScore is the score from which the message is held for review instead of delivered
+	HoldScore int
+}
+
+// ConfigScreening reads SCREENING_NUDGE_SCORE and SCREENING_HOLD_SCORE.
+func ConfigScreening() (ScreeningConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return ScreeningConfig{}, err
+	}
+
+	cfg := ScreeningConfig{
+		NudgeScore: getEnvInt("SCREENING_NUDGE_SCORE", 3),
+		HoldScore:  getEnvInt("SCREENING_HOLD_SCORE", 8),
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// RewindMatchRefuse refuses to rewind a swipe that created a match
+	RewindMatchRefuse = "refuse"
+	// RewindMatchUnwind rewinds the swipe and removes the match with it
+	RewindMatchUnwind = "unwind"
+)
+
+type SwipeConfig struct {
+	RewindWindow      time.Duration
+	RewindMatchPolicy string
+	LikesPageSize     int
+	HistoryPageSize   int
+}
+
+// ConfigSwipe reads REWIND_WINDOW_MINUTES, REWIND_MATCH_POLICY, LIKES_PAGE_SIZE and SWIPE_HISTORY_PAGE_SIZE.
+// Matches that already have messages are never unwound, whatever the policy.
+func ConfigSwipe() (SwipeConfig, error) {
+	var err error
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	once.Do(func() {
+		err = godotenv.Load()
+	})
+
+	if err != nil {
+		return SwipeConfig{}, err
+	}
+
+	cfg := SwipeConfig{
+		RewindWindow:      time.Duration(getEnvInt("REWIND_WINDOW_MINUTES", 10)) * time.Minute,
+		RewindMatchPolicy: os.Getenv("REWIND_MATCH_POLICY"),
+		LikesPageSize:     getEnvInt("LIKES_PAGE_SIZE", 20),
+		HistoryPageSize:   getEnvInt("SWIPE_HISTORY_PAGE_SIZE", 20),
+	}
+
+	switch cfg.RewindMatchPolicy {
+	case "":
+		cfg.RewindMatchPolicy = RewindMatchRefuse
+	case RewindMatchRefuse, RewindMatchUnwind:
+	default:
+		return SwipeConfig{}, fmt.Errorf("invalid REWIND_MATCH_POLICY %q", cfg.RewindMatchPolicy)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/handler"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/routes"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmatchReasons_Report(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	analyticsUseCase := usecase.NewAnalyticsUseCase(mockMatchRepo)
+
+	to := time.Now()
+	counts := []models.UnmatchReasonCount{
+		{Reason: models.UnmatchNoResponse, Count: 5},
+		{Reason: models.UnmatchFakeProfile, Count: 2},
+	}
+	// Thirty days up to now unless given a period
+	mockMatchRepo.On("CountUnmatchReasons", to.Add(-30*24*time.Hour), to).Return(counts, nil)
+
+	report, err := analyticsUseCase.UnmatchReasons(time.Time{}, to)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), report.Total)
+	assert.Equal(t, counts, report.Reasons)
+
+	_, err = analyticsUseCase.UnmatchReasons(to, to.Add(-time.Hour))
+	assert.ErrorIs(t, err, usecase.ErrInvalidReportPeriod)
+}
+
+func TestAdminRoutes_RequireAdmin(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockMatchRepo := new(MockMatchRepository)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers := routes.AppRouteHandlers{AdminHandler: *handler.NewAdminHandler(usecase.NewAnalyticsUseCase(mockMatchRepo))}
+	routes.Routes(router, handlers, testJWTSecret, new(MockQuotaUseCase), new(MockPresenceUseCase), usecase.NewUserUseCase(mockUserRepo))
+
+	userID := uuid.New()
+	adminID := uuid.New()
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, Role: models.RoleUser}, nil)
+	mockUserRepo.On("GetUserByID", adminID).Return(&models.User{ID: adminID, Role: models.RoleAdmin}, nil)
+	mockMatchRepo.On("CountUnmatchReasons", mock.Anything, mock.Anything).Return([]models.UnmatchReasonCount{}, nil)
+
+	get := func(userID uuid.UUID) int {
+		token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/admin/analytics/unmatch-reasons?from=2024-01-01T00:00:00Z", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, get(userID))
+	assert.Equal(t, http.StatusOK, get(adminID))
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/antibot"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocking dependencies
+type MockBotSignalRepository struct {
+	mock.Mock
+}
+
+// CreateSignals is a mocked implementation of the CreateSignals method in the BotSignalRepository interface
+func (m *MockBotSignalRepository) CreateSignals(signals []models.BotSignal) error {
+	args := m.Called(signals)
+	return args.Error(0)
+}
+
+func testAntiBotConfig() config.AntiBotConfig {
+	return config.AntiBotConfig{
+		Window:           time.Minute,
+		MaxSwipes:        10,
+		LikeRatioSample:  10,
+		MaxLikeRatio:     0.9,
+		TimingSample:     5,
+		MinInterval:      time.Second,
+		MinJitter:        0.1,
+		StrikeWindow:     time.Hour,
+		FrictionStrikes:  1,
+		ThrottleStrikes:  3,
+		FlagStrikes:      5,
+		FrictionInterval: 3 * time.Second,
+		ThrottleDuration: 15 * time.Minute,
+	}
+}
+
+// newTestDetector returns a detector that accepts the few swipes of a usecase test.
+func newTestDetector() antibot.Detector {
+	mockSignalRepo := new(MockBotSignalRepository)
+	mockSignalRepo.On("CreateSignals", mock.Anything).Return(nil)
+	return antibot.NewDetector(mockSignalRepo, testAntiBotConfig())
+}
+
+func TestDetector_HumanPaceIsAllowed(t *testing.T) {
+	mockSignalRepo := new(MockBotSignalRepository)
+	detector := antibot.NewDetector(mockSignalRepo, testAntiBotConfig())
+
+	userID := uuid.New()
+	start := time.Now()
+	// Irregular gaps and a mix of likes and passes
+	gaps := []time.Duration{4, 9, 3, 12, 6, 5, 15, 2, 8, 7, 11, 4}
+	types := []models.SwipeType{models.SwipeLike, models.SwipePass, models.SwipePass}
+
+	mockSignalRepo.On("CreateSignals", []models.BotSignal(nil)).Return(nil)
+
+	at := start
+	for i, gap := range gaps {
+		at = at.Add(gap * time.Second)
+		assert.NoError(t, detector.Allow(userID, at))
+
+		level, err := detector.Observe(&models.Swipe{UserID: userID, Type: types[i%len(types)]}, at)
+		assert.NoError(t, err)
+		assert.Equal(t, models.BotLevelNone, level)
+	}
+}
+
+func TestDetector_EscalatesFromFrictionToFlag(t *testing.T) {
+	mockSignalRepo := new(MockBotSignalRepository)
+	detector := antibot.NewDetector(mockSignalRepo, testAntiBotConfig())
+
+	userID := uuid.New()
+	var persisted []models.BotSignal
+	mockSignalRepo.On("CreateSignals", mock.Anything).Run(func(args mock.Arguments) {
+		persisted = append(persisted, args.Get(0).([]models.BotSignal)...)
+	}).Return(nil)
+
+	// A script liking every 200ms
+	at := time.Now()
+	swipe := func() models.BotLevel {
+		at = at.Add(200 * time.Millisecond)
+		level, err := detector.Observe(&models.Swipe{UserID: userID, Type: models.SwipeLike}, at)
+		assert.NoError(t, err)
+		return level
+	}
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, models.BotLevelNone, swipe())
+	}
+	assert.Equal(t, models.BotLevelFriction, swipe())
+
+	// Under friction the next swipe has to wait
+	err := detector.Allow(userID, at.Add(time.Second))
+	assert.ErrorIs(t, err, antibot.ErrFriction)
+	var retryErr *antibot.RetryError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Equal(t, 2*time.Second, retryErr.RetryAfter)
+
+	swipe()
+	assert.Equal(t, models.BotLevelThrottle, swipe())
+	assert.ErrorIs(t, detector.Allow(userID, at.Add(10*time.Minute)), antibot.ErrThrottled)
+	assert.NoError(t, detector.Allow(userID, at.Add(16*time.Minute)))
+
+	swipe()
+	assert.Equal(t, models.BotLevelFlag, swipe())
+
+	// Every crossed threshold was kept for review, the last ones marking the flag
+	assert.NotEmpty(t, persisted)
+	last := persisted[len(persisted)-1]
+	assert.Equal(t, userID, last.UserID)
+	assert.Equal(t, models.BotLevelFlag, last.Level)
+}
+
+func TestSwipe_HeldBackByDetector(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	mockSignalRepo := new(MockBotSignalRepository)
+	detector := antibot.NewDetector(mockSignalRepo, testAntiBotConfig())
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, detector, new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	mockSignalRepo.On("CreateSignals", mock.Anything).Return(nil)
+
+	// Push the user into friction, the last swipe being just now
+	at := time.Now().Add(-time.Second)
+	for i := 0; i < 5; i++ {
+		_, err := detector.Observe(&models.Swipe{UserID: userID, Type: models.SwipeLike}, at.Add(time.Duration(i-4)*100*time.Millisecond))
+		assert.NoError(t, err)
+	}
+
+	_, err := swipeUseCase.Swipe(&models.Swipe{UserID: userID, TargetUserID: uuid.New(), Type: models.SwipeLike})
+	assert.ErrorIs(t, err, antibot.ErrFriction)
+
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	mockSwipeRepo.AssertNotCalled(t, "CreateSwipe", mock.Anything)
+}
@@ -0,0 +1,316 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/media"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/storage"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+// CreateAttachment is a mocked implementation of the CreateAttachment method in the AttachmentRepository interface
+func (m *MockAttachmentRepository) CreateAttachment(attachment *models.Attachment) error {
+	args := m.Called(attachment)
+	return args.Error(0)
+}
+
+// GetAttachment is a mocked implementation of the GetAttachment method in the AttachmentRepository interface
+func (m *MockAttachmentRepository) GetAttachment(id uuid.UUID) (*models.Attachment, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+// MarkUploaded is a mocked implementation of the MarkUploaded method in the AttachmentRepository interface
+func (m *MockAttachmentRepository) MarkUploaded(attachment *models.Attachment) (bool, error) {
+	args := m.Called(attachment)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetPurgeableAttachments is a mocked implementation of the GetPurgeableAttachments method in the AttachmentRepository interface
+func (m *MockAttachmentRepository) GetPurgeableAttachments(unsentBefore time.Time, limit int) ([]models.Attachment, error) {
+	args := m.Called(unsentBefore, limit)
+	return args.Get(0).([]models.Attachment), args.Error(1)
+}
+
+// DeleteAttachment is a mocked implementation of the DeleteAttachment method in the AttachmentRepository interface
+func (m *MockAttachmentRepository) DeleteAttachment(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func testAttachmentConfig() config.AttachmentConfig {
+	return config.AttachmentConfig{
+		SigningKey:       "test-signing-key",
+		MaxImageSize:     1 << 20,
+		MaxVoiceSize:     1 << 20,
+		MaxVoiceDuration: time.Minute,
+		ThumbnailSize:    100,
+		UploadWindow:     15 * time.Minute,
+		URLLifetime:      5 * time.Minute,
+	}
+}
+
+// attachmentFixture is a match room userID takes part in with an open upload slot.
+type attachmentFixture struct {
+	useCase        usecase.AttachmentUseCase
+	attachmentRepo *MockAttachmentRepository
+	blobStore      *storage.LocalStore
+	userID         uuid.UUID
+	attachment     *models.Attachment
+}
+
+func newAttachmentFixture(t *testing.T, kind string) *attachmentFixture {
+	mockMatchRepo := new(MockMatchRepository)
+	mockAttachmentRepo := new(MockAttachmentRepository)
+	blobStore, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	attachment := &models.Attachment{
+		ID:              uuid.New(),
+		MatchRoomID:     uuid.New(),
+		UploaderID:      userID,
+		Kind:            kind,
+		UploadExpiresAt: time.Now().Add(time.Minute),
+	}
+	mockMatchRepo.On("GetMatchRoom", attachment.MatchRoomID, mock.Anything).Return(&models.MatchRoom{ID: attachment.MatchRoomID}, nil)
+	mockAttachmentRepo.On("GetAttachment", attachment.ID).Return(attachment, nil)
+	mockAttachmentRepo.On("MarkUploaded", attachment).Return(true, nil)
+
+	return &attachmentFixture{
+		useCase:        usecase.NewAttachmentUseCase(mockAttachmentRepo, mockMatchRepo, blobStore, testAttachmentConfig()),
+		attachmentRepo: mockAttachmentRepo,
+		blobStore:      blobStore,
+		userID:         userID,
+		attachment:     attachment,
+	}
+}
+
+func (f *attachmentFixture) upload(data []byte) (*models.Attachment, error) {
+	return f.useCase.Upload(f.attachment.MatchRoomID, f.attachment.ID, f.userID, bytes.NewReader(data))
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// testWAV is mono 16-bit PCM at 8 kHz.
+func testWAV(duration time.Duration) []byte {
+	const byteRate = 8000 * 2
+	dataSize := uint32(duration * byteRate / time.Second)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36)+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&buf, binary.LittleEndian, []uint32{8000, byteRate})
+	binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+func oggPage(granule int64, sequence uint32, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("OggS")
+	buf.Write([]byte{0, 0})
+	binary.Write(&buf, binary.LittleEndian, granule)
+	binary.Write(&buf, binary.LittleEndian, []uint32{0x1234, sequence, 0})
+	buf.Write([]byte{1, byte(len(body))})
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func TestUpload_ImageGetsThumbnail(t *testing.T) {
+	f := newAttachmentFixture(t, models.AttachmentImage)
+
+	attachment, err := f.upload(testPNG(t, 400, 200))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", attachment.ContentType)
+	assert.Equal(t, 400, attachment.Width)
+	assert.Equal(t, 200, attachment.Height)
+	assert.NotNil(t, attachment.UploadedAt)
+
+	file, err := f.blobStore.Open("attachments/" + attachment.ID.String() + "/thumbnail")
+	require.NoError(t, err)
+	defer file.Close()
+	thumbnail, err := jpeg.Decode(file)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumbnail.Bounds())
+}
+
+func TestUpload_ValidatesTypeAndSize(t *testing.T) {
+	// The type comes from the content, an image is not a voice note
+	f := newAttachmentFixture(t, models.AttachmentVoice)
+	_, err := f.upload(testPNG(t, 10, 10))
+	assert.ErrorIs(t, err, usecase.ErrUnsupportedAttachment)
+
+	_, err = f.upload(testWAV(2 * time.Minute))
+	assert.ErrorIs(t, err, usecase.ErrAttachmentTooLarge)
+
+	f = newAttachmentFixture(t, models.AttachmentImage)
+	_, err = f.upload([]byte("not an image"))
+	assert.ErrorIs(t, err, usecase.ErrUnsupportedAttachment)
+
+	f.attachment.UploadExpiresAt = time.Now().Add(-time.Second)
+	_, err = f.upload(testPNG(t, 10, 10))
+	assert.ErrorIs(t, err, usecase.ErrUploadSlotExpired)
+
+	f.attachmentRepo.AssertNotCalled(t, "MarkUploaded", mock.Anything)
+}
+
+func TestUpload_VoiceNoteDuration(t *testing.T) {
+	f := newAttachmentFixture(t, models.AttachmentVoice)
+
+	attachment, err := f.upload(testWAV(1500 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, "audio/wav", attachment.ContentType)
+	assert.Equal(t, int64(1500), attachment.DurationMs)
+}
+
+func TestParseAudio(t *testing.T) {
+	// Opus counts 48 kHz samples, less the pre-skip of the header
+	opusHead := append([]byte("OpusHead\x01\x01"), 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	ogg := append(oggPage(0, 0, opusHead), oggPage(0, 1, []byte("OpusTags"))...)
+	ogg = append(ogg, oggPage(48000*3+312, 2, []byte("audio"))...)
+
+	audio, err := media.ParseAudio(ogg)
+	require.NoError(t, err)
+	assert.Equal(t, "audio/ogg", audio.ContentType)
+	assert.Equal(t, 3*time.Second, audio.Duration)
+
+	// M4A keeps the duration in the movie header
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 4250)
+	box := func(name string, body []byte) []byte {
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint32(header, uint32(8+len(body)))
+		copy(header[4:], name)
+		return append(header, body...)
+	}
+	m4a := append(box("ftyp", []byte("M4A \x00\x00\x00\x00")), box("moov", box("mvhd", mvhd))...)
+
+	audio, err = media.ParseAudio(m4a)
+	require.NoError(t, err)
+	assert.Equal(t, "audio/mp4", audio.ContentType)
+	assert.Equal(t, 4250*time.Millisecond, audio.Duration)
+
+	_, err = media.ParseAudio([]byte("OggS truncated"))
+	assert.ErrorIs(t, err, media.ErrUnsupportedFormat)
+}
+
+func TestParseAudio_RejectsForgedSizes(t *testing.T) {
+	// A 64-bit box size that wraps the offset around must not slice backwards
+	moov := []byte("\x00\x00\x00\x01moov")
+	moov = binary.BigEndian.AppendUint64(moov, 1<<64-8)
+	m4a := append([]byte("\x00\x00\x00\x10ftypM4A \x00\x00\x00\x00"), moov...)
+
+	_, err := media.ParseAudio(m4a)
+	assert.ErrorIs(t, err, media.ErrUnsupportedFormat)
+
+	// A granule position past the 24 hour cap is not taken at its word
+	opusHead := append([]byte("OpusHead\x01\x01"), 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	ogg := append(oggPage(0, 0, opusHead), oggPage(1<<62, 1, []byte("audio"))...)
+
+	_, err = media.ParseAudio(ogg)
+	assert.ErrorIs(t, err, media.ErrUnsupportedFormat)
+}
+
+func TestAttachmentURLs_SignedForParticipants(t *testing.T) {
+	f := newAttachmentFixture(t, models.AttachmentImage)
+	_, err := f.upload(testPNG(t, 20, 20))
+	require.NoError(t, err)
+
+	urls, err := f.useCase.GetAttachmentURLs(f.attachment.MatchRoomID, f.attachment.ID, f.userID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(urls.ThumbnailURL, "/attachments/"+f.attachment.ID.String()+"/thumbnail?"))
+
+	link, err := url.Parse(urls.URL)
+	require.NoError(t, err)
+	query := link.Query()
+
+	file, contentType, err := f.useCase.OpenAttachment(f.attachment.ID, usecase.AttachmentOriginal, query.Get("expires"), query.Get("signature"))
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, testPNG(t, 20, 20), data)
+
+	// Not for the thumbnail, not once tampered with or expired
+	_, _, err = f.useCase.OpenAttachment(f.attachment.ID, usecase.AttachmentThumbnail, query.Get("expires"), query.Get("signature"))
+	assert.ErrorIs(t, err, usecase.ErrInvalidAttachmentLink)
+	_, _, err = f.useCase.OpenAttachment(f.attachment.ID, usecase.AttachmentOriginal, "99999999999", query.Get("signature"))
+	assert.ErrorIs(t, err, usecase.ErrInvalidAttachmentLink)
+	_, _, err = f.useCase.OpenAttachment(f.attachment.ID, usecase.AttachmentOriginal, "1", query.Get("signature"))
+	assert.ErrorIs(t, err, usecase.ErrInvalidAttachmentLink)
+
+	// Until it is sent, the other participant cannot get it
+	_, err = f.useCase.GetAttachmentURLs(f.attachment.MatchRoomID, f.attachment.ID, uuid.New())
+	assert.ErrorIs(t, err, usecase.ErrAttachmentNotFound)
+}
+
+func TestAttachmentURLs_RequireMembership(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	attachmentUseCase := usecase.NewAttachmentUseCase(new(MockAttachmentRepository), mockMatchRepo, nil, testAttachmentConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return((*models.MatchRoom)(nil), gorm.ErrRecordNotFound)
+
+	_, err := attachmentUseCase.GetAttachmentURLs(matchRoomID, uuid.New(), userID)
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+	_, err = attachmentUseCase.CreateUploadSlot(matchRoomID, userID, models.AttachmentImage)
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+	_, err = attachmentUseCase.CreateUploadSlot(matchRoomID, userID, "video")
+	assert.ErrorIs(t, err, usecase.ErrInvalidAttachmentKind)
+}
+
+func TestCreateMessage_WithAttachment(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	err := matchUseCase.CreateMessage(&models.Message{MatchRoomID: uuid.New(), SenderID: uuid.New()})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMessage)
+
+	// Without a caption, and not when the attachment is not ready to be sent
+	message := &models.Message{MatchRoomID: uuid.New(), SenderID: uuid.New(), Attachment: &models.Attachment{ID: uuid.New()}}
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, message.SenderID).Return(newMatchRoom(message.MatchRoomID, message.SenderID, uuid.New()), nil)
+	mockMatchRepo.On("CreateMessage", message).Return(gorm.ErrRecordNotFound).Once()
+	mockMatchRepo.On("CreateMessage", message).Return(nil).Once()
+
+	assert.ErrorIs(t, matchUseCase.CreateMessage(message), usecase.ErrAttachmentNotFound)
+	assert.NoError(t, matchUseCase.CreateMessage(message))
+}
@@ -0,0 +1,290 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/recommender"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockBoostRepository struct {
+	mock.Mock
+}
+
+// CreateBoost is a mocked implementation of the CreateBoost method in the BoostRepository interface
+func (m *MockBoostRepository) CreateBoost(boost *models.Boost) (bool, error) {
+	args := m.Called(boost)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetBoost is a mocked implementation of the GetBoost method in the BoostRepository interface
+func (m *MockBoostRepository) GetBoost(id uuid.UUID) (*models.Boost, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Boost), args.Error(1)
+}
+
+// GetActiveBoost is a mocked implementation of the GetActiveBoost method in the BoostRepository interface
+func (m *MockBoostRepository) GetActiveBoost(userID uuid.UUID, at time.Time) (*models.Boost, error) {
+	args := m.Called(userID, at)
+	return args.Get(0).(*models.Boost), args.Error(1)
+}
+
+// GetBoostedUserIDs is a mocked implementation of the GetBoostedUserIDs method in the BoostRepository interface
+func (m *MockBoostRepository) GetBoostedUserIDs(at time.Time) ([]uuid.UUID, error) {
+	args := m.Called(at)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func testBoostConfig() config.BoostConfig {
+	return config.BoostConfig{
+		Duration:       30 * time.Minute,
+		BaselineWindow: 7 * 24 * time.Hour,
+	}
+}
+
+func TestStartBoost(t *testing.T) {
+	mockBoostRepo := new(MockBoostRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	boostUseCase := usecase.NewBoostUseCase(mockBoostRepo, new(MockProfileRepository), new(MockSwipeRepository), mockQuotaUseCase, testBoostConfig())
+
+	userID := uuid.New()
+
+	mockBoostRepo.On("GetActiveBoost", userID, mock.Anything).Return(&models.Boost{}, gorm.ErrRecordNotFound)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaBoosts).Return(&models.QuotaStatus{}, nil)
+	mockBoostRepo.On("CreateBoost", mock.Anything).Return(true, nil)
+
+	boost, err := boostUseCase.StartBoost(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, boost.UserID)
+	assert.Equal(t, 30*time.Minute, boost.EndsAt.Sub(boost.StartsAt))
+
+	mockQuotaUseCase.AssertExpectations(t)
+	mockBoostRepo.AssertExpectations(t)
+}
+
+func TestStartBoost_AlreadyRunning(t *testing.T) {
+	mockBoostRepo := new(MockBoostRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	boostUseCase := usecase.NewBoostUseCase(mockBoostRepo, new(MockProfileRepository), new(MockSwipeRepository), mockQuotaUseCase, testBoostConfig())
+
+	userID := uuid.New()
+
+	mockBoostRepo.On("GetActiveBoost", userID, mock.Anything).Return(&models.Boost{UserID: userID}, nil)
+
+	_, err := boostUseCase.StartBoost(userID)
+	assert.ErrorIs(t, err, usecase.ErrBoostActive)
+
+	// No credit is spent on a boost that does not start
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestStartBoost_StartedMeanwhile(t *testing.T) {
+	mockBoostRepo := new(MockBoostRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	boostUseCase := usecase.NewBoostUseCase(mockBoostRepo, new(MockProfileRepository), new(MockSwipeRepository), mockQuotaUseCase, testBoostConfig())
+
+	userID := uuid.New()
+
+	// A concurrent request starts a boost between the check and the insert
+	mockBoostRepo.On("GetActiveBoost", userID, mock.Anything).Return(&models.Boost{}, gorm.ErrRecordNotFound)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaBoosts).Return(&models.QuotaStatus{}, nil)
+	mockBoostRepo.On("CreateBoost", mock.Anything).Return(false, nil)
+	mockQuotaUseCase.On("Release", userID, models.QuotaBoosts).Return(nil)
+
+	_, err := boostUseCase.StartBoost(userID)
+	assert.ErrorIs(t, err, usecase.ErrBoostActive)
+
+	// The credit of the boost that did not start is given back
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestGetBoostSummary(t *testing.T) {
+	mockBoostRepo := new(MockBoostRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	boostUseCase := usecase.NewBoostUseCase(mockBoostRepo, mockProfileRepo, mockSwipeRepo, new(MockQuotaUseCase), testBoostConfig())
+
+	userID := uuid.New()
+	start := time.Now().Add(-2 * time.Hour)
+	boost := &models.Boost{ID: uuid.New(), UserID: userID, StartsAt: start, EndsAt: start.Add(30 * time.Minute)}
+	baselineStart := start.Add(-7 * 24 * time.Hour)
+
+	mockBoostRepo.On("GetBoost", boost.ID).Return(boost, nil)
+	mockProfileRepo.On("CountImpressions", userID, start, boost.EndsAt).Return(int64(40), nil)
+	mockSwipeRepo.On("CountLikesReceived", userID, start, boost.EndsAt).Return(int64(6), nil)
+
+	// Over a week the user got 3360 views and 336 likes, so 10 views and 1 like per half hour
+	mockProfileRepo.On("CountImpressions", userID, baselineStart, start).Return(int64(3360), nil)
+	mockSwipeRepo.On("CountLikesReceived", userID, baselineStart, start).Return(int64(336), nil)
+
+	summary, err := boostUseCase.GetSummary(userID, boost.ID)
+	assert.NoError(t, err)
+	assert.False(t, summary.Active)
+	assert.Equal(t, int64(40), summary.Views)
+	assert.Equal(t, int64(30), summary.ExtraViews)
+	assert.Equal(t, int64(6), summary.Likes)
+	assert.Equal(t, int64(5), summary.ExtraLikes)
+}
+
+func TestGetBoostSummary_OtherUsersBoost(t *testing.T) {
+	mockBoostRepo := new(MockBoostRepository)
+	boostUseCase := usecase.NewBoostUseCase(mockBoostRepo, new(MockProfileRepository), new(MockSwipeRepository), new(MockQuotaUseCase), testBoostConfig())
+
+	boost := &models.Boost{ID: uuid.New(), UserID: uuid.New()}
+	mockBoostRepo.On("GetBoost", boost.ID).Return(boost, nil)
+
+	_, err := boostUseCase.GetSummary(uuid.New(), boost.ID)
+	assert.ErrorIs(t, err, usecase.ErrBoostNotFound)
+}
+
+func TestRecommend_BoostedProfileRanksHigher(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockBoostRepo := new(MockBoostRepository)
+	rec := recommender.NewRecommender(mockProfileRepo, mockBoostRepo, testRecommenderConfig())
+
+	userID := uuid.New()
+	now := time.Now()
+
+	// Without the boost the active profile would come first
+	active := models.Profile{UserID: uuid.New(), Name: "Active", Bio: "Bio", LastActiveAt: now}
+	boosted := models.Profile{UserID: uuid.New(), LastActiveAt: now.AddDate(0, 0, -10)}
+
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{}, gorm.ErrRecordNotFound)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(&models.Preference{}, gorm.ErrRecordNotFound)
+	mockProfileRepo.On("GetProfilesExcluding", mock.Anything, 100).Return([]models.Profile{active, boosted}, nil)
+	mockProfileRepo.On("GetPreferencesByUserIDs", mock.Anything).Return([]models.Preference{}, nil)
+	mockBoostRepo.On("GetBoostedUserIDs", mock.Anything).Return([]uuid.UUID{boosted.UserID}, nil)
+
+	profiles, err := rec.Recommend(userID, []uuid.UUID{userID}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, boosted.UserID, profiles[0].UserID)
+}
+
+func TestViewProfiles_GeneratedDeckStartsWithBoosts(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	superLikerID := uuid.New()
+	first := models.Profile{UserID: uuid.New()}
+	second := models.Profile{UserID: uuid.New()}
+	swiped := models.Profile{UserID: uuid.New()}
+	ranked := models.Profile{UserID: uuid.New()}
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(&models.Deck{}, gorm.ErrRecordNotFound)
+
+	// A boosted profile already swiped on is left out
+	mockSwipeRepo.On("GetSwipedUsersID", userID, mock.Anything).Return([]uuid.UUID{swiped.UserID}, nil)
+	mockMatchRepo.On("GetMatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockMatchRepo.On("GetUnmatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockRecommender.On("Recommend", userID, mock.Anything, 100).Return([]models.Profile{ranked, first}, nil)
+	mockRecommender.On("Boosted", userID, 3).Return([]models.Profile{first, swiped, second}, nil)
+	mockSwipeRepo.On("GetSuperLikerIDs", userID).Return([]uuid.UUID{superLikerID}, nil)
+
+	var candidateIDs []uuid.UUID
+	mockDeckRepo.On("ReplaceDeck", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		candidateIDs = args.Get(1).([]uuid.UUID)
+	}).Return(nil)
+	mockDeckRepo.On("GetEntries", userID, mock.Anything, 10).Return([]models.DeckEntry{}, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+
+	_, err := profileUseCase.ViewProfiles(userID, "")
+	assert.NoError(t, err)
+
+	// Super likers first, then the boosts best ranked first, then the rest of the ranking
+	assert.Equal(t, []uuid.UUID{superLikerID, first.UserID, second.UserID, ranked.UserID}, candidateIDs)
+}
+
+func TestViewProfiles_CurrentDeckIsNotReranked(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), mockSwipeRepo, new(MockMatchRepository), noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+	mockDeckRepo.On("GetEntries", userID, mock.Anything, 10).Return([]models.DeckEntry{}, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+
+	_, err := profileUseCase.ViewProfiles(userID, "")
+	assert.NoError(t, err)
+
+	// The first page of a current deck neither ranks the boosts nor works out exclusions
+	mockRecommender.AssertNotCalled(t, "Boosted", mock.Anything, mock.Anything)
+	mockSwipeRepo.AssertNotCalled(t, "GetSwipedUsersID", mock.Anything, mock.Anything)
+}
+
+func TestViewProfiles_RefillPushesBoosts(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	queuedID := uuid.New()
+	first := models.Profile{UserID: uuid.New()}
+	second := models.Profile{UserID: uuid.New()}
+	queued := models.Profile{UserID: queuedID}
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+	mockDeckRepo.On("GetEntries", userID, mock.Anything, 10).Return([]models.DeckEntry{}, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(1), nil)
+
+	mockSwipeRepo.On("GetSwipedUsersID", userID, mock.Anything).Return([]uuid.UUID{}, nil)
+	mockMatchRepo.On("GetMatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockMatchRepo.On("GetUnmatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockDeckRepo.On("GetCandidateIDs", userID).Return([]uuid.UUID{queuedID}, nil)
+	// A boosted profile already in the deck stays where it is
+	mockRecommender.On("Boosted", userID, 3).Return([]models.Profile{first, queued, second}, nil)
+
+	var pushed []uuid.UUID
+	mockDeckRepo.On("PushFront", userID, mock.Anything).Run(func(args mock.Arguments) {
+		pushed = append(pushed, args.Get(1).(uuid.UUID))
+	}).Return(nil)
+
+	refilled := make(chan []uuid.UUID, 1)
+	mockRecommender.On("Recommend", userID, mock.Anything, 97).Run(func(args mock.Arguments) {
+		refilled <- args.Get(1).([]uuid.UUID)
+	}).Return([]models.Profile{}, nil)
+	mockDeckRepo.On("AppendEntries", userID, mock.Anything).Return(nil)
+
+	_, err := profileUseCase.ViewProfiles(userID, "")
+	assert.NoError(t, err)
+
+	select {
+	case excludedIDs := <-refilled:
+		// The best ranked boost is pushed last so it ends up first
+		assert.Equal(t, []uuid.UUID{second.UserID, first.UserID}, pushed)
+		assert.Contains(t, excludedIDs, first.UserID)
+		assert.Contains(t, excludedIDs, second.UserID)
+	case <-time.After(time.Second):
+		t.Fatal("the deck was not refilled")
+	}
+}
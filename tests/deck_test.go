@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockDeckRepository struct {
+	mock.Mock
+}
+
+// GetDeck is a mocked implementation of the GetDeck method in the DeckRepository interface
+func (m *MockDeckRepository) GetDeck(userID uuid.UUID) (*models.Deck, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.Deck), args.Error(1)
+}
+
+// ReplaceDeck is a mocked implementation of the ReplaceDeck method in the DeckRepository interface
+func (m *MockDeckRepository) ReplaceDeck(deck *models.Deck, candidateIDs []uuid.UUID) error {
+	args := m.Called(deck, candidateIDs)
+	return args.Error(0)
+}
+
+// AppendEntries is a mocked implementation of the AppendEntries method in the DeckRepository interface
+func (m *MockDeckRepository) AppendEntries(userID uuid.UUID, candidateIDs []uuid.UUID) error {
+	args := m.Called(userID, candidateIDs)
+	return args.Error(0)
+}
+
+// GetEntries is a mocked implementation of the GetEntries method in the DeckRepository interface
+func (m *MockDeckRepository) GetEntries(userID uuid.UUID, afterPosition, limit int) ([]models.DeckEntry, error) {
+	args := m.Called(userID, afterPosition, limit)
+	return args.Get(0).([]models.DeckEntry), args.Error(1)
+}
+
+// GetCandidateIDs is a mocked implementation of the GetCandidateIDs method in the DeckRepository interface
+func (m *MockDeckRepository) GetCandidateIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// CountEntries is a mocked implementation of the CountEntries method in the DeckRepository interface
+func (m *MockDeckRepository) CountEntries(userID uuid.UUID) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// ConsumeEntry is a mocked implementation of the ConsumeEntry method in the DeckRepository interface
+func (m *MockDeckRepository) ConsumeEntry(userID, candidateID uuid.UUID) error {
+	args := m.Called(userID, candidateID)
+	return args.Error(0)
+}
+
+// PushFront is a mocked implementation of the PushFront method in the DeckRepository interface
+func (m *MockDeckRepository) PushFront(userID, candidateID uuid.UUID) error {
+	args := m.Called(userID, candidateID)
+	return args.Error(0)
+}
+
+// DeleteDeck is a mocked implementation of the DeleteDeck method in the DeckRepository interface
+func (m *MockDeckRepository) DeleteDeck(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func testDeckConfig() config.DeckConfig {
+	return config.DeckConfig{
+		Size:             100,
+		Period:           24 * time.Hour,
+		RefillThreshold:  20,
+		PageSize:         50,
+		BoostSlots:       3,
+		PassRecycleAfter: 30 * 24 * time.Hour,
+	}
+}
+
+func freeSwipesLeft(remaining int) *models.QuotaStatus {
+	return &models.QuotaStatus{Name: models.QuotaDailySwipes, Plan: config.PlanFree, Limit: 10, Used: 10 - remaining, Remaining: remaining}
+}
+
+func deckEntries(userID uuid.UUID, from, to int) ([]models.DeckEntry, []models.Profile) {
+	var entries []models.DeckEntry
+	var profiles []models.Profile
+	for position := from; position <= to; position++ {
+		candidateID := uuid.New()
+		entries = append(entries, models.DeckEntry{UserID: userID, CandidateUserID: candidateID, Position: position})
+		profiles = append(profiles, models.Profile{ID: uuid.New(), UserID: candidateID})
+	}
+	return entries, profiles
+}
+
+func TestViewProfiles_GeneratesDeckAndPagesWithCursor(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	firstPage, firstProfiles := deckEntries(userID, 1, 10)
+	secondPage, secondProfiles := deckEntries(userID, 11, 12)
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockSwipeRepo.On("GetSwipedUsersID", userID, mock.Anything).Return([]uuid.UUID{}, nil)
+	mockMatchRepo.On("GetMatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockMatchRepo.On("GetUnmatchedUsersID", userID).Return([]uuid.UUID{}, nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{}, gorm.ErrRecordNotFound)
+
+	// No deck yet, so one is generated from the recommender
+	mockDeckRepo.On("GetDeck", userID).Return(&models.Deck{}, gorm.ErrRecordNotFound).Once()
+	mockRecommender.On("Recommend", userID, []uuid.UUID{userID}, 100).Return(append(firstProfiles, secondProfiles...), nil)
+	mockSwipeRepo.On("GetSuperLikerIDs", userID).Return([]uuid.UUID{}, nil)
+
+	var generated *models.Deck
+	mockDeckRepo.On("ReplaceDeck", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		generated = args.Get(0).(*models.Deck)
+		assert.Len(t, args.Get(1).([]uuid.UUID), 12)
+	}).Return(nil)
+
+	mockRecommender.On("Boosted", userID, 3).Return([]models.Profile{}, nil)
+	mockProfileRepo.On("RecordImpressions", userID, mock.Anything, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetEntries", userID, math.MinInt32, 10).Return(firstPage, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", mock.Anything).Return(firstProfiles, nil).Once()
+
+	page, err := profileUseCase.ViewProfiles(userID, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Profiles, 10)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// The next page continues after the last position of the first one
+	mockDeckRepo.On("GetDeck", userID).Return(generated, nil)
+	mockDeckRepo.On("GetEntries", userID, 10, 10).Return(secondPage, nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", mock.Anything).Return(secondProfiles, nil).Once()
+
+	page, err = profileUseCase.ViewProfiles(userID, page.NextCursor)
+	assert.NoError(t, err)
+	assert.Len(t, page.Profiles, 2)
+	assert.Empty(t, page.NextCursor)
+
+	mockDeckRepo.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
+}
+
+func TestViewProfiles_StaleCursorRestartsDeck(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, new(MockMatchRepository), noBlocks(), mockDeckRepo, new(MockRecommender), mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	staleCursor := utils.EncodeCursor("12345", "40")
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+	mockDeckRepo.On("GetEntries", userID, math.MinInt32, 10).Return([]models.DeckEntry{}, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+
+	page, err := profileUseCase.ViewProfiles(userID, staleCursor)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Profiles)
+
+	mockDeckRepo.AssertExpectations(t)
+}
+
+func TestViewProfiles_InvalidCursor(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, new(MockMatchRepository), noBlocks(), mockDeckRepo, new(MockRecommender), mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+
+	_, err := profileUseCase.ViewProfiles(userID, "not a cursor")
+	assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+}
+
+func TestUpdatePreference_InvalidatesDeck(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), new(MockSwipeRepository), new(MockMatchRepository), noBlocks(), mockDeckRepo, new(MockRecommender), new(MockQuotaUseCase), new(MockPresenceUseCase), testDeckConfig())
+
+	preference := &models.Preference{UserID: uuid.New(), Gender: "female", MinAge: 25, MaxAge: 35}
+
+	mockProfileRepo.On("UpsertPreference", preference).Return(nil)
+	mockDeckRepo.On("DeleteDeck", preference.UserID).Return(nil)
+
+	err := profileUseCase.UpdatePreference(preference)
+	assert.NoError(t, err)
+
+	mockProfileRepo.AssertExpectations(t)
+	mockDeckRepo.AssertExpectations(t)
+}
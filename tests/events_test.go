@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  realtime.Event
+}
+
+// openStream opens the event stream of userID, returning once the stream is subscribed.
+// The stream is closed by calling the returned function or at the end of the test.
+func openStream(t *testing.T, server *httptest.Server, userID uuid.UUID, lastEventID string) (*bufio.Reader, func()) {
+	token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	return bufio.NewReader(resp.Body), func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+// readSSE reads the next event off the stream, skipping comments.
+func readSSE(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.Event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Data))
+		}
+	}
+}
+
+func TestStream_DeliversEventsWithIncreasingIDs(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	room := *newMatchRoom(uuid.New(), userID, uuid.New())
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{room}, nil)
+
+	reader, _ := openStream(t, server, userID, "")
+
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(uuid.New()), realtime.EventNewMessage, "not for you"))
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(room.ID), realtime.EventNewMessage, "hi"))
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "someone"))
+
+	first := readSSE(t, reader)
+	assert.Equal(t, realtime.EventNewMessage, first.Event)
+	assert.Equal(t, realtime.Event{Channel: realtime.MatchRoomChannel(room.ID), Event: realtime.EventNewMessage, Data: "hi"}, first.Data)
+
+	second := readSSE(t, reader)
+	assert.Equal(t, realtime.EventSuperLikeReceived, second.Event)
+
+	firstID, err := strconv.ParseInt(first.ID, 10, 64)
+	require.NoError(t, err)
+	secondID, err := strconv.ParseInt(second.ID, 10, 64)
+	require.NoError(t, err)
+	assert.Greater(t, secondID, firstID)
+}
+
+func TestStream_ResumesFromLastEventID(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil)
+
+	reader, closeStream := openStream(t, server, userID, "")
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "first"))
+	last := readSSE(t, reader)
+	closeStream()
+
+	// Published while the client is away
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "second"))
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "third"))
+
+	reader, _ = openStream(t, server, userID, last.ID)
+	assert.Equal(t, "second", readSSE(t, reader).Data.Data)
+	assert.Equal(t, "third", readSSE(t, reader).Data.Data)
+
+	// The session was created once, the resumed stream did not reload the rooms
+	mockMatchRepo.AssertNumberOfCalls(t, "GetMatchRooms", 1)
+}
+
+func TestStream_ResetsWhenMissedEventsAreGone(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	cfg := testRealtimeConfig()
+	cfg.ReplayBufferSize = 2
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, cfg)
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil)
+
+	// An ID from before this instance knew the user
+	reader, closeStream := openStream(t, server, userID, "1")
+	assert.Equal(t, "reset", readSSE(t, reader).Event)
+
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "first"))
+	last := readSSE(t, reader)
+	closeStream()
+
+	// More events were missed than the buffer keeps
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "missed"))
+	}
+
+	reader, _ = openStream(t, server, userID, last.ID)
+	assert.Equal(t, "reset", readSSE(t, reader).Event)
+
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "live"))
+	assert.Equal(t, "live", readSSE(t, reader).Data.Data)
+}
+
+func TestStream_FollowsMatchesAndUnmatches(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	matchRoomID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil)
+
+	reader, _ := openStream(t, server, userID, "")
+
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventNewMatch, realtime.MatchData{MatchRoomID: matchRoomID, UserID: uuid.New()}))
+	assert.Equal(t, realtime.EventNewMatch, readSSE(t, reader).Event)
+
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(matchRoomID), realtime.EventNewMessage, "hi"))
+	assert.Equal(t, realtime.EventNewMessage, readSSE(t, reader).Event)
+
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(matchRoomID), realtime.EventUnmatch, realtime.MatchData{MatchRoomID: matchRoomID, UserID: userID}))
+	assert.Equal(t, realtime.EventUnmatch, readSSE(t, reader).Event)
+
+	// Nothing more of the room comes through
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(matchRoomID), realtime.EventNewMessage, "still there?"))
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "someone"))
+	assert.Equal(t, realtime.EventSuperLikeReceived, readSSE(t, reader).Event)
+}
+
+func TestStream_RequiresToken(t *testing.T) {
+	server := serveHub(t, realtime.NewHub(new(MockMatchRepository), nil, nil, testRealtimeConfig()))
+
+	resp, err := http.Get(server.URL + "/events/stream")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestStream_TypingIsNotReplayed(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	targetUserID := uuid.New()
+	room := *newMatchRoom(uuid.New(), userID, targetUserID)
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{room}, nil)
+
+	reader, closeStream := openStream(t, server, userID, "")
+	assert.NoError(t, hub.Publish(realtime.UserChannel(userID), realtime.EventSuperLikeReceived, "first"))
+	last := readSSE(t, reader)
+
+	typing := realtime.TypingData{MatchRoomID: room.ID, UserID: targetUserID}
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(room.ID), realtime.EventTypingStarted, typing))
+	event := readSSE(t, reader)
+	assert.Equal(t, realtime.EventTypingStarted, event.Event)
+	assert.Empty(t, event.ID)
+	closeStream()
+
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(room.ID), realtime.EventTypingStopped, typing))
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(room.ID), realtime.EventNewMessage, "hi"))
+
+	// Only the message is caught up on
+	reader, _ = openStream(t, server, userID, last.ID)
+	assert.Equal(t, realtime.EventNewMessage, readSSE(t, reader).Event)
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newRuledMatchRoom returns a room likerID liked first and matcherID matched at createdAt.
+func newRuledMatchRoom(id, likerID, matcherID uuid.UUID, createdAt time.Time) *models.MatchRoom {
+	room := newMatchRoom(id, likerID, matcherID)
+	room.MatchedByID = &matcherID
+	room.CreatedAt = createdAt
+	return room
+}
+
+func TestCreateMessage_FirstMessagePolicy(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	cfg := testChatConfig()
+	cfg.FirstMessagePolicy = config.FirstMessageLiker
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), cfg)
+
+	roomID, likerID, matcherID := uuid.New(), uuid.New(), uuid.New()
+	room := newRuledMatchRoom(roomID, likerID, matcherID, time.Now().Add(-time.Hour))
+	mockMatchRepo.On("GetMatchRoom", roomID, mock.Anything).Return(room, nil)
+
+	err := matchUseCase.CreateMessage(&models.Message{MatchRoomID: roomID, SenderID: matcherID, Content: "hi"})
+	assert.ErrorIs(t, err, usecase.ErrNotYourTurn)
+
+	message := &models.Message{MatchRoomID: roomID, SenderID: likerID, Content: "hi"}
+	mockMatchRepo.On("CreateMessage", message).Return(nil)
+	assert.NoError(t, matchUseCase.CreateMessage(message))
+
+	// Once the conversation started either side writes
+	opened := time.Now()
+	room.FirstMessageAt, room.FirstSenderID = &opened, &likerID
+	answer := &models.Message{MatchRoomID: roomID, SenderID: matcherID, Content: "hey"}
+	mockMatchRepo.On("CreateMessage", answer).Return(nil)
+	assert.NoError(t, matchUseCase.CreateMessage(answer))
+}
+
+func TestCreateMessage_MatchExpired(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	roomID, userID := uuid.New(), uuid.New()
+	// Nobody wrote within the day, the scheduler did not close it yet
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newRuledMatchRoom(roomID, userID, uuid.New(), time.Now().Add(-25*time.Hour)), nil)
+
+	err := matchUseCase.CreateMessage(&models.Message{MatchRoomID: roomID, SenderID: userID, Content: "sorry, busy day"})
+	assert.ErrorIs(t, err, usecase.ErrMatchExpired)
+	mockMatchRepo.AssertNotCalled(t, "CreateMessage", mock.Anything)
+}
+
+func TestExtendMatch(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, mockUserRepo, noPresence(), testScreener(), new(MockModerationUseCase), mockQuotaUseCase, testChatConfig())
+
+	roomID, userID := uuid.New(), uuid.New()
+	matchedAt := time.Now().Add(-20 * time.Hour)
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newRuledMatchRoom(roomID, userID, uuid.New(), matchedAt), nil)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true}, nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaMatchExtensions).Return(&models.QuotaStatus{}, nil)
+	mockMatchRepo.On("ExtendMatch", roomID).Return(true, nil)
+
+	expiresAt, err := matchUseCase.ExtendMatch(roomID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, matchedAt.Add(48*time.Hour), *expiresAt)
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestExtendMatch_QuotaExceeded(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, mockUserRepo, noPresence(), testScreener(), new(MockModerationUseCase), mockQuotaUseCase, testChatConfig())
+
+	roomID, userID := uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newRuledMatchRoom(roomID, userID, uuid.New(), time.Now()), nil)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true}, nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaMatchExtensions).Return((*models.QuotaStatus)(nil), usecase.ErrQuotaExceeded)
+
+	_, err := matchUseCase.ExtendMatch(roomID, userID)
+	assert.ErrorIs(t, err, usecase.ErrQuotaExceeded)
+	mockMatchRepo.AssertNotCalled(t, "ExtendMatch", mock.Anything)
+}
+
+func TestExtendMatch_ExtendedMeanwhileReleasesQuota(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, mockUserRepo, noPresence(), testScreener(), new(MockModerationUseCase), mockQuotaUseCase, testChatConfig())
+
+	roomID, userID := uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newRuledMatchRoom(roomID, userID, uuid.New(), time.Now()), nil)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true}, nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaMatchExtensions).Return(&models.QuotaStatus{}, nil)
+	// The other participant extended it first
+	mockMatchRepo.On("ExtendMatch", roomID).Return(false, nil)
+	mockQuotaUseCase.On("Release", userID, models.QuotaMatchExtensions).Return(nil)
+
+	_, err := matchUseCase.ExtendMatch(roomID, userID)
+	assert.ErrorIs(t, err, usecase.ErrCannotExtend)
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestExtendMatch_Refused(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockUserRepo := new(MockUserRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, mockUserRepo, noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID}, nil)
+
+	free := uuid.New()
+	mockMatchRepo.On("GetMatchRoom", free, userID).Return(newRuledMatchRoom(free, userID, uuid.New(), time.Now()), nil)
+	_, err := matchUseCase.ExtendMatch(free, userID)
+	assert.ErrorIs(t, err, usecase.ErrPremiumRequired)
+
+	extended := uuid.New()
+	room := newRuledMatchRoom(extended, userID, uuid.New(), time.Now())
+	room.Extended = true
+	mockMatchRepo.On("GetMatchRoom", extended, userID).Return(room, nil)
+	_, err = matchUseCase.ExtendMatch(extended, userID)
+	assert.ErrorIs(t, err, usecase.ErrCannotExtend)
+
+	// Rooms from before the rules never expire, so there is nothing to extend
+	old := uuid.New()
+	mockMatchRepo.On("GetMatchRoom", old, userID).Return(newMatchRoom(old, userID, uuid.New()), nil)
+	_, err = matchUseCase.ExtendMatch(old, userID)
+	assert.ErrorIs(t, err, usecase.ErrCannotExtend)
+
+	mockMatchRepo.AssertNotCalled(t, "ExtendMatch", mock.Anything)
+}
+
+func TestExpireMatches(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	now := time.Now()
+	cutoffs := models.MatchCutoffs{
+		FirstMessage: now.Add(-24 * time.Hour),
+		Extended:     now.Add(-48 * time.Hour),
+		Reply:        now.Add(-7 * 24 * time.Hour),
+	}
+	unopened := newRuledMatchRoom(uuid.New(), uuid.New(), uuid.New(), now.Add(-30*time.Hour))
+	unanswered := newRuledMatchRoom(uuid.New(), uuid.New(), uuid.New(), now.Add(-10*24*time.Hour))
+	openedAt := now.Add(-8 * 24 * time.Hour)
+	unanswered.FirstMessageAt = &openedAt
+	// Answered since it was picked
+	answered := newRuledMatchRoom(uuid.New(), uuid.New(), uuid.New(), now.Add(-26*time.Hour))
+
+	mockMatchRepo.On("GetMatchesDue", cutoffs, 100).Return([]models.MatchRoom{*unopened, *unanswered, *answered}, nil)
+	mockMatchRepo.On("ExpireMatch", unopened.ID, cutoffs, now).Return(true, nil)
+	mockMatchRepo.On("ExpireMatch", unanswered.ID, cutoffs, now).Return(true, nil)
+	mockMatchRepo.On("ExpireMatch", answered.ID, cutoffs, now).Return(false, nil)
+
+	expired, err := matchUseCase.ExpireMatches(now)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.MatchExpiry{
+		{MatchRoomID: unopened.ID, ExpiresAt: unopened.CreatedAt.Add(24 * time.Hour)},
+		{MatchRoomID: unanswered.ID, ExpiresAt: openedAt.Add(7 * 24 * time.Hour)},
+	}, expired)
+}
+
+func TestRemindExpiringMatches(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	now := time.Now()
+	// Due within the three hours of the reminder
+	soon := now.Add(3 * time.Hour)
+	cutoffs := models.MatchCutoffs{
+		FirstMessage: soon.Add(-24 * time.Hour),
+		Extended:     soon.Add(-48 * time.Hour),
+		Reply:        soon.Add(-7 * 24 * time.Hour),
+	}
+	room := newRuledMatchRoom(uuid.New(), uuid.New(), uuid.New(), now.Add(-22*time.Hour))
+	mockMatchRepo.On("GetMatchesToRemind", cutoffs, 100).Return([]models.MatchRoom{*room}, nil)
+	mockMatchRepo.On("MarkReminded", room.ID, now).Return(true, nil)
+
+	expiring, err := matchUseCase.RemindExpiringMatches(now)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.MatchExpiry{{MatchRoomID: room.ID, ExpiresAt: room.CreatedAt.Add(24 * time.Hour)}}, expiring)
+}
+
+func TestGetMatchRooms_LeavesOutExpired(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	now := time.Now()
+	fresh := newRuledMatchRoom(uuid.New(), userID, uuid.New(), now.Add(-time.Hour))
+	expired := newRuledMatchRoom(uuid.New(), userID, uuid.New(), now.Add(-25*time.Hour))
+	old := newMatchRoom(uuid.New(), userID, uuid.New())
+	mockMatchRepo.On("GetMatchRoomSummaries", userID).Return([]models.MatchRoomSummary{
+		{MatchRoom: *fresh}, {MatchRoom: *expired}, {MatchRoom: *old},
+	}, nil)
+
+	rooms, err := matchUseCase.GetMatchRooms(userID)
+	require.NoError(t, err)
+	require.Len(t, rooms, 2)
+	assert.Equal(t, fresh.ID, rooms[0].ID)
+	assert.Equal(t, fresh.CreatedAt.Add(24*time.Hour), *rooms[0].ExpiresAt)
+	assert.Equal(t, old.ID, rooms[1].ID)
+	assert.Nil(t, rooms[1].ExpiresAt)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestHistory_FiltersByTypeAndPages(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), mockProfileRepo, new(MockUserRepository), new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	types := []models.SwipeType{models.SwipeLike, models.SwipeSuperLike}
+	newer := recentSwipe(userID, models.SwipeSuperLike, time.Minute)
+	older := recentSwipe(userID, models.SwipeLike, time.Hour)
+
+	mockSwipeRepo.On("GetSwipeHistory", userID, types, time.Time{}, uuid.Nil, 2).Return([]models.Swipe{*newer, *older}, nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{newer.TargetUserID, older.TargetUserID}).Return([]models.Profile{
+		{UserID: older.TargetUserID},
+		{UserID: newer.TargetUserID},
+	}, nil)
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{UserID: userID}, nil)
+
+	page, err := swipeUseCase.History(userID, types, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Swipes, 2)
+	assert.Equal(t, newer.TargetUserID, page.Swipes[0].Profile.UserID)
+	assert.Equal(t, models.SwipeSuperLike, page.Swipes[0].Type)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// The next page continues after the oldest swipe served
+	mockSwipeRepo.On("GetSwipeHistory", userID, types, mock.Anything, older.ID, 2).Return([]models.Swipe{}, nil)
+
+	page, err = swipeUseCase.History(userID, types, page.NextCursor)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Swipes)
+	assert.Empty(t, page.NextCursor)
+
+	mockSwipeRepo.AssertExpectations(t)
+}
+
+func TestHistory_InvalidType(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	_, err := swipeUseCase.History(uuid.New(), []models.SwipeType{models.SwipeLike, "maybe"}, "")
+	assert.ErrorIs(t, err, usecase.ErrInvalidSwipeType)
+
+	mockSwipeRepo.AssertNotCalled(t, "GetSwipeHistory", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestViewProfiles_RecyclesPassesAfterConfiguredDays(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	liked := uuid.New()
+	matched := uuid.New()
+	unmatched := uuid.New()
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetDeck", userID).Return(&models.Deck{}, gorm.ErrRecordNotFound)
+
+	// Passes older than the recycle period are no longer excluded
+	var passedSince time.Time
+	mockSwipeRepo.On("GetSwipedUsersID", userID, mock.Anything).Run(func(args mock.Arguments) {
+		passedSince = args.Get(1).(time.Time)
+	}).Return([]uuid.UUID{liked}, nil)
+	mockMatchRepo.On("GetMatchedUsersID", userID).Return([]uuid.UUID{matched}, nil)
+	mockMatchRepo.On("GetUnmatchedUsersID", userID).Return([]uuid.UUID{unmatched}, nil)
+	mockRecommender.On("Recommend", userID, []uuid.UUID{liked, matched, unmatched, userID}, 100).Return([]models.Profile{}, errors.New("stop here"))
+
+	_, err := profileUseCase.ViewProfiles(userID, "")
+	assert.Error(t, err)
+
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -30), passedSince, time.Minute)
+	mockRecommender.AssertExpectations(t)
+}
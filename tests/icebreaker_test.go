@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockIcebreakerRepository struct {
+	mock.Mock
+}
+
+// GetTemplates is a mocked implementation of the GetTemplates method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) GetTemplates(activeOnly bool) ([]models.IcebreakerTemplate, error) {
+	args := m.Called(activeOnly)
+	return args.Get(0).([]models.IcebreakerTemplate), args.Error(1)
+}
+
+// GetTemplate is a mocked implementation of the GetTemplate method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) GetTemplate(id uuid.UUID) (*models.IcebreakerTemplate, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.IcebreakerTemplate), args.Error(1)
+}
+
+// CreateTemplate is a mocked implementation of the CreateTemplate method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) CreateTemplate(template *models.IcebreakerTemplate) error {
+	args := m.Called(template)
+	return args.Error(0)
+}
+
+// UpdateTemplate is a mocked implementation of the UpdateTemplate method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) UpdateTemplate(template *models.IcebreakerTemplate) error {
+	args := m.Called(template)
+	return args.Error(0)
+}
+
+// DeleteTemplate is a mocked implementation of the DeleteTemplate method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) DeleteTemplate(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// GetUnsentSuggestions is a mocked implementation of the GetUnsentSuggestions method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) GetUnsentSuggestions(matchRoomID, userID uuid.UUID) ([]models.IcebreakerSuggestion, error) {
+	args := m.Called(matchRoomID, userID)
+	return args.Get(0).([]models.IcebreakerSuggestion), args.Error(1)
+}
+
+// CreateSuggestions is a mocked implementation of the CreateSuggestions method in the IcebreakerRepository interface
+func (m *MockIcebreakerRepository) CreateSuggestions(suggestions []models.IcebreakerSuggestion) error {
+	args := m.Called(suggestions)
+	return args.Error(0)
+}
+
+func icebreakerTemplate(source models.IcebreakerSource, text string, suggested, replied int64) models.IcebreakerTemplate {
+	return models.IcebreakerTemplate{
+		ID:     uuid.New(),
+		Source: source,
+		Text:   text,
+		Active: true,
+		Stats:  models.IcebreakerStats{Suggested: suggested, Sent: suggested, Replied: replied},
+	}
+}
+
+func TestGetIcebreakers_FillsTemplatesFromProfiles(t *testing.T) {
+	mockIcebreakerRepo := new(MockIcebreakerRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(mockIcebreakerRepo, mockMatchRepo, mockProfileRepo)
+
+	roomID, userID, targetUserID := uuid.New(), uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newMatchRoom(roomID, userID, targetUserID), nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{userID, targetUserID}).Return([]models.Profile{
+		{UserID: userID, Interests: []string{"hiking", "Jazz"}},
+		{
+			UserID:    targetUserID,
+			Name:      "Sam",
+			Bio:       "Weekend baker and amateur astronomer. Ask me about sourdough",
+			Interests: []string{"jazz", "chess", "Hiking"},
+			Prompts:   []models.PromptAnswer{{Prompt: "My ideal Sunday", Answer: "Farmers market, then a nap"}},
+		},
+	}, nil)
+
+	generic := icebreakerTemplate(models.IcebreakerGeneric, "Hi {name}!", 0, 0)
+	interest := icebreakerTemplate(models.IcebreakerInterest, "You like {interest} too?", 10, 6)
+	otherInterest := icebreakerTemplate(models.IcebreakerInterest, "Best thing about {interest}, {name}?", 10, 4)
+	bio := icebreakerTemplate(models.IcebreakerBio, "\"{bio}\", tell me more!", 10, 1)
+	mockIcebreakerRepo.On("GetTemplates", true).Return([]models.IcebreakerTemplate{generic, bio, otherInterest, interest}, nil)
+	mockIcebreakerRepo.On("GetUnsentSuggestions", roomID, userID).Return([]models.IcebreakerSuggestion{}, nil)
+	mockIcebreakerRepo.On("CreateSuggestions", mock.Anything).Return(nil)
+
+	icebreakers, err := icebreakerUseCase.GetIcebreakers(roomID, userID)
+	require.NoError(t, err)
+	require.Len(t, icebreakers, 3)
+
+	// The most answered first, a template without stats yet before the ones answered less
+	// often, and the interest templates take one shared interest each
+	assert.Equal(t, interest.ID, icebreakers[0].TemplateID)
+	assert.Equal(t, "You like jazz too?", icebreakers[0].Text)
+	assert.Equal(t, "Hi Sam!", icebreakers[1].Text)
+	assert.Equal(t, "Best thing about Hiking, Sam?", icebreakers[2].Text)
+	for _, icebreaker := range icebreakers {
+		assert.Equal(t, roomID, icebreaker.MatchRoomID)
+		assert.Equal(t, userID, icebreaker.UserID)
+	}
+	mockIcebreakerRepo.AssertCalled(t, "CreateSuggestions", icebreakers)
+}
+
+func TestGetIcebreakers_SkipsTemplatesWithoutData(t *testing.T) {
+	mockIcebreakerRepo := new(MockIcebreakerRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(mockIcebreakerRepo, mockMatchRepo, mockProfileRepo)
+
+	roomID, userID, targetUserID := uuid.New(), uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newMatchRoom(roomID, userID, targetUserID), nil)
+	// The other profile is hidden
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{userID, targetUserID}).Return([]models.Profile{{UserID: userID, Interests: []string{"chess"}}}, nil)
+	mockIcebreakerRepo.On("GetTemplates", true).Return([]models.IcebreakerTemplate{
+		icebreakerTemplate(models.IcebreakerInterest, "You like {interest} too?", 0, 0),
+		icebreakerTemplate(models.IcebreakerPrompt, "\"{answer}\", really?", 0, 0),
+		icebreakerTemplate(models.IcebreakerGeneric, "Hi {name}!", 0, 0),
+		icebreakerTemplate(models.IcebreakerGeneric, "Two truths and a lie, you go first.", 0, 0),
+	}, nil)
+	mockIcebreakerRepo.On("GetUnsentSuggestions", roomID, userID).Return([]models.IcebreakerSuggestion{}, nil)
+	mockIcebreakerRepo.On("CreateSuggestions", mock.Anything).Return(nil)
+
+	icebreakers, err := icebreakerUseCase.GetIcebreakers(roomID, userID)
+	require.NoError(t, err)
+	require.Len(t, icebreakers, 1)
+	assert.Equal(t, "Two truths and a lie, you go first.", icebreakers[0].Text)
+}
+
+func TestGetIcebreakers_ReusesUnsentSuggestions(t *testing.T) {
+	mockIcebreakerRepo := new(MockIcebreakerRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(mockIcebreakerRepo, mockMatchRepo, mockProfileRepo)
+
+	roomID, userID, targetUserID := uuid.New(), uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return(newMatchRoom(roomID, userID, targetUserID), nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{userID, targetUserID}).Return([]models.Profile{{UserID: userID}, {UserID: targetUserID, Name: "Sam", Bio: "Amateur astronomer"}}, nil)
+
+	greeting := icebreakerTemplate(models.IcebreakerGeneric, "Hi {name}!", 0, 0)
+	question := icebreakerTemplate(models.IcebreakerBio, "\"{bio}\", tell me more!", 0, 0)
+	mockIcebreakerRepo.On("GetTemplates", true).Return([]models.IcebreakerTemplate{greeting, question}, nil)
+
+	// The screen was opened before, when the bio was still empty
+	shown := models.IcebreakerSuggestion{ID: uuid.New(), TemplateID: greeting.ID, MatchRoomID: roomID, UserID: userID, Source: models.IcebreakerGeneric, Text: "Hi Sam!"}
+	mockIcebreakerRepo.On("GetUnsentSuggestions", roomID, userID).Return([]models.IcebreakerSuggestion{shown}, nil)
+	mockIcebreakerRepo.On("CreateSuggestions", mock.Anything).Return(nil)
+
+	icebreakers, err := icebreakerUseCase.GetIcebreakers(roomID, userID)
+	require.NoError(t, err)
+	require.Len(t, icebreakers, 2)
+	assert.Equal(t, shown, icebreakers[0])
+	assert.Equal(t, question.ID, icebreakers[1].TemplateID)
+	assert.NotEqual(t, uuid.Nil, icebreakers[1].ID)
+	// Only the new one is recorded as suggested
+	mockIcebreakerRepo.AssertCalled(t, "CreateSuggestions", icebreakers[1:])
+}
+
+func TestGetIcebreakers_MatchRoomNotFound(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(new(MockIcebreakerRepository), mockMatchRepo, new(MockProfileRepository))
+
+	roomID, userID := uuid.New(), uuid.New()
+	mockMatchRepo.On("GetMatchRoom", roomID, userID).Return((*models.MatchRoom)(nil), gorm.ErrRecordNotFound)
+
+	_, err := icebreakerUseCase.GetIcebreakers(roomID, userID)
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+}
+
+func TestCreateTemplate_Validates(t *testing.T) {
+	mockIcebreakerRepo := new(MockIcebreakerRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(mockIcebreakerRepo, new(MockMatchRepository), new(MockProfileRepository))
+	mockIcebreakerRepo.On("CreateTemplate", mock.Anything).Return(nil)
+
+	invalid := []*models.IcebreakerTemplate{
+		{Source: "weather", Text: "Nice weather, huh?"},
+		{Source: models.IcebreakerGeneric, Text: "   "},
+		// Without the data of its source
+		{Source: models.IcebreakerInterest, Text: "What do you like?"},
+	}
+	for _, template := range invalid {
+		assert.ErrorIs(t, icebreakerUseCase.CreateTemplate(template), usecase.ErrInvalidTemplate, template.Text)
+	}
+
+	template := &models.IcebreakerTemplate{Source: models.IcebreakerPrompt, Text: " Loved your answer to \"{prompt}\" "}
+	assert.NoError(t, icebreakerUseCase.CreateTemplate(template))
+	assert.Equal(t, "Loved your answer to \"{prompt}\"", template.Text)
+	mockIcebreakerRepo.AssertNumberOfCalls(t, "CreateTemplate", 1)
+}
+
+func TestUpdateTemplate_NotFound(t *testing.T) {
+	mockIcebreakerRepo := new(MockIcebreakerRepository)
+	icebreakerUseCase := usecase.NewIcebreakerUseCase(mockIcebreakerRepo, new(MockMatchRepository), new(MockProfileRepository))
+
+	template := &models.IcebreakerTemplate{ID: uuid.New(), Source: models.IcebreakerGeneric, Text: "Hi!"}
+	mockIcebreakerRepo.On("GetTemplate", template.ID).Return((*models.IcebreakerTemplate)(nil), gorm.ErrRecordNotFound)
+
+	assert.ErrorIs(t, icebreakerUseCase.UpdateTemplate(template), usecase.ErrTemplateNotFound)
+	mockIcebreakerRepo.AssertNotCalled(t, "UpdateTemplate", mock.Anything)
+}
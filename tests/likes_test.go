@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestLikesReceived_FreeUserGetsCountOnly(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), mockProfileRepo, mockUserRepo, new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID}, nil)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(&models.Preference{}, gorm.ErrRecordNotFound)
+	mockSwipeRepo.On("CountPendingLikes", userID, repository.LikesFilter{}).Return(int64(7), nil)
+
+	page, err := swipeUseCase.LikesReceived(userID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), page.Count)
+	assert.True(t, page.Blurred)
+	assert.Empty(t, page.Likes)
+
+	mockSwipeRepo.AssertNotCalled(t, "GetPendingLikes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLikesReceived_PremiumUserPagesThroughLikes(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), mockProfileRepo, mockUserRepo, new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	now := time.Now().Truncate(time.Microsecond)
+	likes := []models.Swipe{
+		{ID: uuid.New(), UserID: uuid.New(), TargetUserID: userID, Type: models.SwipeSuperLike, Model: gorm.Model{CreatedAt: now}},
+		{ID: uuid.New(), UserID: uuid.New(), TargetUserID: userID, Type: models.SwipeLike, Model: gorm.Model{CreatedAt: now.Add(-time.Hour)}},
+	}
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true}, nil)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(&models.Preference{UserID: userID, Gender: "female", MinAge: 25, MaxAge: 35, MaxDistanceKm: 50}, nil)
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{UserID: userID, Latitude: -6.2, Longitude: 106.8}, nil)
+
+	// The preferences become the filter on the senders
+	matchesPreference := mock.MatchedBy(func(filter repository.LikesFilter) bool {
+		return filter.Gender == "female" && filter.MaxDistanceKm == 50 && filter.Latitude == -6.2 &&
+			filter.BornBefore.Year() == time.Now().Year()-25 && filter.BornAfter.Year() == time.Now().Year()-36
+	})
+	mockSwipeRepo.On("CountPendingLikes", userID, matchesPreference).Return(int64(3), nil)
+	mockSwipeRepo.On("GetPendingLikes", userID, matchesPreference, time.Time{}, uuid.Nil, 2).Return(likes, nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{likes[0].UserID, likes[1].UserID}).
+		Return([]models.Profile{{UserID: likes[1].UserID}, {UserID: likes[0].UserID}}, nil)
+
+	page, err := swipeUseCase.LikesReceived(userID, "")
+	assert.NoError(t, err)
+	assert.False(t, page.Blurred)
+	assert.Equal(t, int64(3), page.Count)
+	assert.Len(t, page.Likes, 2)
+	assert.Equal(t, likes[0].UserID, page.Likes[0].Profile.UserID)
+	assert.Equal(t, models.SwipeSuperLike, page.Likes[0].Type)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// The next page continues after the oldest like served
+	mockSwipeRepo.On("GetPendingLikes", userID, matchesPreference, mock.MatchedBy(func(before time.Time) bool {
+		return before.Equal(likes[1].CreatedAt)
+	}), likes[1].ID, 2).Return([]models.Swipe{}, nil)
+
+	page, err = swipeUseCase.LikesReceived(userID, page.NextCursor)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Likes)
+	assert.Empty(t, page.NextCursor)
+
+	mockSwipeRepo.AssertExpectations(t)
+}
+
+func TestLikesReceived_InvalidCursor(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	mockUserRepo := new(MockUserRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), mockProfileRepo, mockUserRepo, new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true}, nil)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(&models.Preference{}, gorm.ErrRecordNotFound)
+	mockSwipeRepo.On("CountPendingLikes", userID, repository.LikesFilter{}).Return(int64(0), nil)
+
+	_, err := swipeUseCase.LikesReceived(userID, "not a cursor")
+	assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+}
@@ -7,10 +7,20 @@ import (
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
 	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
+// newMatchRoom is a match room of userID with targetUserID.
+func newMatchRoom(id, userID, targetUserID uuid.UUID) *models.MatchRoom {
+	return &models.MatchRoom{
+		ID:           id,
+		Participants: []models.RoomParticipant{{MatchRoomID: id, UserID: userID}, {MatchRoomID: id, UserID: targetUserID}},
+	}
+}
+
 type MockMatchRepository struct {
 	mock.Mock
 }
@@ -22,8 +32,8 @@ func (m *MockMatchRepository) CreateMatch(match *models.MatchRoom) error {
 }
 
 // GetMatchedUsersID is a mocked implementation of the GetMatchedUsersID method in the MatchRepository interface
-func (m *MockMatchRepository) GetMatchedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error) {
-	args := m.Called(userID, date)
+func (m *MockMatchRepository) GetMatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
 	return args.Get(0).([]uuid.UUID), args.Error(1)
 }
 
@@ -33,41 +43,217 @@ func (m *MockMatchRepository) GetMatchRooms(userID uuid.UUID) ([]models.MatchRoo
 	return args.Get(0).([]models.MatchRoom), args.Error(1)
 }
 
-// DeleteMatchRoom is a mocked implementation of the DeleteMatchRoom method in the MatchRepository interface
-func (m *MockMatchRepository) DeleteMatchRoom(id, userID uuid.UUID) error {
+// GetMatchRoom is a mocked implementation of the GetMatchRoom method in the MatchRepository interface
+func (m *MockMatchRepository) GetMatchRoom(id, userID uuid.UUID) (*models.MatchRoom, error) {
 	args := m.Called(id, userID)
+	return args.Get(0).(*models.MatchRoom), args.Error(1)
+}
+
+// GetMatchRoomSummaries is a mocked implementation of the GetMatchRoomSummaries method in the MatchRepository interface
+func (m *MockMatchRepository) GetMatchRoomSummaries(userID uuid.UUID) ([]models.MatchRoomSummary, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.MatchRoomSummary), args.Error(1)
+}
+
+// GetParticipants is a mocked implementation of the GetParticipants method in the MatchRepository interface
+func (m *MockMatchRepository) GetParticipants(matchRoomID uuid.UUID) ([]models.RoomParticipant, error) {
+	args := m.Called(matchRoomID)
+	return args.Get(0).([]models.RoomParticipant), args.Error(1)
+}
+
+// MarkRead is a mocked implementation of the MarkRead method in the MatchRepository interface
+func (m *MockMatchRepository) MarkRead(matchRoomID, userID uuid.UUID, message *models.Message, readAt time.Time) (bool, error) {
+	args := m.Called(matchRoomID, userID, message, readAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// Unmatch is a mocked implementation of the Unmatch method in the MatchRepository interface
+func (m *MockMatchRepository) Unmatch(unmatch *models.Unmatch) error {
+	args := m.Called(unmatch)
 	return args.Error(0)
 }
 
+// GetUnmatchedUsersID is a mocked implementation of the GetUnmatchedUsersID method in the MatchRepository interface
+func (m *MockMatchRepository) GetUnmatchedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// GetUnmatchesToPurge is a mocked implementation of the GetUnmatchesToPurge method in the MatchRepository interface
+func (m *MockMatchRepository) GetUnmatchesToPurge(unmatchedBefore time.Time, limit int) ([]models.Unmatch, error) {
+	args := m.Called(unmatchedBefore, limit)
+	return args.Get(0).([]models.Unmatch), args.Error(1)
+}
+
+// PurgeMessages is a mocked implementation of the PurgeMessages method in the MatchRepository interface
+func (m *MockMatchRepository) PurgeMessages(unmatch *models.Unmatch, purgedAt time.Time) error {
+	args := m.Called(unmatch, purgedAt)
+	return args.Error(0)
+}
+
+// CountUnmatchReasons is a mocked implementation of the CountUnmatchReasons method in the MatchRepository interface
+func (m *MockMatchRepository) CountUnmatchReasons(from, to time.Time) ([]models.UnmatchReasonCount, error) {
+	args := m.Called(from, to)
+	return args.Get(0).([]models.UnmatchReasonCount), args.Error(1)
+}
+
+// GetMatchBetween is a mocked implementation of the GetMatchBetween method in the MatchRepository interface
+func (m *MockMatchRepository) GetMatchBetween(userID, targetUserID uuid.UUID) (*models.MatchRoom, error) {
+	args := m.Called(userID, targetUserID)
+	return args.Get(0).(*models.MatchRoom), args.Error(1)
+}
+
+// ExtendMatch is a mocked implementation of the ExtendMatch method in the MatchRepository interface
+func (m *MockMatchRepository) ExtendMatch(id uuid.UUID) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetMatchesDue is a mocked implementation of the GetMatchesDue method in the MatchRepository interface
+func (m *MockMatchRepository) GetMatchesDue(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error) {
+	args := m.Called(cutoffs, limit)
+	return args.Get(0).([]models.MatchRoom), args.Error(1)
+}
+
+// GetMatchesToRemind is a mocked implementation of the GetMatchesToRemind method in the MatchRepository interface
+func (m *MockMatchRepository) GetMatchesToRemind(cutoffs models.MatchCutoffs, limit int) ([]models.MatchRoom, error) {
+	args := m.Called(cutoffs, limit)
+	return args.Get(0).([]models.MatchRoom), args.Error(1)
+}
+
+// MarkReminded is a mocked implementation of the MarkReminded method in the MatchRepository interface
+func (m *MockMatchRepository) MarkReminded(id uuid.UUID, remindedAt time.Time) (bool, error) {
+	args := m.Called(id, remindedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// ExpireMatch is a mocked implementation of the ExpireMatch method in the MatchRepository interface
+func (m *MockMatchRepository) ExpireMatch(id uuid.UUID, cutoffs models.MatchCutoffs, expiredAt time.Time) (bool, error) {
+	args := m.Called(id, cutoffs, expiredAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// CountMessages is a mocked implementation of the CountMessages method in the MatchRepository interface
+func (m *MockMatchRepository) CountMessages(matchRoomID uuid.UUID) (int64, error) {
+	args := m.Called(matchRoomID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // CreateMessage is a mocked implementation of the CreateMessage method in the MatchRepository interface
 func (m *MockMatchRepository) CreateMessage(message *models.Message) error {
 	args := m.Called(message)
 	return args.Error(0)
 }
 
-// GetMessages is a mocked implementation of the GetMessages method in the MatchRepository interface
-func (m *MockMatchRepository) GetMessages(matchRoomID uuid.UUID) ([]models.Message, error) {
-	args := m.Called(matchRoomID)
+// GetMessage is a mocked implementation of the GetMessage method in the MatchRepository interface
+func (m *MockMatchRepository) GetMessage(id uuid.UUID) (*models.Message, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+// EditMessage is a mocked implementation of the EditMessage method in the MatchRepository interface
+func (m *MockMatchRepository) EditMessage(message *models.Message, edit *models.MessageEdit) error {
+	args := m.Called(message, edit)
+	return args.Error(0)
+}
+
+// ReleaseMessage is a mocked implementation of the ReleaseMessage method in the MatchRepository interface
+func (m *MockMatchRepository) ReleaseMessage(id uuid.UUID, releasedAt time.Time) error {
+	args := m.Called(id, releasedAt)
+	return args.Error(0)
+}
+
+// MarkUnwanted is a mocked implementation of the MarkUnwanted method in the MatchRepository interface
+func (m *MockMatchRepository) MarkUnwanted(id uuid.UUID, markedAt time.Time) (bool, error) {
+	args := m.Called(id, markedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// UnsendMessage is a mocked implementation of the UnsendMessage method in the MatchRepository interface
+func (m *MockMatchRepository) UnsendMessage(message *models.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+// GetMessageEdits is a mocked implementation of the GetMessageEdits method in the MatchRepository interface
+func (m *MockMatchRepository) GetMessageEdits(messageID uuid.UUID) ([]models.MessageEdit, error) {
+	args := m.Called(messageID)
+	return args.Get(0).([]models.MessageEdit), args.Error(1)
+}
+
+// SetReaction is a mocked implementation of the SetReaction method in the MatchRepository interface
+func (m *MockMatchRepository) SetReaction(reaction *models.MessageReaction) error {
+	args := m.Called(reaction)
+	return args.Error(0)
+}
+
+// DeleteReaction is a mocked implementation of the DeleteReaction method in the MatchRepository interface
+func (m *MockMatchRepository) DeleteReaction(messageID, userID uuid.UUID) (bool, error) {
+	args := m.Called(messageID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetMessagesBefore is a mocked implementation of the GetMessagesBefore method in the MatchRepository interface
+func (m *MockMatchRepository) GetMessagesBefore(matchRoomID uuid.UUID, before time.Time, beforeID uuid.UUID, limit int) ([]models.Message, error) {
+	args := m.Called(matchRoomID, before, beforeID, limit)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+// GetMessagesAfter is a mocked implementation of the GetMessagesAfter method in the MatchRepository interface
+func (m *MockMatchRepository) GetMessagesAfter(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error) {
+	args := m.Called(matchRoomID, after, afterID, limit)
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
+// GetMessageUpdates is a mocked implementation of the GetMessageUpdates method in the MatchRepository interface
+func (m *MockMatchRepository) GetMessageUpdates(matchRoomID uuid.UUID, after time.Time, afterID uuid.UUID, limit int) ([]models.Message, error) {
+	args := m.Called(matchRoomID, after, afterID, limit)
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func testChatConfig() config.ChatConfig {
+	return config.ChatConfig{
+		MessagePageSize:    2,
+		MaxMessagePageSize: 3,
+		EditWindow:         15 * time.Minute,
+		UnmatchRetention:   30 * 24 * time.Hour,
+		FirstMessagePolicy: config.FirstMessageAnyone,
+		FirstMessageWindow: 24 * time.Hour,
+		MatchExtension:     24 * time.Hour,
+		ReplyWindow:        7 * 24 * time.Hour,
+		ExpiryReminder:     3 * time.Hour,
+	}
+}
+
+func TestMatchRoom_PairKey(t *testing.T) {
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ff000000-0000-0000-0000-000000000000")
+
+	// The key is the same whichever of the pair matched
+	for _, room := range []*models.MatchRoom{newMatchRoom(uuid.New(), low, high), newMatchRoom(uuid.New(), high, low)} {
+		assert.NoError(t, room.BeforeCreate(nil))
+		assert.Equal(t, low, *room.UserLowID)
+		assert.Equal(t, high, *room.UserHighID)
+	}
+}
+
 func TestGetMatchRooms(t *testing.T) {
 	// Create a new instance of the mock MatchRepository
 	mockMatchRepo := new(MockMatchRepository)
-	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
 
 	// Mock user ID
 	userID := uuid.New()
 
 	// Mock match rooms
-	mockMatchRooms := []models.MatchRoom{
-		{ID: uuid.New(), UserID: userID},
-		{ID: uuid.New(), UserID: userID},
+	mockMatchRooms := []models.MatchRoomSummary{
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, TargetUserID: uuid.New()},
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, TargetUserID: uuid.New()},
 		// Add more match rooms as needed
 	}
 
-	// Set up expectation for GetMatchRooms method in mock repository
-	mockMatchRepo.On("GetMatchRooms", userID).Return(mockMatchRooms, nil)
+	// Set up expectation for GetMatchRoomSummaries method in mock repository
+	mockMatchRepo.On("GetMatchRoomSummaries", userID).Return(mockMatchRooms, nil)
 
 	// Call the GetMatchRooms method and assert the result
 	resultMatchRooms, err := matchUseCase.GetMatchRooms(userID)
@@ -79,30 +265,76 @@ func TestGetMatchRooms(t *testing.T) {
 	mockMatchRepo.AssertExpectations(t)
 }
 
-func TestDeleteMatchRoom(t *testing.T) {
+func TestUnmatch(t *testing.T) {
 	// Create a new instance of the mock MatchRepository
 	mockMatchRepo := new(MockMatchRepository)
-	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
 
 	// Mock match room ID and user ID
 	matchRoomID := uuid.New()
 	userID := uuid.New()
 
-	// Set up expectation for DeleteMatchRoom method in mock repository
-	mockMatchRepo.On("DeleteMatchRoom", matchRoomID, userID).Return(nil)
+	// Set up expectation for Unmatch method in mock repository
+	mockMatchRepo.On("Unmatch", mock.MatchedBy(func(unmatch *models.Unmatch) bool {
+		return unmatch.MatchRoomID == matchRoomID && unmatch.UserID == userID && unmatch.Reason == models.UnmatchNoResponse
+	})).Return(nil)
 
-	// Call the DeleteMatchRoom method and assert the result
-	err := matchUseCase.DeleteMatchRoom(matchRoomID, userID)
+	// Call the Unmatch method and assert the result
+	unmatch, err := matchUseCase.Unmatch(matchRoomID, userID, models.UnmatchNoResponse)
 	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), unmatch.UnmatchedAt, time.Minute)
 
 	// Assert that all expectations were met
 	mockMatchRepo.AssertExpectations(t)
 }
 
+func TestUnmatch_Reason(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+	mockMatchRepo.On("Unmatch", mock.Anything).Return(nil)
+
+	// The reason is optional
+	unmatch, err := matchUseCase.Unmatch(uuid.New(), uuid.New(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.UnmatchUnspecified, unmatch.Reason)
+
+	_, err = matchUseCase.Unmatch(uuid.New(), uuid.New(), "bored")
+	assert.ErrorIs(t, err, usecase.ErrInvalidUnmatchReason)
+	mockMatchRepo.AssertNumberOfCalls(t, "Unmatch", 1)
+}
+
+func TestUnmatch_NotParticipant(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	mockMatchRepo.On("Unmatch", mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	_, err := matchUseCase.Unmatch(matchRoomID, userID, models.UnmatchOther)
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+}
+
+func TestPurgeUnmatchedMessages_AfterRetention(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	cfg := testChatConfig()
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), cfg)
+
+	now := time.Now()
+	unmatches := []models.Unmatch{{ID: uuid.New()}, {ID: uuid.New()}}
+	mockMatchRepo.On("GetUnmatchesToPurge", now.Add(-cfg.UnmatchRetention), mock.Anything).Return(unmatches, nil)
+	mockMatchRepo.On("PurgeMessages", mock.Anything, now).Return(nil)
+
+	purged, err := matchUseCase.PurgeUnmatchedMessages(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	mockMatchRepo.AssertNumberOfCalls(t, "PurgeMessages", 2)
+}
+
 func TestCreateMessage(t *testing.T) {
 	// Create a new instance of the mock MatchRepository
 	mockMatchRepo := new(MockMatchRepository)
-	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
 
 	// Mock message
 	mockMessage := &models.Message{
@@ -113,7 +345,8 @@ func TestCreateMessage(t *testing.T) {
 		// Add other message fields as needed
 	}
 
-	// Set up expectation for CreateMessage method in mock repository
+	// Set up expectation for GetMatchRoom and CreateMessage methods in mock repository
+	mockMatchRepo.On("GetMatchRoom", mockMessage.MatchRoomID, mockMessage.SenderID).Return(newMatchRoom(mockMessage.MatchRoomID, mockMessage.SenderID, uuid.New()), nil)
 	mockMatchRepo.On("CreateMessage", mockMessage).Return(nil)
 
 	// Call the CreateMessage method and assert the result
@@ -124,13 +357,27 @@ func TestCreateMessage(t *testing.T) {
 	mockMatchRepo.AssertExpectations(t)
 }
 
+func TestCreateMessage_ClosedRoom(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	// Unmatched, or one side blocked the other
+	message := &models.Message{MatchRoomID: uuid.New(), SenderID: uuid.New(), Content: "still there?"}
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, message.SenderID).Return((*models.MatchRoom)(nil), gorm.ErrRecordNotFound)
+
+	err := matchUseCase.CreateMessage(message)
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+	mockMatchRepo.AssertNotCalled(t, "CreateMessage", message)
+}
+
 func TestGetMessages(t *testing.T) {
 	// Create a new instance of the mock MatchRepository
 	mockMatchRepo := new(MockMatchRepository)
-	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
 
-	// Mock match room ID
+	// Mock match room ID and user ID
 	matchRoomID := uuid.New()
+	userID := uuid.New()
 
 	// Mock messages
 	mockMessages := []models.Message{
@@ -139,14 +386,15 @@ func TestGetMessages(t *testing.T) {
 		// Add more messages as needed
 	}
 
-	// Set up expectation for GetMessages method in mock repository
-	mockMatchRepo.On("GetMessages", matchRoomID).Return(mockMessages, nil)
+	// Set up expectations for GetMatchRoom and GetMessagesBefore methods in mock repository
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessagesBefore", matchRoomID, time.Time{}, uuid.Nil, 2).Return(mockMessages, nil)
 
 	// Call the GetMessages method and assert the result
-	resultMessages, err := matchUseCase.GetMessages(matchRoomID)
+	page, err := matchUseCase.GetMessages(matchRoomID, userID, models.MessageQuery{})
 	assert.NoError(t, err)
-	assert.NotNil(t, resultMessages)
-	assert.Len(t, resultMessages, len(mockMessages))
+	assert.NotNil(t, page)
+	assert.Len(t, page.Messages, len(mockMessages))
 
 	// Assert that all expectations were met
 	mockMatchRepo.AssertExpectations(t)
@@ -0,0 +1,265 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func messageAt(matchRoomID uuid.UUID, createdAt time.Time) models.Message {
+	message := models.Message{ID: uuid.New(), MatchRoomID: matchRoomID}
+	message.CreatedAt = createdAt
+	message.UpdatedAt = createdAt
+	return message
+}
+
+// sameInstant matches a time decoded from a cursor, which comes back in another location.
+func sameInstant(at time.Time) interface{} {
+	return mock.MatchedBy(func(decoded time.Time) bool { return decoded.Equal(at) })
+}
+
+func TestGetMessages_PagesBackAndForth(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	now := time.Now().Truncate(time.Microsecond)
+	newest := messageAt(matchRoomID, now)
+	older := messageAt(matchRoomID, now.Add(-time.Minute))
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessagesBefore", matchRoomID, time.Time{}, uuid.Nil, 2).Return([]models.Message{newest, older}, nil)
+
+	page, err := matchUseCase.GetMessages(matchRoomID, userID, models.MessageQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{newest, older}, page.Messages)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// Older messages continue before the oldest one served
+	mockMatchRepo.On("GetMessagesBefore", matchRoomID, mock.Anything, older.ID, 3).Return([]models.Message{}, nil)
+
+	page, err = matchUseCase.GetMessages(matchRoomID, userID, models.MessageQuery{Before: page.NextCursor, Limit: 3})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Messages)
+	assert.Empty(t, page.NextCursor)
+
+	// Newer messages are listed after a cursor, oldest first
+	var after time.Time
+	mockMatchRepo.On("GetMessagesAfter", matchRoomID, mock.Anything, older.ID, 2).Run(func(args mock.Arguments) {
+		after = args.Get(1).(time.Time)
+	}).Return([]models.Message{newest}, nil)
+
+	cursorPage, err := matchUseCase.GetMessages(matchRoomID, userID, models.MessageQuery{})
+	assert.NoError(t, err)
+	page, err = matchUseCase.GetMessages(matchRoomID, userID, models.MessageQuery{After: cursorPage.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{newest}, page.Messages)
+	assert.Empty(t, page.NextCursor)
+	assert.True(t, older.CreatedAt.Equal(after))
+}
+
+func TestGetMessages_InvalidQuery(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	_, err := matchUseCase.GetMessages(uuid.New(), uuid.New(), models.MessageQuery{Before: "a", After: "b"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMessageQuery)
+
+	_, err = matchUseCase.GetMessages(uuid.New(), uuid.New(), models.MessageQuery{Limit: 4})
+	assert.ErrorIs(t, err, usecase.ErrInvalidMessageQuery)
+
+	mockMatchRepo.AssertNotCalled(t, "GetMatchRoom", mock.Anything, mock.Anything)
+}
+
+func TestGetMessages_OnlyForParticipants(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	outsider := uuid.New()
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, outsider).Return((*models.MatchRoom)(nil), gorm.ErrRecordNotFound)
+
+	_, err := matchUseCase.GetMessages(matchRoomID, outsider, models.MessageQuery{})
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+
+	_, err = matchUseCase.GetMessageUpdates(matchRoomID, outsider, time.Now().Format(time.RFC3339Nano))
+	assert.ErrorIs(t, err, usecase.ErrMatchRoomNotFound)
+
+	mockMatchRepo.AssertNotCalled(t, "GetMessagesBefore", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockMatchRepo.AssertNotCalled(t, "GetMessageUpdates", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetMessageUpdates_SplitsDeletedAndPages(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	since := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	created := messageAt(matchRoomID, since.Add(time.Minute))
+	deleted := messageAt(matchRoomID, since.Add(-time.Hour))
+	deleted.DeletedAt = gorm.DeletedAt{Time: since.Add(2 * time.Minute), Valid: true}
+	edited := messageAt(matchRoomID, since.Add(3*time.Minute))
+	more := messageAt(matchRoomID, since.Add(4*time.Minute))
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessageUpdates", matchRoomID, sameInstant(since), uuid.Nil, 4).Return([]models.Message{created, deleted, edited, more}, nil)
+
+	updates, err := matchUseCase.GetMessageUpdates(matchRoomID, userID, since.Format(time.RFC3339Nano))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{created, edited}, updates.Messages)
+	assert.Equal(t, []uuid.UUID{deleted.ID}, updates.Deleted)
+	assert.True(t, updates.HasMore)
+
+	// The next sync continues after the last change served
+	mockMatchRepo.On("GetMessageUpdates", matchRoomID, sameInstant(edited.UpdatedAt), edited.ID, 4).Return([]models.Message{}, nil)
+
+	next, err := matchUseCase.GetMessageUpdates(matchRoomID, userID, updates.NextSince)
+	assert.NoError(t, err)
+	assert.Empty(t, next.Messages)
+	assert.False(t, next.HasMore)
+	// Nothing new keeps the client where it was
+	assert.Equal(t, updates.NextSince, next.NextSince)
+}
+
+func TestGetMessageUpdates_PageOfTheSameInstant(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	since := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+	// More changes at one instant than fit in a page, e.g. a bulk update
+	at := since.Add(time.Minute)
+	changes := []models.Message{messageAt(matchRoomID, at), messageAt(matchRoomID, at), messageAt(matchRoomID, at), messageAt(matchRoomID, at)}
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessageUpdates", matchRoomID, sameInstant(since), uuid.Nil, 4).Return(changes, nil)
+	mockMatchRepo.On("GetMessageUpdates", matchRoomID, sameInstant(at), changes[2].ID, 4).Return(changes[3:], nil)
+
+	updates, err := matchUseCase.GetMessageUpdates(matchRoomID, userID, since.Format(time.RFC3339Nano))
+	assert.NoError(t, err)
+	assert.Equal(t, changes[:3], updates.Messages)
+	assert.True(t, updates.HasMore)
+
+	// The rest of that instant comes with the next sync
+	next, err := matchUseCase.GetMessageUpdates(matchRoomID, userID, updates.NextSince)
+	assert.NoError(t, err)
+	assert.Equal(t, changes[3:], next.Messages)
+	assert.False(t, next.HasMore)
+}
+
+func TestGetMessageUpdates_InvalidSince(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	for _, since := range []string{"", "yesterday"} {
+		_, err := matchUseCase.GetMessageUpdates(uuid.New(), uuid.New(), since)
+		assert.ErrorIs(t, err, utils.ErrInvalidCursor, since)
+	}
+	mockMatchRepo.AssertNotCalled(t, "GetMatchRoom", mock.Anything, mock.Anything)
+}
+
+// sentMessage sets up a match room with a message userID sent at createdAt.
+func sentMessage(mockMatchRepo *MockMatchRepository, userID uuid.UUID, createdAt time.Time) models.Message {
+	matchRoomID := uuid.New()
+	message := messageAt(matchRoomID, createdAt)
+	message.SenderID = userID
+	message.Content = "helo"
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, mock.Anything).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessage", message.ID).Return(&message, nil)
+	return message
+}
+
+func TestEditMessage_KeepsHistoryWithinWindow(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	message := sentMessage(mockMatchRepo, userID, time.Now().Add(-time.Minute))
+
+	var edit *models.MessageEdit
+	mockMatchRepo.On("EditMessage", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		edit = args.Get(1).(*models.MessageEdit)
+	}).Return(nil)
+
+	edited, err := matchUseCase.EditMessage(message.MatchRoomID, message.ID, userID, " hello ")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", edited.Content)
+	assert.NotNil(t, edited.EditedAt)
+	assert.Equal(t, "helo", edit.Content)
+	assert.Equal(t, message.ID, edit.MessageID)
+
+	// Only the sender may edit
+	_, err = matchUseCase.EditMessage(message.MatchRoomID, message.ID, uuid.New(), "hi")
+	assert.ErrorIs(t, err, usecase.ErrNotMessageSender)
+}
+
+func TestEditMessage_WindowClosed(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	message := sentMessage(mockMatchRepo, userID, time.Now().Add(-time.Hour))
+
+	_, err := matchUseCase.EditMessage(message.MatchRoomID, message.ID, userID, "hello")
+	assert.ErrorIs(t, err, usecase.ErrEditWindowClosed)
+
+	mockMatchRepo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything)
+}
+
+func TestUnsendMessage_LeavesTombstone(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	message := sentMessage(mockMatchRepo, userID, time.Now().Add(-time.Hour))
+	mockMatchRepo.On("UnsendMessage", mock.Anything).Return(nil)
+
+	unsent, err := matchUseCase.UnsendMessage(message.MatchRoomID, message.ID, userID)
+	assert.NoError(t, err)
+	assert.Empty(t, unsent.Content)
+	assert.NotNil(t, unsent.UnsentAt)
+
+	// The tombstone can no longer be changed or reacted to
+	_, err = matchUseCase.EditMessage(message.MatchRoomID, message.ID, userID, "hello")
+	assert.ErrorIs(t, err, usecase.ErrMessageUnsent)
+	_, err = matchUseCase.React(message.MatchRoomID, message.ID, uuid.New(), "👍")
+	assert.ErrorIs(t, err, usecase.ErrMessageUnsent)
+}
+
+func TestReact_OneEmojiPerParticipant(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	message := sentMessage(mockMatchRepo, uuid.New(), time.Now())
+	reactorID := uuid.New()
+	mockMatchRepo.On("SetReaction", mock.Anything).Return(nil)
+
+	for _, emoji := range []string{"", "lol", "👍 👍", ":)"} {
+		_, err := matchUseCase.React(message.MatchRoomID, message.ID, reactorID, emoji)
+		assert.ErrorIs(t, err, usecase.ErrInvalidReaction, emoji)
+	}
+
+	for _, emoji := range []string{"👍", "❤️", "👍🏽", "🇮🇩"} {
+		reaction, err := matchUseCase.React(message.MatchRoomID, message.ID, reactorID, emoji)
+		assert.NoError(t, err, emoji)
+		assert.Equal(t, models.MessageReaction{MessageID: message.ID, UserID: reactorID, Emoji: emoji, CreatedAt: reaction.CreatedAt}, *reaction)
+	}
+
+	mockMatchRepo.On("DeleteReaction", message.ID, reactorID).Return(true, nil)
+	removed, err := matchUseCase.Unreact(message.MatchRoomID, message.ID, reactorID)
+	assert.NoError(t, err)
+	assert.True(t, removed)
+}
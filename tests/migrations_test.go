@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/migrations"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The tables as the first release created them
+
+type baselineUser struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key"`
+	Username          string    `gorm:"uniqueIndex;not null"`
+	Email             string    `gorm:"uniqueIndex;not null"`
+	Password          string    `gorm:"not null"`
+	IsPremium         bool
+	PremiumExpiryTime time.Time
+	gorm.Model
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineProfile struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	Name         string
+	Bio          string
+	ProfileImage string
+	gorm.Model
+}
+
+func (baselineProfile) TableName() string { return "profiles" }
+
+type baselineMatchRoom struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	TargetUserID uuid.UUID `gorm:"type:uuid;not null"`
+	gorm.Model
+}
+
+func (baselineMatchRoom) TableName() string { return "match_rooms" }
+
+type baselineMessage struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	MatchRoomID uuid.UUID `gorm:"type:uuid;not null"`
+	SenderID    uuid.UUID `gorm:"type:uuid;not null"`
+	Content     string    `gorm:"not null"`
+	gorm.Model
+}
+
+func (baselineMessage) TableName() string { return "messages" }
+
+type baselineSwipe struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	TargetUserID uuid.UUID `gorm:"type:uuid;not null"`
+	Liked        bool      `gorm:"not null"`
+	gorm.Model
+}
+
+func (baselineSwipe) TableName() string { return "swipes" }
+
+// migrationsDB opens a schema of its own in the database of TEST_DATABASE_DSN, a DSN in
+// key=value form, and drops it after the test. Without the DSN the test is skipped.
+func migrationsDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := "migrations_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRun_UpgradesBaselineDatabase(t *testing.T) {
+	db := migrationsDB(t)
+
+	err := db.AutoMigrate(&baselineUser{}, &baselineProfile{}, &baselineMatchRoom{}, &baselineMessage{}, &baselineSwipe{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := baselineUser{ID: uuid.New(), Username: "user", Email: "user@example.com", Password: "secret"}
+	target := baselineUser{ID: uuid.New(), Username: "target", Email: "target@example.com", Password: "secret"}
+	// The first release stored a match for the liking user only
+	room := baselineMatchRoom{ID: uuid.New(), UserID: user.ID, TargetUserID: target.ID}
+	rows := []interface{}{
+		&user,
+		&target,
+		&baselineSwipe{ID: uuid.New(), UserID: target.ID, TargetUserID: user.ID, Liked: true},
+		&baselineSwipe{ID: uuid.New(), UserID: user.ID, TargetUserID: target.ID, Liked: true},
+		&room,
+		&baselineMessage{ID: uuid.New(), MatchRoomID: room.ID, SenderID: user.ID, Content: "Hi"},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !assert.NoError(t, migrations.Run(db)) {
+		return
+	}
+	// Nothing is left pending, a restart is a no-op
+	assert.NoError(t, migrations.Run(db))
+
+	var rooms int64
+	db.Table("match_rooms").Where("id = ? AND deleted_at IS NULL", room.ID).Count(&rooms)
+	assert.Equal(t, int64(1), rooms)
+
+	var participants []uuid.UUID
+	db.Table("room_participants").Where("match_room_id = ?", room.ID).Pluck("user_id", &participants)
+	assert.ElementsMatch(t, []uuid.UUID{user.ID, target.ID}, participants)
+
+	var key struct{ UserLowID, UserHighID uuid.UUID }
+	db.Table("match_rooms").Select("user_low_id, user_high_id").Where("id = ?", room.ID).Scan(&key)
+	assert.ElementsMatch(t, []uuid.UUID{user.ID, target.ID}, []uuid.UUID{key.UserLowID, key.UserHighID})
+	assert.NotEqual(t, key.UserLowID, key.UserHighID)
+
+	var types []string
+	db.Table("swipes").Distinct().Pluck("type", &types)
+	assert.Equal(t, []string{"like"}, types)
+
+	var keys int64
+	db.Raw("SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = current_schema() AND constraint_name = ?", "fk_match_rooms_participants").Scan(&keys)
+	assert.Equal(t, int64(1), keys)
+}
+
+func TestRun_NewDatabase(t *testing.T) {
+	db := migrationsDB(t)
+
+	if !assert.NoError(t, migrations.Run(db)) {
+		return
+	}
+	assert.False(t, db.Migrator().HasColumn("match_rooms", "user_id"))
+	assert.True(t, db.Migrator().HasTable("room_participants"))
+}
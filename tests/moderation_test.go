@@ -0,0 +1,343 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/handler"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/routes"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockModerationRepository struct {
+	mock.Mock
+}
+
+// OpenCase is a mocked implementation of the OpenCase method in the ModerationRepository interface
+func (m *MockModerationRepository) OpenCase(moderationCase *models.ModerationCase, note *models.CaseNote) (*models.ModerationCase, error) {
+	args := m.Called(moderationCase, note)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// CreateReport is a mocked implementation of the CreateReport method in the ModerationRepository interface
+func (m *MockModerationRepository) CreateReport(report *models.Report, moderationCase *models.ModerationCase) (*models.ModerationCase, error) {
+	args := m.Called(report, moderationCase)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// MarkProfileHidden is a mocked implementation of the MarkProfileHidden method in the ModerationRepository interface
+func (m *MockModerationRepository) MarkProfileHidden(caseID uuid.UUID, hiddenAt time.Time) error {
+	args := m.Called(caseID, hiddenAt)
+	return args.Error(0)
+}
+
+// GetCase is a mocked implementation of the GetCase method in the ModerationRepository interface
+func (m *MockModerationRepository) GetCase(id uuid.UUID) (*models.ModerationCase, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// GetCases is a mocked implementation of the GetCases method in the ModerationRepository interface
+func (m *MockModerationRepository) GetCases(filter models.CaseFilter, afterDueAt time.Time, afterID uuid.UUID, limit int) ([]models.ModerationCase, error) {
+	args := m.Called(filter, afterDueAt, afterID, limit)
+	return args.Get(0).([]models.ModerationCase), args.Error(1)
+}
+
+// UpdateCase is a mocked implementation of the UpdateCase method in the ModerationRepository interface
+func (m *MockModerationRepository) UpdateCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote) error {
+	args := m.Called(moderationCase, from, note)
+	return args.Error(0)
+}
+
+// ResolveCase is a mocked implementation of the ResolveCase method in the ModerationRepository interface
+func (m *MockModerationRepository) ResolveCase(moderationCase *models.ModerationCase, from models.CaseStatus, note *models.CaseNote, now time.Time) error {
+	args := m.Called(moderationCase, from, note, now)
+	return args.Error(0)
+}
+
+// CreateNote is a mocked implementation of the CreateNote method in the ModerationRepository interface
+func (m *MockModerationRepository) CreateNote(note *models.CaseNote) error {
+	args := m.Called(note)
+	return args.Error(0)
+}
+
+// GetMetrics is a mocked implementation of the GetMetrics method in the ModerationRepository interface
+func (m *MockModerationRepository) GetMetrics(now, resolvedSince time.Time) (*models.ModerationMetrics, error) {
+	args := m.Called(now, resolvedSince)
+	return args.Get(0).(*models.ModerationMetrics), args.Error(1)
+}
+
+type MockModerationUseCase struct {
+	mock.Mock
+}
+
+// OpenCase is a mocked implementation of the OpenCase method in the ModerationUseCase interface
+func (m *MockModerationUseCase) OpenCase(userID uuid.UUID, source models.CaseSource, summary string) (*models.ModerationCase, error) {
+	args := m.Called(userID, source, summary)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// FileReport is a mocked implementation of the FileReport method in the ModerationUseCase interface
+func (m *MockModerationUseCase) FileReport(report *models.Report) (*models.ModerationCase, error) {
+	args := m.Called(report)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// GetCases is a mocked implementation of the GetCases method in the ModerationUseCase interface
+func (m *MockModerationUseCase) GetCases(filter models.CaseFilter, cursor string) (*models.CasePage, error) {
+	args := m.Called(filter, cursor)
+	return args.Get(0).(*models.CasePage), args.Error(1)
+}
+
+// GetCase is a mocked implementation of the GetCase method in the ModerationUseCase interface
+func (m *MockModerationUseCase) GetCase(id uuid.UUID) (*models.ModerationCase, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// Assign is a mocked implementation of the Assign method in the ModerationUseCase interface
+func (m *MockModerationUseCase) Assign(caseID, moderatorID, assigneeID uuid.UUID) (*models.ModerationCase, error) {
+	args := m.Called(caseID, moderatorID, assigneeID)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// Release is a mocked implementation of the Release method in the ModerationUseCase interface
+func (m *MockModerationUseCase) Release(caseID, moderatorID uuid.UUID) (*models.ModerationCase, error) {
+	args := m.Called(caseID, moderatorID)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// AddNote is a mocked implementation of the AddNote method in the ModerationUseCase interface
+func (m *MockModerationUseCase) AddNote(caseID, authorID uuid.UUID, body string) (*models.CaseNote, error) {
+	args := m.Called(caseID, authorID, body)
+	return args.Get(0).(*models.CaseNote), args.Error(1)
+}
+
+// Decide is a mocked implementation of the Decide method in the ModerationUseCase interface
+func (m *MockModerationUseCase) Decide(caseID, moderatorID uuid.UUID, request usecase.DecisionRequest) (*models.ModerationCase, error) {
+	args := m.Called(caseID, moderatorID, request)
+	return args.Get(0).(*models.ModerationCase), args.Error(1)
+}
+
+// GetMetrics is a mocked implementation of the GetMetrics method in the ModerationUseCase interface
+func (m *MockModerationUseCase) GetMetrics(now time.Time) (*models.ModerationMetrics, error) {
+	args := m.Called(now)
+	return args.Get(0).(*models.ModerationMetrics), args.Error(1)
+}
+
+// LiftEndedSuspensions is a mocked implementation of the LiftEndedSuspensions method in the ModerationUseCase interface
+func (m *MockModerationUseCase) LiftEndedSuspensions(now time.Time) (int, error) {
+	args := m.Called(now)
+	return args.Int(0), args.Error(1)
+}
+
+// ReleaseMessage is a mocked implementation of the ReleaseMessage method in the ModerationUseCase interface
+func (m *MockModerationUseCase) ReleaseMessage(messageID uuid.UUID) (*models.Message, error) {
+	args := m.Called(messageID)
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func testModerationConfig() config.ModerationConfig {
+	return config.ModerationConfig{AutoHideReports: 3, SLA: 24 * time.Hour, UrgentSLA: 4 * time.Hour}
+}
+
+func newModerationUseCase() (usecase.ModerationUseCase, *MockModerationRepository, *MockUserRepository, *MockProfileRepository) {
+	mockModerationRepo := new(MockModerationRepository)
+	mockUserRepo := new(MockUserRepository)
+	mockProfileRepo := new(MockProfileRepository)
+	return usecase.NewModerationUseCase(mockModerationRepo, mockUserRepo, mockProfileRepo, new(MockMatchRepository), testModerationConfig()), mockModerationRepo, mockUserRepo, mockProfileRepo
+}
+
+func TestFileReport_UrgentCategoriesAreDueSooner(t *testing.T) {
+	moderationUseCase, mockModerationRepo, _, _ := newModerationUseCase()
+
+	var opened []*models.ModerationCase
+	mockModerationRepo.On("CreateReport", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		opened = append(opened, args.Get(1).(*models.ModerationCase))
+	}).Return(&models.ModerationCase{ID: uuid.New(), ReporterCount: 1}, nil)
+
+	start := time.Now()
+	_, err := moderationUseCase.FileReport(&models.Report{ReportedUserID: uuid.New(), Category: models.ReportHarassment})
+	assert.NoError(t, err)
+	_, err = moderationUseCase.FileReport(&models.Report{ReportedUserID: uuid.New(), Category: models.ReportSpam})
+	assert.NoError(t, err)
+
+	require.Len(t, opened, 2)
+	assert.Equal(t, models.CasePriorityUrgent, opened[0].Priority)
+	assert.WithinDuration(t, start.Add(4*time.Hour), opened[0].DueAt, time.Second)
+	assert.Equal(t, models.CasePriorityNormal, opened[1].Priority)
+	assert.WithinDuration(t, start.Add(24*time.Hour), opened[1].DueAt, time.Second)
+	assert.Equal(t, models.CaseSourceReport, opened[1].Source)
+}
+
+func TestAssign_OnlyToModerators(t *testing.T) {
+	moderationUseCase, mockModerationRepo, mockUserRepo, _ := newModerationUseCase()
+
+	caseID := uuid.New()
+	moderatorID := uuid.New()
+	userID := uuid.New()
+	mockModerationRepo.On("GetCase", caseID).Return(&models.ModerationCase{ID: caseID, Status: models.CaseOpen}, nil)
+	mockUserRepo.On("GetUserByID", moderatorID).Return(&models.User{ID: moderatorID, Role: models.RoleModerator}, nil)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, Role: models.RoleUser}, nil)
+	mockModerationRepo.On("UpdateCase", mock.Anything, models.CaseOpen, mock.Anything).Return(nil)
+
+	_, err := moderationUseCase.Assign(caseID, moderatorID, userID)
+	assert.ErrorIs(t, err, usecase.ErrInvalidAssignee)
+
+	moderationCase, err := moderationUseCase.Assign(caseID, moderatorID, moderatorID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CaseInReview, moderationCase.Status)
+	assert.Equal(t, &moderatorID, moderationCase.AssigneeID)
+}
+
+func TestAssign_CaseChangedMeanwhile(t *testing.T) {
+	moderationUseCase, mockModerationRepo, mockUserRepo, _ := newModerationUseCase()
+
+	caseID := uuid.New()
+	moderatorID := uuid.New()
+	mockModerationRepo.On("GetCase", caseID).Return(&models.ModerationCase{ID: caseID, Status: models.CaseOpen}, nil)
+	mockUserRepo.On("GetUserByID", moderatorID).Return(&models.User{ID: moderatorID, Role: models.RoleAdmin}, nil)
+	// Another moderator resolved it first
+	mockModerationRepo.On("UpdateCase", mock.Anything, models.CaseOpen, mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	_, err := moderationUseCase.Assign(caseID, moderatorID, moderatorID)
+	assert.ErrorIs(t, err, usecase.ErrCaseChanged)
+
+	mockModerationRepo.On("GetCase", mock.Anything).Return((*models.ModerationCase)(nil), gorm.ErrRecordNotFound)
+	_, err = moderationUseCase.Assign(uuid.New(), moderatorID, moderatorID)
+	assert.ErrorIs(t, err, usecase.ErrCaseNotFound)
+}
+
+func TestDecide_SuspendsUserAndHidesProfile(t *testing.T) {
+	moderationUseCase, mockModerationRepo, _, _ := newModerationUseCase()
+
+	caseID := uuid.New()
+	userID := uuid.New()
+	moderatorID := uuid.New()
+	mockModerationRepo.On("GetCase", caseID).Return(&models.ModerationCase{ID: caseID, UserID: userID, Status: models.CaseInReview}, nil)
+	mockModerationRepo.On("ResolveCase", mock.Anything, models.CaseInReview, mock.Anything, mock.Anything).Return(nil)
+
+	_, err := moderationUseCase.Decide(caseID, moderatorID, usecase.DecisionRequest{Decision: models.DecisionSuspend})
+	assert.ErrorIs(t, err, usecase.ErrInvalidDecision)
+
+	start := time.Now()
+	moderationCase, err := moderationUseCase.Decide(caseID, moderatorID, usecase.DecisionRequest{Decision: models.DecisionSuspend, SuspendFor: 72 * time.Hour, Note: "scam messages"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.CaseResolved, moderationCase.Status)
+	assert.Equal(t, &moderatorID, moderationCase.DecidedByID)
+	require.NotNil(t, moderationCase.SuspendUntil)
+	assert.WithinDuration(t, start.Add(72*time.Hour), *moderationCase.SuspendUntil, time.Second)
+	mockModerationRepo.AssertNumberOfCalls(t, "ResolveCase", 1)
+}
+
+func TestDecide_CaseChangedMeanwhile(t *testing.T) {
+	moderationUseCase, mockModerationRepo, _, _ := newModerationUseCase()
+
+	caseID := uuid.New()
+	mockModerationRepo.On("GetCase", caseID).Return(&models.ModerationCase{ID: caseID, UserID: uuid.New(), Status: models.CaseOpen}, nil)
+	// Another moderator resolved it first
+	mockModerationRepo.On("ResolveCase", mock.Anything, models.CaseOpen, mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+
+	_, err := moderationUseCase.Decide(caseID, uuid.New(), usecase.DecisionRequest{Decision: models.DecisionWarn})
+	assert.ErrorIs(t, err, usecase.ErrCaseChanged)
+}
+
+func TestDecide_ResolvedCaseIsFinal(t *testing.T) {
+	moderationUseCase, mockModerationRepo, _, _ := newModerationUseCase()
+
+	caseID := uuid.New()
+	resolved := &models.ModerationCase{ID: caseID, UserID: uuid.New(), Status: models.CaseResolved}
+	mockModerationRepo.On("GetCase", caseID).Return(resolved, nil)
+
+	_, err := moderationUseCase.Decide(caseID, uuid.New(), usecase.DecisionRequest{Decision: models.DecisionBan})
+	assert.ErrorIs(t, err, usecase.ErrCaseResolved)
+	mockModerationRepo.AssertNotCalled(t, "ResolveCase", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLiftEndedSuspensions(t *testing.T) {
+	moderationUseCase, _, mockUserRepo, mockProfileRepo := newModerationUseCase()
+
+	now := time.Now()
+	users := []models.User{{ID: uuid.New()}, {ID: uuid.New()}}
+	mockUserRepo.On("GetEndedSuspensions", now).Return(users, nil)
+	mockUserRepo.On("SetSuspendedUntil", mock.Anything, (*time.Time)(nil)).Return(nil)
+	mockProfileRepo.On("ShowProfile", mock.Anything, now).Return(nil)
+
+	lifted, err := moderationUseCase.LiftEndedSuspensions(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lifted)
+	// Whether an open case keeps a profile hidden is left to the repository
+	mockProfileRepo.AssertNumberOfCalls(t, "ShowProfile", 2)
+	mockProfileRepo.AssertNotCalled(t, "SetHidden", mock.Anything, mock.Anything)
+}
+
+func TestLogin_BarredAccount(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	userUseCase := usecase.NewUserUseCase(mockUserRepo)
+
+	hashedPassword, _ := utils.HashPassword("password")
+	bannedAt := time.Now().Add(-time.Hour)
+	mockUserRepo.On("GetUserByEmail", "banned@example.com").Return(&models.User{ID: uuid.New(), Password: hashedPassword, BannedAt: &bannedAt}, nil)
+
+	_, err := userUseCase.Login("banned@example.com", "password")
+	assert.ErrorIs(t, err, usecase.ErrAccountBarred)
+}
+
+func TestModerationRoutes_RequireModerator(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	publisher := realtime.NewMemoryPublisher()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers := routes.AppRouteHandlers{ModerationHandler: *handler.NewModerationHandler(mockModerationUseCase, publisher)}
+	routes.Routes(router, handlers, testJWTSecret, new(MockQuotaUseCase), new(MockPresenceUseCase), usecase.NewUserUseCase(mockUserRepo))
+
+	userID := uuid.New()
+	moderatorID := uuid.New()
+	suspendedID := uuid.New()
+	suspendedUntil := time.Now().Add(time.Hour)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, Role: models.RoleUser}, nil)
+	mockUserRepo.On("GetUserByID", moderatorID).Return(&models.User{ID: moderatorID, Role: models.RoleModerator}, nil)
+	mockUserRepo.On("GetUserByID", suspendedID).Return(&models.User{ID: suspendedID, Role: models.RoleModerator, SuspendedUntil: &suspendedUntil}, nil)
+
+	caseID := uuid.New()
+	sanctionedID := uuid.New()
+	decision := models.DecisionWarn
+	mockModerationUseCase.On("Decide", caseID, moderatorID, usecase.DecisionRequest{Decision: models.DecisionWarn}).
+		Return(&models.ModerationCase{ID: caseID, UserID: sanctionedID, Status: models.CaseResolved, Decision: &decision}, nil)
+
+	decide := func(userID uuid.UUID) int {
+		token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/admin/moderation/cases/"+caseID.String()+"/decision", strings.NewReader(`{"decision":"warn"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, decide(userID))
+	assert.Equal(t, http.StatusForbidden, decide(suspendedID))
+	assert.Equal(t, http.StatusOK, decide(moderatorID))
+
+	// The warned user hears of it
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, realtime.UserChannel(sanctionedID), events[0].Channel)
+	assert.Equal(t, realtime.EventModerationAction, events[0].Event)
+}
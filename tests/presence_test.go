@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPresenceUseCase struct {
+	mock.Mock
+}
+
+// Touch is a mocked implementation of the Touch method in the PresenceUseCase interface
+func (m *MockPresenceUseCase) Touch(userID uuid.UUID, now time.Time) error {
+	args := m.Called(userID, now)
+	return args.Error(0)
+}
+
+// GetPresence is a mocked implementation of the GetPresence method in the PresenceUseCase interface
+func (m *MockPresenceUseCase) GetPresence(userIDs []uuid.UUID, now time.Time) (map[uuid.UUID]*models.Presence, error) {
+	args := m.Called(userIDs, now)
+	return args.Get(0).(map[uuid.UUID]*models.Presence), args.Error(1)
+}
+
+func testPresenceConfig() config.PresenceConfig {
+	return config.PresenceConfig{
+		OnlineWindow:  5 * time.Minute,
+		TouchInterval: time.Minute,
+	}
+}
+
+// noPresence returns a presence usecase for which every user hides their presence.
+func noPresence() *MockPresenceUseCase {
+	mockPresenceUseCase := new(MockPresenceUseCase)
+	mockPresenceUseCase.On("GetPresence", mock.Anything, mock.Anything).Return(map[uuid.UUID]*models.Presence{}, nil)
+	return mockPresenceUseCase
+}
+
+func TestPresence_TouchIsSpacedOut(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	presenceUseCase := usecase.NewPresenceUseCase(mockProfileRepo, testPresenceConfig())
+
+	userID := uuid.New()
+	now := time.Now()
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+
+	assert.NoError(t, presenceUseCase.Touch(userID, now))
+	assert.NoError(t, presenceUseCase.Touch(userID, now.Add(30*time.Second)))
+	mockProfileRepo.AssertNumberOfCalls(t, "TouchLastActive", 1)
+
+	assert.NoError(t, presenceUseCase.Touch(userID, now.Add(time.Minute)))
+	mockProfileRepo.AssertNumberOfCalls(t, "TouchLastActive", 2)
+}
+
+func TestPresence_OnlineLastActiveAndHidden(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	presenceUseCase := usecase.NewPresenceUseCase(mockProfileRepo, testPresenceConfig())
+
+	now := time.Now()
+	online := models.UserActivity{UserID: uuid.New(), LastActiveAt: now.Add(-2 * time.Minute)}
+	away := models.UserActivity{UserID: uuid.New(), LastActiveAt: now.Add(-3 * time.Hour)}
+	hidden := models.UserActivity{UserID: uuid.New(), LastActiveAt: now, HidePresence: true}
+	userIDs := []uuid.UUID{online.UserID, away.UserID, hidden.UserID}
+
+	mockProfileRepo.On("GetActivity", userIDs).Return([]models.UserActivity{online, away, hidden}, nil)
+
+	presence, err := presenceUseCase.GetPresence(userIDs, now)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Presence{Online: true, LastActiveAt: online.LastActiveAt}, presence[online.UserID])
+	assert.Equal(t, &models.Presence{Online: false, LastActiveAt: away.LastActiveAt}, presence[away.UserID])
+	assert.NotContains(t, presence, hidden.UserID)
+}
+
+func TestGetMatchRooms_WithPresence(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockPresenceUseCase := new(MockPresenceUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), mockPresenceUseCase, testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	visible := uuid.New()
+	hidden := uuid.New()
+	presence := &models.Presence{Online: true, LastActiveAt: time.Now()}
+
+	mockMatchRepo.On("GetMatchRoomSummaries", userID).Return([]models.MatchRoomSummary{
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, TargetUserID: visible},
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, TargetUserID: hidden},
+	}, nil)
+	mockPresenceUseCase.On("GetPresence", []uuid.UUID{visible, hidden}, mock.Anything).Return(map[uuid.UUID]*models.Presence{visible: presence}, nil)
+
+	summaries, err := matchUseCase.GetMatchRooms(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, presence, summaries[0].Presence)
+	assert.Nil(t, summaries[1].Presence)
+}
@@ -1,7 +1,10 @@
 package tests
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
@@ -27,6 +30,24 @@ func (m *MockProfileRepository) GetProfileByID(id uuid.UUID) (*models.Profile, e
 	return args.Get(0).(*models.Profile), args.Error(1)
 }
 
+// GetProfileByUserID is a mocked implementation of the GetProfileByUserID method in the ProfileRepository interface
+func (m *MockProfileRepository) GetProfileByUserID(userID uuid.UUID) (*models.Profile, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.Profile), args.Error(1)
+}
+
+// GetProfiles is a mocked implementation of the GetProfiles method in the ProfileRepository interface
+func (m *MockProfileRepository) GetProfiles() ([]models.Profile, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Profile), args.Error(1)
+}
+
+// GetProfilesByUserIDs is a mocked implementation of the GetProfilesByUserIDs method in the ProfileRepository interface
+func (m *MockProfileRepository) GetProfilesByUserIDs(userIDs []uuid.UUID) ([]models.Profile, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]models.Profile), args.Error(1)
+}
+
 // UpdateProfile is a mocked implementation of the UpdateProfile method in the ProfileRepository interface
 func (m *MockProfileRepository) UpdateProfile(profile *models.Profile) error {
 	args := m.Called(profile)
@@ -39,16 +60,79 @@ func (m *MockProfileRepository) GetProfilesExcluding(excludeIDs []uuid.UUID, lim
 	return args.Get(0).([]models.Profile), args.Error(1)
 }
 
+// SetHidden is a mocked implementation of the SetHidden method in the ProfileRepository interface
+func (m *MockProfileRepository) SetHidden(userID uuid.UUID, hiddenAt *time.Time) error {
+	args := m.Called(userID, hiddenAt)
+	return args.Error(0)
+}
+
+// ShowProfile is a mocked implementation of the ShowProfile method in the ProfileRepository interface
+func (m *MockProfileRepository) ShowProfile(userID uuid.UUID, now time.Time) error {
+	args := m.Called(userID, now)
+	return args.Error(0)
+}
+
+// AdjustRating is a mocked implementation of the AdjustRating method in the ProfileRepository interface
+func (m *MockProfileRepository) AdjustRating(userID uuid.UUID, change float64) error {
+	args := m.Called(userID, change)
+	return args.Error(0)
+}
+
+// TouchLastActive is a mocked implementation of the TouchLastActive method in the ProfileRepository interface
+func (m *MockProfileRepository) TouchLastActive(userID uuid.UUID, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+// GetActivity is a mocked implementation of the GetActivity method in the ProfileRepository interface
+func (m *MockProfileRepository) GetActivity(userIDs []uuid.UUID) ([]models.UserActivity, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]models.UserActivity), args.Error(1)
+}
+
+// GetPreferenceByUserID is a mocked implementation of the GetPreferenceByUserID method in the ProfileRepository interface
+func (m *MockProfileRepository) GetPreferenceByUserID(userID uuid.UUID) (*models.Preference, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.Preference), args.Error(1)
+}
+
+// GetPreferencesByUserIDs is a mocked implementation of the GetPreferencesByUserIDs method in the ProfileRepository interface
+func (m *MockProfileRepository) GetPreferencesByUserIDs(userIDs []uuid.UUID) ([]models.Preference, error) {
+	args := m.Called(userIDs)
+	return args.Get(0).([]models.Preference), args.Error(1)
+}
+
+// RecordImpressions is a mocked implementation of the RecordImpressions method in the ProfileRepository interface
+func (m *MockProfileRepository) RecordImpressions(viewerID uuid.UUID, profileUserIDs []uuid.UUID, at time.Time) error {
+	args := m.Called(viewerID, profileUserIDs, at)
+	return args.Error(0)
+}
+
+// CountImpressions is a mocked implementation of the CountImpressions method in the ProfileRepository interface
+func (m *MockProfileRepository) CountImpressions(profileUserID uuid.UUID, start, end time.Time) (int64, error) {
+	args := m.Called(profileUserID, start, end)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// UpsertPreference is a mocked implementation of the UpsertPreference method in the ProfileRepository interface
+func (m *MockProfileRepository) UpsertPreference(preference *models.Preference) error {
+	args := m.Called(preference)
+	return args.Error(0)
+}
+
 // Test cases
 func TestValidProfileCreation(t *testing.T) {
 	mockProfileRepo := new(MockProfileRepository)
 	mockUserRepo := new(MockUserRepository)
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 	// Create mock repositories for dependencies
 
 	// Create a new instance of the ProfileUseCase with the mock repositories
-	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
 
 	// Valid profile creation input
 	profile := &models.Profile{
@@ -72,10 +156,13 @@ func TestValidProfileUpdate(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 	// Create mock repositories for dependencies
 
 	// Create a new instance of the ProfileUseCase with the mock repositories
-	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
 
 	// Valid profile update input
 	profile := &models.Profile{
@@ -99,10 +186,14 @@ func TestGetProfileByID(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	mockPresenceUseCase := new(MockPresenceUseCase)
 	// Create mock repositories for dependencies
 
 	// Create a new instance of the ProfileUseCase with the mock repositories
-	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, mockPresenceUseCase, testDeckConfig())
 
 	// Mock a profile
 	profileID := uuid.New()
@@ -115,64 +206,121 @@ func TestGetProfileByID(t *testing.T) {
 
 	// Set up expectation for GetProfileByID method in mock repository
 	mockProfileRepo.On("GetProfileByID", profileID).Return(mockProfile, nil)
+	presence := &models.Presence{Online: true, LastActiveAt: time.Now()}
+	mockPresenceUseCase.On("GetPresence", []uuid.UUID{mockProfile.UserID}, mock.Anything).Return(map[uuid.UUID]*models.Presence{mockProfile.UserID: presence}, nil)
 
 	// Call the GetProfileByID method and assert the result
 	resultProfile, err := profileUseCase.GetProfileByID(profileID)
 	assert.NoError(t, err)
 	assert.NotNil(t, resultProfile)
 	assert.Equal(t, profileID, resultProfile.ID)
+	assert.Equal(t, presence, resultProfile.Presence)
 
 	// Assert that all expectations were met
 	mockProfileRepo.AssertExpectations(t)
 }
 
+func TestGetProfileByID_HiddenPresence(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockPresenceUseCase := new(MockPresenceUseCase)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), new(MockSwipeRepository), new(MockMatchRepository), noBlocks(), new(MockDeckRepository), new(MockRecommender), new(MockQuotaUseCase), mockPresenceUseCase, testDeckConfig())
+
+	profile := &models.Profile{ID: uuid.New(), UserID: uuid.New(), Name: "Hidden", LastActiveAt: time.Now()}
+	mockProfileRepo.On("GetProfileByID", profile.ID).Return(profile, nil)
+	// The owner hides their presence, so there is none for them
+	mockPresenceUseCase.On("GetPresence", []uuid.UUID{profile.UserID}, mock.Anything).Return(map[uuid.UUID]*models.Presence{}, nil)
+
+	result, err := profileUseCase.GetProfileByID(profile.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, result.Presence)
+
+	body, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "LastActiveAt")
+	assert.NotContains(t, string(body), profile.LastActiveAt.Format("2006-01-02T15:04:05"))
+}
+
 func TestViewProfiles(t *testing.T) {
 	mockProfileRepo := new(MockProfileRepository)
 	mockUserRepo := new(MockUserRepository)
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 	// Create mock repositories for dependencies
 
 	// Create a new instance of the ProfileUseCase with the mock repositories
-	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, mockUserRepo, mockSwipeRepo, mockMatchRepo, noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
 
 	// Mock user ID
 	userID := uuid.New()
 
-	// Mock user with premium status
-	mockUser := &models.User{
-		ID:        userID,
-		IsPremium: true,
-	}
-
-	// Set up expectations for GetUserByID method in mock user repository
-	mockUserRepo.On("GetUserByID", userID).Return(mockUser, nil)
-
-	// Set up expectations for GetSwipedUsersID method in mock swipe repository
-	mockSwipeRepo.On("GetSwipedUsersID", userID, mock.Anything).Return([]uuid.UUID{}, nil)
-
-	// Set up expectations for GetMatchedUsersID method in mock match repository
-	mockMatchRepo.On("GetMatchedUsersID", userID, mock.Anything).Return([]uuid.UUID{}, nil)
+	// Mock a premium user with unlimited daily swipes
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(&models.QuotaStatus{Name: models.QuotaDailySwipes, Limit: -1, Remaining: -1, Unlimited: true}, nil)
 
 	// Mock profiles
 	mockProfiles := []models.Profile{
-		{ID: uuid.New(), Name: "Profile 1", UserID: uuid.New()},
+		{ID: uuid.New(), Name: "Profile 1", UserID: uuid.New(), Latitude: -6.21, Longitude: 106.85},
 		{ID: uuid.New(), Name: "Profile 2", UserID: uuid.New()},
 		// Add more profiles as needed
 	}
+	viewer := &models.Profile{UserID: userID, Latitude: -6.2, Longitude: 106.8}
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(viewer, nil)
+
+	// Set up expectations for the activity update
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+
+	// Set up expectations for a current deck holding both profiles
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	entries := []models.DeckEntry{
+		{UserID: userID, CandidateUserID: mockProfiles[0].UserID, Position: 1},
+		{UserID: userID, CandidateUserID: mockProfiles[1].UserID, Position: 2},
+	}
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+	mockDeckRepo.On("GetEntries", userID, math.MinInt32, 50).Return(entries, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+
+	// The repository returns profiles in arbitrary order
+	mockProfileRepo.On("GetProfilesByUserIDs", []uuid.UUID{mockProfiles[0].UserID, mockProfiles[1].UserID}).
+		Return([]models.Profile{mockProfiles[1], mockProfiles[0]}, nil)
 
-	// Set up expectations for GetProfilesExcluding method in mock profile repository
-	mockProfileRepo.On("GetProfilesExcluding", mock.Anything, 50).Return(mockProfiles, nil)
+	// Set up expectations for the super like badges and the impressions
+	mockSwipeRepo.On("GetSuperLikerIDs", userID).Return([]uuid.UUID{}, nil)
+	mockProfileRepo.On("RecordImpressions", userID, []uuid.UUID{mockProfiles[0].UserID, mockProfiles[1].UserID}, mock.Anything).Return(nil)
 
 	// Call the ViewProfiles method and assert the result
-	resultProfiles, err := profileUseCase.ViewProfiles(userID)
+	resultPage, err := profileUseCase.ViewProfiles(userID, "")
 	assert.NoError(t, err)
-	assert.NotNil(t, resultProfiles)
-	assert.Len(t, resultProfiles, len(mockProfiles))
+	assert.NotNil(t, resultPage)
+	assert.Len(t, resultPage.Profiles, len(mockProfiles))
+	assert.Equal(t, mockProfiles[0].ID, resultPage.Profiles[0].ID)
+	assert.Empty(t, resultPage.NextCursor)
+
+	// Others get a rounded distance, not where the profile is
+	assert.Equal(t, 6, *resultPage.Profiles[0].DistanceKm)
+	assert.Nil(t, resultPage.Profiles[1].DistanceKm)
+	body, err := json.Marshal(resultPage.Profiles[0])
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "Latitude")
+	assert.NotContains(t, string(body), "Longitude")
 
 	// Assert that all expectations were met
-	mockUserRepo.AssertExpectations(t)
-	mockSwipeRepo.AssertExpectations(t)
-	mockMatchRepo.AssertExpectations(t)
+	mockQuotaUseCase.AssertExpectations(t)
 	mockProfileRepo.AssertExpectations(t)
+	mockDeckRepo.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
+}
+
+func TestUpdatePreference_InvalidAgeRange(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), new(MockSwipeRepository), new(MockMatchRepository), noBlocks(), new(MockDeckRepository), new(MockRecommender), new(MockQuotaUseCase), new(MockPresenceUseCase), testDeckConfig())
+
+	preference := &models.Preference{UserID: uuid.New(), MinAge: 40, MaxAge: 30}
+
+	err := profileUseCase.UpdatePreference(preference)
+	assert.EqualError(t, err, "min age must not be greater than max age")
+
+	// The repository must not be reached with an invalid preference
+	mockProfileRepo.AssertNotCalled(t, "UpsertPreference", preference)
 }
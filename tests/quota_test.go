@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/middleware"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocking dependencies
+type MockQuotaRepository struct {
+	mock.Mock
+}
+
+// Increment is a mocked implementation of the Increment method in the QuotaRepository interface
+func (m *MockQuotaRepository) Increment(userID uuid.UUID, quota string, windowStart time.Time, limit int) (int, bool, error) {
+	args := m.Called(userID, quota, windowStart, limit)
+	return args.Int(0), args.Bool(1), args.Error(2)
+}
+
+// Decrement is a mocked implementation of the Decrement method in the QuotaRepository interface
+func (m *MockQuotaRepository) Decrement(userID uuid.UUID, quota string, windowStart time.Time) error {
+	args := m.Called(userID, quota, windowStart)
+	return args.Error(0)
+}
+
+// GetCounters is a mocked implementation of the GetCounters method in the QuotaRepository interface
+func (m *MockQuotaRepository) GetCounters(userID uuid.UUID, since time.Time) ([]models.QuotaCounter, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).([]models.QuotaCounter), args.Error(1)
+}
+
+type MockQuotaUseCase struct {
+	mock.Mock
+}
+
+// Consume is a mocked implementation of the Consume method in the QuotaUseCase interface
+func (m *MockQuotaUseCase) Consume(userID uuid.UUID, quota string) (*models.QuotaStatus, error) {
+	args := m.Called(userID, quota)
+	return args.Get(0).(*models.QuotaStatus), args.Error(1)
+}
+
+// Release is a mocked implementation of the Release method in the QuotaUseCase interface
+func (m *MockQuotaUseCase) Release(userID uuid.UUID, quota string) error {
+	args := m.Called(userID, quota)
+	return args.Error(0)
+}
+
+// GetQuota is a mocked implementation of the GetQuota method in the QuotaUseCase interface
+func (m *MockQuotaUseCase) GetQuota(userID uuid.UUID, quota string) (*models.QuotaStatus, error) {
+	args := m.Called(userID, quota)
+	return args.Get(0).(*models.QuotaStatus), args.Error(1)
+}
+
+// GetQuotas is a mocked implementation of the GetQuotas method in the QuotaUseCase interface
+func (m *MockQuotaUseCase) GetQuotas(userID uuid.UUID) ([]models.QuotaStatus, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.QuotaStatus), args.Error(1)
+}
+
+func testQuotaConfig() config.QuotaConfig {
+	free, _ := config.ParseQuotaPlan("daily_swipes=10/day,super_likes=1/day,boosts=0/month,rewinds=0/day,match_extensions=0/day")
+	premium, _ := config.ParseQuotaPlan("daily_swipes=unlimited/day,super_likes=5/day,boosts=1/month,rewinds=10/day,match_extensions=3/day")
+	return config.QuotaConfig{Plans: map[string]map[string]config.QuotaLimit{
+		config.PlanFree:    free,
+		config.PlanPremium: premium,
+	}}
+}
+
+func TestConsumeQuota(t *testing.T) {
+	mockQuotaRepo := new(MockQuotaRepository)
+	mockUserRepo := new(MockUserRepository)
+	quotaUseCase := usecase.NewQuotaUseCase(mockQuotaRepo, mockUserRepo, testQuotaConfig())
+
+	userID := uuid.New()
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID}, nil)
+	mockQuotaRepo.On("Increment", userID, models.QuotaDailySwipes, mock.Anything, 10).Return(4, true, nil)
+
+	status, err := quotaUseCase.Consume(userID, models.QuotaDailySwipes)
+	assert.NoError(t, err)
+	assert.Equal(t, config.PlanFree, status.Plan)
+	assert.Equal(t, 4, status.Used)
+	assert.Equal(t, 6, status.Remaining)
+
+	mockQuotaRepo.AssertExpectations(t)
+}
+
+func TestConsumeQuota_Exceeded(t *testing.T) {
+	mockQuotaRepo := new(MockQuotaRepository)
+	mockUserRepo := new(MockUserRepository)
+	quotaUseCase := usecase.NewQuotaUseCase(mockQuotaRepo, mockUserRepo, testQuotaConfig())
+
+	userID := uuid.New()
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID}, nil)
+	mockQuotaRepo.On("Increment", userID, models.QuotaDailySwipes, mock.Anything, 10).Return(10, false, nil)
+
+	status, err := quotaUseCase.Consume(userID, models.QuotaDailySwipes)
+	assert.True(t, errors.Is(err, usecase.ErrQuotaExceeded))
+	assert.Equal(t, 0, status.Remaining)
+}
+
+func TestConsumeQuota_NotInPlan(t *testing.T) {
+	mockQuotaRepo := new(MockQuotaRepository)
+	mockUserRepo := new(MockUserRepository)
+	quotaUseCase := usecase.NewQuotaUseCase(mockQuotaRepo, mockUserRepo, testQuotaConfig())
+
+	userID := uuid.New()
+
+	// Free users get no rewinds, so the counter store is never touched
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID}, nil)
+
+	_, err := quotaUseCase.Consume(userID, models.QuotaRewinds)
+	assert.True(t, errors.Is(err, usecase.ErrQuotaExceeded))
+
+	mockQuotaRepo.AssertNotCalled(t, "Increment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetQuotas_PremiumPlan(t *testing.T) {
+	mockQuotaRepo := new(MockQuotaRepository)
+	mockUserRepo := new(MockUserRepository)
+	quotaUseCase := usecase.NewQuotaUseCase(mockQuotaRepo, mockUserRepo, testQuotaConfig())
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, IsPremium: true, PremiumExpiryTime: now.Add(time.Hour)}, nil)
+	mockQuotaRepo.On("GetCounters", userID, mock.Anything).Return([]models.QuotaCounter{
+		{UserID: userID, Quota: models.QuotaSuperLikes, WindowStart: today, Used: 2},
+		// A counter from yesterday no longer counts
+		{UserID: userID, Quota: models.QuotaRewinds, WindowStart: today.AddDate(0, 0, -1), Used: 3},
+	}, nil)
+
+	statuses, err := quotaUseCase.GetQuotas(userID)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 5)
+
+	byName := map[string]models.QuotaStatus{}
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+
+	assert.True(t, byName[models.QuotaDailySwipes].Unlimited)
+	assert.Equal(t, 3, byName[models.QuotaSuperLikes].Remaining)
+	assert.Equal(t, 10, byName[models.QuotaRewinds].Remaining)
+	assert.Equal(t, today.AddDate(0, 0, 1), byName[models.QuotaRewinds].ResetsAt)
+}
+
+func TestParseQuotaPlan_Invalid(t *testing.T) {
+	_, err := config.ParseQuotaPlan("daily_swipes=10/fortnight")
+	assert.Error(t, err)
+
+	_, err = config.ParseQuotaPlan("daily_swipes=ten/day")
+	assert.Error(t, err)
+}
+
+func TestParseQuotaPlan_MissingQuota(t *testing.T) {
+	// The plans of before match extensions were counted
+	_, err := config.ParseQuotaPlan("daily_swipes=10/day,super_likes=1/day,boosts=0/month,rewinds=0/day")
+	assert.EqualError(t, err, `missing quota "match_extensions"`)
+
+	// Every quota the use cases consume has to be in the plans
+	assert.ElementsMatch(t, []string{models.QuotaDailySwipes, models.QuotaSuperLikes, models.QuotaBoosts, models.QuotaRewinds, models.QuotaMatchExtensions}, config.Quotas)
+}
+
+func TestQuotaHeaderName(t *testing.T) {
+	assert.Equal(t, "X-Quota-Daily-Swipes-Remaining", middleware.QuotaHeaderName(models.QuotaDailySwipes))
+	assert.Equal(t, "X-Quota-Boosts-Remaining", middleware.QuotaHeaderName(models.QuotaBoosts))
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMarkRead_LatestMessage(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	latest := messageAt(matchRoomID, time.Now())
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessagesBefore", matchRoomID, time.Time{}, uuid.Nil, 1).Return([]models.Message{latest}, nil)
+	mockMatchRepo.On("MarkRead", matchRoomID, userID, &latest, mock.Anything).Return(true, nil).Once()
+
+	receipt, err := matchUseCase.MarkRead(matchRoomID, userID, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Equal(t, latest.ID, receipt.MessageID)
+	assert.Equal(t, userID, receipt.UserID)
+	assert.WithinDuration(t, time.Now(), receipt.ReadAt, time.Second)
+
+	// Reading it again does not move the cursor, so there is nothing to tell
+	mockMatchRepo.On("MarkRead", matchRoomID, userID, &latest, mock.Anything).Return(false, nil)
+
+	receipt, err = matchUseCase.MarkRead(matchRoomID, userID, uuid.Nil)
+	assert.NoError(t, err)
+	assert.Nil(t, receipt)
+}
+
+func TestMarkRead_MessageOfAnotherRoom(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	matchRoomID := uuid.New()
+	userID := uuid.New()
+	elsewhere := messageAt(uuid.New(), time.Now())
+
+	mockMatchRepo.On("GetMatchRoom", matchRoomID, userID).Return(&models.MatchRoom{ID: matchRoomID}, nil)
+	mockMatchRepo.On("GetMessage", elsewhere.ID).Return(&elsewhere, nil)
+
+	_, err := matchUseCase.MarkRead(matchRoomID, userID, elsewhere.ID)
+	assert.ErrorIs(t, err, usecase.ErrMessageNotFound)
+
+	mockMatchRepo.AssertNotCalled(t, "MarkRead", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetMatchRooms_ShortensPreview(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), noPresence(), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	long := strings.Repeat("é", 150)
+	mockMatchRepo.On("GetMatchRoomSummaries", userID).Return([]models.MatchRoomSummary{
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, LastMessage: &models.MessagePreview{Content: long}, UnreadCount: 3},
+		{MatchRoom: models.MatchRoom{ID: uuid.New()}, TargetUserID: uuid.New()},
+	}, nil)
+
+	summaries, err := matchUseCase.GetMatchRooms(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("é", 100)+"…", summaries[0].LastMessage.Content)
+	assert.Equal(t, int64(3), summaries[0].UnreadCount)
+	assert.Nil(t, summaries[1].LastMessage)
+}
@@ -0,0 +1,306 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/mdzakyabd/dating-app/app/handler"
+	"github.com/mdzakyabd/dating-app/app/middleware"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/realtime"
+	"github.com/mdzakyabd/dating-app/app/routes"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "test-secret"
+
+// memoryBackplane connects hubs in the same process the way Postgres connects instances.
+type memoryBackplane struct {
+	mu        sync.Mutex
+	listeners []func(payload []byte)
+}
+
+func (b *memoryBackplane) Publish(payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, deliver := range b.listeners {
+		deliver(payload)
+	}
+	return nil
+}
+
+func (b *memoryBackplane) Listen(ctx context.Context, deliver func(payload []byte)) error {
+	b.mu.Lock()
+	b.listeners = append(b.listeners, deliver)
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func testRealtimeConfig() config.RealtimeConfig {
+	return config.RealtimeConfig{
+		Provider:         config.RealtimeWebSocket,
+		ReplayBufferSize: 10,
+		ReplayRetention:  time.Minute,
+	}
+}
+
+// serveHub serves the WebSocket endpoint of hub on a test server.
+func serveHub(t *testing.T, hub *realtime.Hub) *httptest.Server {
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("GetUserByID", mock.Anything).Return(&models.User{}, nil)
+	return serveHubFor(t, hub, mockUserRepo)
+}
+
+// serveHubFor serves the realtime endpoints of hub to the users of userRepo.
+func serveHubFor(t *testing.T, hub *realtime.Hub, userRepo *MockUserRepository) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userUseCase := usecase.NewUserUseCase(userRepo)
+	routes.Routes(router, routes.AppRouteHandlers{RealtimeHandler: handler.NewRealtimeHandler(hub, userUseCase, testJWTSecret)}, testJWTSecret, new(MockQuotaUseCase), new(MockPresenceUseCase), userUseCase)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dialHub(t *testing.T, server *httptest.Server, userID uuid.UUID) *websocket.Conn {
+	token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?token="+token, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	// Subscriptions are in place once the connection answers
+	require.NoError(t, conn.WriteJSON(map[string]string{"action": "ping"}))
+	require.Equal(t, "pong", readEvent(t, conn).Event)
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) realtime.Event {
+	var event realtime.Event
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, conn.ReadJSON(&event))
+	return event
+}
+
+func TestHub_DeliversMatchRoomEvents(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	room := *newMatchRoom(uuid.New(), userID, uuid.New())
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{room}, nil)
+
+	conn := dialHub(t, server, userID)
+
+	// Events of other rooms are not delivered
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(uuid.New()), "new_message", map[string]string{"content": "not for you"}))
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(room.ID), "new_message", map[string]string{"content": "hi"}))
+
+	event := readEvent(t, conn)
+	assert.Equal(t, realtime.MatchRoomChannel(room.ID), event.Channel)
+	assert.Equal(t, "new_message", event.Event)
+	assert.Equal(t, map[string]interface{}{"content": "hi"}, event.Data)
+}
+
+func TestHub_SubscribeChecksMembership(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	newRoom := *newMatchRoom(uuid.New(), userID, uuid.New())
+	// The room is matched after the connection was opened
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil).Once()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{newRoom}, nil)
+
+	conn := dialHub(t, server, userID)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"action": "subscribe", "match_room_id": uuid.New()}))
+	assert.Equal(t, "error", readEvent(t, conn).Event)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"action": "subscribe", "match_room_id": newRoom.ID}))
+	assert.Equal(t, "subscribed", readEvent(t, conn).Event)
+
+	assert.NoError(t, hub.Publish(realtime.MatchRoomChannel(newRoom.ID), "new_message", nil))
+	assert.Equal(t, realtime.MatchRoomChannel(newRoom.ID), readEvent(t, conn).Channel)
+}
+
+func TestHub_FansOutAcrossInstances(t *testing.T) {
+	backplane := &memoryBackplane{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockMatchRepo := new(MockMatchRepository)
+	first := realtime.NewHub(mockMatchRepo, backplane, nil, testRealtimeConfig())
+	second := realtime.NewHub(mockMatchRepo, backplane, nil, testRealtimeConfig())
+	go first.Run(ctx)
+	go second.Run(ctx)
+
+	userID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil)
+
+	// The user is connected to the second instance, the event is published on the first
+	conn := dialHub(t, serveHub(t, second), userID)
+	require.Eventually(t, func() bool {
+		backplane.mu.Lock()
+		defer backplane.mu.Unlock()
+		return len(backplane.listeners) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, first.Publish(realtime.UserChannel(userID), "super_like_received", map[string]string{"user_id": "someone"}))
+	assert.Equal(t, "super_like_received", readEvent(t, conn).Event)
+}
+
+func TestHub_RejectsInvalidToken(t *testing.T) {
+	server := serveHub(t, realtime.NewHub(new(MockMatchRepository), nil, nil, testRealtimeConfig()))
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?token=nope", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHub_RejectsBarredAccount(t *testing.T) {
+	userID := uuid.New()
+	bannedAt := time.Now().Add(-time.Hour)
+	mockUserRepo := new(MockUserRepository)
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, BannedAt: &bannedAt}, nil)
+	server := serveHubFor(t, realtime.NewHub(new(MockMatchRepository), nil, nil, testRealtimeConfig()), mockUserRepo)
+
+	token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+	require.NoError(t, err)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?token="+token, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestLogger_RedactsToken(t *testing.T) {
+	assert.Equal(t, "/ws?token=REDACTED", middleware.RedactQuery("/ws?token=eyJhbGciOi.secret"))
+	assert.Equal(t, "/ws?room=1&token=REDACTED", middleware.RedactQuery("/ws?token=eyJhbGciOi.secret&room=1"))
+	assert.Equal(t, "/profile?page=2", middleware.RedactQuery("/profile?page=2"))
+	assert.Equal(t, "/profile", middleware.RedactQuery("/profile"))
+
+	var out bytes.Buffer
+	gin.SetMode(gin.TestMode)
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &out
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	router := gin.New()
+	router.Use(middleware.Logger())
+	router.GET("/ws", func(c *gin.Context) { c.Status(http.StatusUnauthorized) })
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws?token=eyJhbGciOi.secret", nil))
+
+	assert.Contains(t, out.String(), "/ws?token=REDACTED")
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+	token, err := utils.GenerateJWT(userID.String(), testJWTSecret)
+	require.NoError(t, err)
+
+	parsed, err := utils.ParseJWT(token, testJWTSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, parsed)
+
+	_, err = utils.ParseJWT(token, "another-secret")
+	assert.ErrorIs(t, err, utils.ErrInvalidToken)
+}
+
+func TestMemoryPublisher_RecordsEvents(t *testing.T) {
+	publisher := realtime.NewMemoryPublisher()
+	matchRoomID := uuid.New()
+
+	assert.NoError(t, publisher.Publish(realtime.MatchRoomChannel(matchRoomID), "new_message", "hi"))
+
+	events := publisher.Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, realtime.Event{Channel: "chat_room_" + matchRoomID.String(), Event: "new_message", Data: "hi"}, events[0])
+}
+
+// failingPublisher is a provider that is down.
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(channel, event string, data interface{}) error {
+	return errors.New("provider unavailable")
+}
+
+func TestMultiPublisher_PublishesThroughEach(t *testing.T) {
+	hub := realtime.NewMemoryPublisher()
+	publisher := realtime.NewMultiPublisher(failingPublisher{}, hub)
+	userID := uuid.New()
+
+	// A provider that is down does not keep the event from the others
+	err := publisher.Publish(realtime.UserChannel(userID), realtime.EventNewMatch, "match")
+	assert.EqualError(t, err, "provider unavailable")
+	assert.Equal(t, []realtime.Event{{Channel: realtime.UserChannel(userID), Event: realtime.EventNewMatch, Data: "match"}}, hub.Events())
+}
+
+func TestHub_RelaysTyping(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	hub := realtime.NewHub(mockMatchRepo, nil, nil, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	targetUserID := uuid.New()
+	roomID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{*newMatchRoom(roomID, userID, targetUserID)}, nil)
+	mockMatchRepo.On("GetMatchRooms", targetUserID).Return([]models.MatchRoom{*newMatchRoom(roomID, targetUserID, userID)}, nil)
+
+	conn := dialHub(t, server, userID)
+	target := dialHub(t, server, targetUserID)
+
+	// Only in rooms the user takes part in
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"action": realtime.EventTypingStarted, "match_room_id": uuid.New()}))
+	assert.Equal(t, "error", readEvent(t, conn).Event)
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"action": realtime.EventTypingStarted, "match_room_id": roomID}))
+	event := readEvent(t, target)
+	assert.Equal(t, realtime.EventTypingStarted, event.Event)
+	assert.Equal(t, map[string]interface{}{"match_room_id": roomID.String(), "user_id": userID.String()}, event.Data)
+}
+
+func TestHub_RecordsActivity(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockPresenceUseCase := new(MockPresenceUseCase)
+	hub := realtime.NewHub(mockMatchRepo, nil, mockPresenceUseCase, testRealtimeConfig())
+	server := serveHub(t, hub)
+
+	userID := uuid.New()
+	mockMatchRepo.On("GetMatchRooms", userID).Return([]models.MatchRoom{}, nil)
+	mockPresenceUseCase.On("Touch", userID, mock.Anything).Return(nil)
+
+	dialHub(t, server, userID)
+
+	// Once when connecting, once for the ping
+	mockPresenceUseCase.AssertNumberOfCalls(t, "Touch", 2)
+}
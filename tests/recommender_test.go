@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/recommender"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockRecommender struct {
+	mock.Mock
+}
+
+// Recommend is a mocked implementation of the Recommend method in the Recommender interface
+func (m *MockRecommender) Recommend(userID uuid.UUID, excludeIDs []uuid.UUID, limit int) ([]models.Profile, error) {
+	args := m.Called(userID, excludeIDs, limit)
+	return args.Get(0).([]models.Profile), args.Error(1)
+}
+
+// Boosted is a mocked implementation of the Boosted method in the Recommender interface
+func (m *MockRecommender) Boosted(userID uuid.UUID, limit int) ([]models.Profile, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.Profile), args.Error(1)
+}
+
+// RecordSwipe is a mocked implementation of the RecordSwipe method in the Recommender interface
+func (m *MockRecommender) RecordSwipe(swipe *models.Swipe) error {
+	args := m.Called(swipe)
+	return args.Error(0)
+}
+
+func testRecommenderConfig() config.RecommenderConfig {
+	return config.RecommenderConfig{
+		Weights: config.RecommenderWeights{
+			Preference:   3,
+			Distance:     2,
+			Activity:     1.5,
+			Completeness: 1,
+			Interests:    1.5,
+			Desirability: 1,
+		},
+		CandidatePoolSize:     100,
+		DistanceScaleKm:       25,
+		ActivityHalfLifeHours: 72,
+		EloK:                  32,
+		BoostMultiplier:       3,
+	}
+}
+
+func TestRecommend_RanksByScoreAndAppliesPreferences(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockBoostRepo := new(MockBoostRepository)
+	rec := recommender.NewRecommender(mockProfileRepo, mockBoostRepo, testRecommenderConfig())
+
+	userID := uuid.New()
+	now := time.Now()
+
+	viewer := &models.Profile{
+		UserID:    userID,
+		Gender:    "male",
+		Latitude:  -6.2,
+		Longitude: 106.8,
+		Interests: []string{"hiking", "coffee"},
+	}
+	preference := &models.Preference{UserID: userID, Gender: "female", MinAge: 20, MaxAge: 35}
+
+	// Close by, active, shares interests
+	best := models.Profile{
+		UserID:       uuid.New(),
+		Name:         "Best",
+		Bio:          "Bio",
+		Gender:       "female",
+		BirthDate:    now.AddDate(-25, 0, 0),
+		Latitude:     -6.21,
+		Longitude:    106.81,
+		Interests:    []string{"hiking", "coffee"},
+		Rating:       1300,
+		LastActiveAt: now.Add(-time.Hour),
+	}
+	// Far away and inactive
+	worse := models.Profile{
+		UserID:       uuid.New(),
+		Gender:       "female",
+		BirthDate:    now.AddDate(-30, 0, 0),
+		Latitude:     3.59,
+		Longitude:    98.67,
+		Rating:       1100,
+		LastActiveAt: now.AddDate(0, 0, -30),
+	}
+	// Outside the preferred age range
+	tooOld := models.Profile{
+		UserID:    uuid.New(),
+		Gender:    "female",
+		BirthDate: now.AddDate(-50, 0, 0),
+	}
+	// Wrong gender
+	wrongGender := models.Profile{
+		UserID: uuid.New(),
+		Gender: "male",
+	}
+
+	excludeIDs := []uuid.UUID{userID}
+
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(viewer, nil)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(preference, nil)
+	mockProfileRepo.On("GetProfilesExcluding", excludeIDs, 100).Return([]models.Profile{worse, tooOld, wrongGender, best}, nil)
+	mockProfileRepo.On("GetPreferencesByUserIDs", mock.Anything).Return([]models.Preference{}, nil)
+	mockBoostRepo.On("GetBoostedUserIDs", mock.Anything).Return([]uuid.UUID{}, nil)
+
+	profiles, err := rec.Recommend(userID, excludeIDs, 10)
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, best.UserID, profiles[0].UserID)
+	assert.Equal(t, worse.UserID, profiles[1].UserID)
+
+	mockProfileRepo.AssertExpectations(t)
+}
+
+func TestRecommend_WithoutViewerProfile(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockBoostRepo := new(MockBoostRepository)
+	rec := recommender.NewRecommender(mockProfileRepo, mockBoostRepo, testRecommenderConfig())
+
+	userID := uuid.New()
+	candidates := []models.Profile{{UserID: uuid.New()}, {UserID: uuid.New()}, {UserID: uuid.New()}}
+
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{}, gorm.ErrRecordNotFound)
+	mockProfileRepo.On("GetPreferenceByUserID", userID).Return(&models.Preference{}, gorm.ErrRecordNotFound)
+	mockProfileRepo.On("GetProfilesExcluding", mock.Anything, 100).Return(candidates, nil)
+	mockProfileRepo.On("GetPreferencesByUserIDs", mock.Anything).Return([]models.Preference{}, nil)
+	mockBoostRepo.On("GetBoostedUserIDs", mock.Anything).Return([]uuid.UUID{}, nil)
+
+	profiles, err := rec.Recommend(userID, []uuid.UUID{userID}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 2)
+
+	mockProfileRepo.AssertExpectations(t)
+}
+
+func TestRecommend_NothingMissing(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	rec := recommender.NewRecommender(mockProfileRepo, new(MockBoostRepository), testRecommenderConfig())
+
+	// A deck holding more than its size asks for a negative number of profiles
+	for _, limit := range []int{0, -5} {
+		profiles, err := rec.Recommend(uuid.New(), nil, limit)
+		assert.NoError(t, err)
+		assert.Empty(t, profiles)
+	}
+	mockProfileRepo.AssertNotCalled(t, "GetProfilesExcluding", mock.Anything, mock.Anything)
+}
+
+func TestRecordSwipe_UpdatesTargetRating(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	rec := recommender.NewRecommender(mockProfileRepo, new(MockBoostRepository), testRecommenderConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeLike}
+
+	mockProfileRepo.On("GetProfileByUserID", swipe.TargetUserID).Return(&models.Profile{UserID: swipe.TargetUserID, Rating: 1200}, nil)
+	mockProfileRepo.On("GetProfileByUserID", swipe.UserID).Return(&models.Profile{UserID: swipe.UserID, Rating: 1200}, nil)
+	// Equal ratings expect a 50% outcome, so a like moves the target up by K/2
+	mockProfileRepo.On("AdjustRating", swipe.TargetUserID, 16.0).Return(nil)
+
+	err := rec.RecordSwipe(swipe)
+	assert.NoError(t, err)
+	assert.Equal(t, 16.0, swipe.RatingChange)
+
+	mockProfileRepo.AssertExpectations(t)
+}
+
+func TestUpdateRating_PassFromLowerRatedSwiper(t *testing.T) {
+	// Being passed by someone rated far below you costs more than being passed by an equal
+	equal := recommender.UpdateRating(1200, 1200, false, 32)
+	lower := recommender.UpdateRating(1200, 1000, false, 32)
+
+	assert.Equal(t, 1184.0, equal)
+	assert.Less(t, lower, equal)
+}
+
+func TestEvaluate_MeasuresTopKLikeRate(t *testing.T) {
+	now := time.Now()
+	viewerID := uuid.New()
+	near := uuid.New()
+	far := uuid.New()
+
+	profiles := []models.Profile{
+		{UserID: viewerID, Latitude: -6.2, Longitude: 106.8, Interests: []string{"music"}},
+		{UserID: near, Name: "Near", Bio: "Bio", Latitude: -6.2, Longitude: 106.8, Interests: []string{"music"}, LastActiveAt: now},
+		{UserID: far, Latitude: 3.59, Longitude: 98.67},
+	}
+	swipes := []models.Swipe{
+		{UserID: viewerID, TargetUserID: far, Type: models.SwipePass, Model: gorm.Model{CreatedAt: now}},
+		{UserID: viewerID, TargetUserID: near, Type: models.SwipeLike, Model: gorm.Model{CreatedAt: now.Add(time.Minute)}},
+	}
+
+	result := recommender.Evaluate(profiles, nil, swipes, testRecommenderConfig(), 1)
+
+	assert.Equal(t, 1, result.Sessions)
+	assert.Equal(t, 2, result.Swipes)
+	assert.Equal(t, 0.5, result.BaselineRate)
+	assert.Equal(t, 1.0, result.TopKLikeRate)
+	assert.Equal(t, 2.0, result.Lift)
+}
+
+func TestEvaluate_ActivityAsOfTheSwipe(t *testing.T) {
+	now := time.Now()
+	viewerID := uuid.New()
+	stale := uuid.New()
+	fresh := uuid.New()
+
+	profiles := []models.Profile{
+		{UserID: viewerID, Latitude: -6.2, Longitude: 106.8},
+		// Active today, long after the viewer swiped on them
+		{UserID: stale, Latitude: -6.2, Longitude: 106.8, LastActiveAt: now},
+		{UserID: fresh, Latitude: -6.2, Longitude: 106.8},
+	}
+	swipes := []models.Swipe{
+		// fresh was swiping the day before the viewer saw them
+		{UserID: fresh, TargetUserID: uuid.New(), Type: models.SwipePass, Model: gorm.Model{CreatedAt: now.AddDate(0, 0, -11)}},
+		{UserID: viewerID, TargetUserID: stale, Type: models.SwipePass, Model: gorm.Model{CreatedAt: now.AddDate(0, 0, -10)}},
+		{UserID: viewerID, TargetUserID: fresh, Type: models.SwipeLike, Model: gorm.Model{CreatedAt: now.AddDate(0, 0, -10).Add(time.Minute)}},
+	}
+
+	result := recommender.Evaluate(profiles, nil, swipes, testRecommenderConfig(), 1)
+
+	assert.Equal(t, 1, result.Sessions)
+	assert.Equal(t, 1.0, result.TopKLikeRate)
+}
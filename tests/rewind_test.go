@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func testSwipeConfig() config.SwipeConfig {
+	return config.SwipeConfig{
+		RewindWindow:      10 * time.Minute,
+		RewindMatchPolicy: config.RewindMatchRefuse,
+		LikesPageSize:     2,
+		HistoryPageSize:   2,
+	}
+}
+
+func recentSwipe(userID uuid.UUID, swipeType models.SwipeType, age time.Duration) *models.Swipe {
+	return &models.Swipe{
+		ID:           uuid.New(),
+		UserID:       userID,
+		TargetUserID: uuid.New(),
+		Type:         swipeType,
+		Model:        gorm.Model{CreatedAt: time.Now().Add(-age)},
+	}
+}
+
+func TestRewind(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipePass, time.Minute)
+	swipe.RatingChange = -14
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaRewinds).Return(&models.QuotaStatus{}, nil)
+	// The swipe goes together with its change of the target's rating
+	mockSwipeRepo.On("RewindSwipe", swipe, (*uuid.UUID)(nil)).Return(nil)
+
+	// The profile comes back at the front of the deck
+	mockDeckRepo.On("PushFront", userID, swipe.TargetUserID).Return(nil)
+
+	rewound, err := swipeUseCase.Rewind(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, swipe.TargetUserID, rewound.TargetUserID)
+
+	mockSwipeRepo.AssertExpectations(t)
+	mockDeckRepo.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestRewind_DeckFailureKeepsRewind(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	mockMatchRepo := new(MockMatchRepository)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipeSuperLike, time.Minute)
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockMatchRepo.On("GetMatchBetween", userID, swipe.TargetUserID).Return(&models.MatchRoom{}, gorm.ErrRecordNotFound)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaRewinds).Return(&models.QuotaStatus{}, nil)
+	mockSwipeRepo.On("RewindSwipe", swipe, (*uuid.UUID)(nil)).Return(nil)
+	mockDeckRepo.On("ConsumeEntry", swipe.TargetUserID, userID).Return(errors.New("connection reset"))
+	mockDeckRepo.On("PushFront", userID, swipe.TargetUserID).Return(errors.New("connection reset"))
+
+	// The swipe is gone, the rewind that paid for it is not given back as failed
+	_, err := swipeUseCase.Rewind(userID)
+	assert.NoError(t, err)
+	mockQuotaUseCase.AssertNotCalled(t, "Release", userID, models.QuotaRewinds)
+}
+
+func TestRewind_RewoundMeanwhile(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipePass, time.Minute)
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaRewinds).Return(&models.QuotaStatus{}, nil)
+	mockSwipeRepo.On("RewindSwipe", swipe, (*uuid.UUID)(nil)).Return(gorm.ErrRecordNotFound)
+	mockQuotaUseCase.On("Release", userID, models.QuotaRewinds).Return(nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.ErrorIs(t, err, usecase.ErrNothingToRewind)
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestRewind_OutsideWindow(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(recentSwipe(userID, models.SwipePass, time.Hour), nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.ErrorIs(t, err, usecase.ErrNothingToRewind)
+
+	// No rewind is charged for a swipe that cannot be undone
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestRewind_MatchRefused(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipeLike, time.Minute)
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockMatchRepo.On("GetMatchBetween", userID, swipe.TargetUserID).Return(&models.MatchRoom{ID: uuid.New()}, nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.ErrorIs(t, err, usecase.ErrRewindMatched)
+
+	mockSwipeRepo.AssertNotCalled(t, "RewindSwipe", mock.Anything, mock.Anything)
+}
+
+func TestRewind_MatchUnwound(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	cfg := testSwipeConfig()
+	cfg.RewindMatchPolicy = config.RewindMatchUnwind
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), cfg)
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipeLike, time.Minute)
+	// The like answered one of the target, and made the match
+	match := newMatchRoom(uuid.New(), userID, swipe.TargetUserID)
+	match.MatchedByID = &userID
+	match.CreatedAt = swipe.CreatedAt
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockMatchRepo.On("GetMatchBetween", userID, swipe.TargetUserID).Return(match, nil)
+	mockMatchRepo.On("CountMessages", match.ID).Return(int64(0), nil)
+	mockQuotaUseCase.On("Consume", userID, models.QuotaRewinds).Return(&models.QuotaStatus{}, nil)
+	mockSwipeRepo.On("RewindSwipe", swipe, &match.ID).Return(nil)
+	mockDeckRepo.On("PushFront", userID, swipe.TargetUserID).Return(nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.NoError(t, err)
+
+	mockMatchRepo.AssertExpectations(t)
+	mockSwipeRepo.AssertExpectations(t)
+}
+
+func TestRewind_MatchWithMessagesIsKept(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	cfg := testSwipeConfig()
+	cfg.RewindMatchPolicy = config.RewindMatchUnwind
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), new(MockQuotaUseCase), newTestDetector(), new(MockModerationUseCase), cfg)
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipeSuperLike, time.Minute)
+	match := newMatchRoom(uuid.New(), userID, swipe.TargetUserID)
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockMatchRepo.On("GetMatchBetween", userID, swipe.TargetUserID).Return(match, nil)
+	mockMatchRepo.On("CountMessages", match.ID).Return(int64(3), nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.ErrorIs(t, err, usecase.ErrRewindMatched)
+
+	mockSwipeRepo.AssertNotCalled(t, "RewindSwipe", mock.Anything, mock.Anything)
+}
+
+func TestRewind_MatchOfALaterLikeIsKept(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	cfg := testSwipeConfig()
+	cfg.RewindMatchPolicy = config.RewindMatchUnwind
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), cfg)
+
+	userID := uuid.New()
+	swipe := recentSwipe(userID, models.SwipeLike, 2*time.Minute)
+	// The target liked back after the swipe, their like made the match
+	match := newMatchRoom(uuid.New(), userID, swipe.TargetUserID)
+	match.MatchedByID = &swipe.TargetUserID
+	match.CreatedAt = swipe.CreatedAt.Add(time.Minute)
+
+	mockSwipeRepo.On("GetLastSwipe", userID).Return(swipe, nil)
+	mockMatchRepo.On("GetMatchBetween", userID, swipe.TargetUserID).Return(match, nil)
+
+	_, err := swipeUseCase.Rewind(userID)
+	assert.ErrorIs(t, err, usecase.ErrRewindMatched)
+
+	mockSwipeRepo.AssertNotCalled(t, "RewindSwipe", mock.Anything, mock.Anything)
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mocking dependencies
+type MockBlockRepository struct {
+	mock.Mock
+}
+
+// CreateBlock is a mocked implementation of the CreateBlock method in the BlockRepository interface
+func (m *MockBlockRepository) CreateBlock(block *models.Block) error {
+	args := m.Called(block)
+	return args.Error(0)
+}
+
+// GetBlockedUsersID is a mocked implementation of the GetBlockedUsersID method in the BlockRepository interface
+func (m *MockBlockRepository) GetBlockedUsersID(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// noBlocks is a block repository for users who blocked nobody.
+func noBlocks() *MockBlockRepository {
+	mockBlockRepo := new(MockBlockRepository)
+	mockBlockRepo.On("GetBlockedUsersID", mock.Anything).Return([]uuid.UUID{}, nil)
+	return mockBlockRepo
+}
+
+type safetyMocks struct {
+	blockRepo      *MockBlockRepository
+	moderationRepo *MockModerationRepository
+	matchRepo      *MockMatchRepository
+	deckRepo       *MockDeckRepository
+	profileRepo    *MockProfileRepository
+	userRepo       *MockUserRepository
+}
+
+func newSafetyUseCase() (usecase.SafetyUseCase, safetyMocks) {
+	mocks := safetyMocks{
+		blockRepo:      new(MockBlockRepository),
+		moderationRepo: new(MockModerationRepository),
+		matchRepo:      new(MockMatchRepository),
+		deckRepo:       new(MockDeckRepository),
+		profileRepo:    new(MockProfileRepository),
+		userRepo:       new(MockUserRepository),
+	}
+	moderationUseCase := usecase.NewModerationUseCase(mocks.moderationRepo, mocks.userRepo, mocks.profileRepo, mocks.matchRepo, testModerationConfig())
+	safetyUseCase := usecase.NewSafetyUseCase(mocks.blockRepo, mocks.matchRepo, mocks.deckRepo, mocks.userRepo, moderationUseCase)
+	return safetyUseCase, mocks
+}
+
+func TestBlock_ClosesSharedMatchRoom(t *testing.T) {
+	safetyUseCase, mocks := newSafetyUseCase()
+
+	userID := uuid.New()
+	blockedUserID := uuid.New()
+	room := newMatchRoom(uuid.New(), userID, blockedUserID)
+	mocks.userRepo.On("GetUserByID", blockedUserID).Return(&models.User{ID: blockedUserID}, nil)
+	mocks.blockRepo.On("CreateBlock", &models.Block{UserID: userID, BlockedUserID: blockedUserID}).Return(nil)
+	mocks.deckRepo.On("ConsumeEntry", userID, blockedUserID).Return(nil)
+	mocks.deckRepo.On("ConsumeEntry", blockedUserID, userID).Return(nil)
+	mocks.matchRepo.On("GetMatchBetween", userID, blockedUserID).Return(room, nil)
+	mocks.matchRepo.On("Unmatch", mock.Anything).Return(nil)
+
+	unmatch, err := safetyUseCase.Block(userID, blockedUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, room.ID, unmatch.MatchRoomID)
+	assert.Equal(t, models.UnmatchBlocked, unmatch.Reason)
+	mocks.deckRepo.AssertExpectations(t)
+}
+
+func TestBlock_WithoutMatch(t *testing.T) {
+	safetyUseCase, mocks := newSafetyUseCase()
+
+	userID := uuid.New()
+	blockedUserID := uuid.New()
+	mocks.userRepo.On("GetUserByID", blockedUserID).Return(&models.User{ID: blockedUserID}, nil)
+	mocks.blockRepo.On("CreateBlock", mock.Anything).Return(nil)
+	mocks.deckRepo.On("ConsumeEntry", mock.Anything, mock.Anything).Return(nil)
+	mocks.matchRepo.On("GetMatchBetween", userID, blockedUserID).Return((*models.MatchRoom)(nil), gorm.ErrRecordNotFound)
+
+	unmatch, err := safetyUseCase.Block(userID, blockedUserID)
+	assert.NoError(t, err)
+	assert.Nil(t, unmatch)
+	mocks.matchRepo.AssertNotCalled(t, "Unmatch", mock.Anything)
+
+	_, err = safetyUseCase.Block(userID, userID)
+	assert.ErrorIs(t, err, usecase.ErrSelfBlock)
+}
+
+func TestReport_CopiesReportedMessages(t *testing.T) {
+	safetyUseCase, mocks := newSafetyUseCase()
+
+	reporterID := uuid.New()
+	reportedUserID := uuid.New()
+	room := newMatchRoom(uuid.New(), reporterID, reportedUserID)
+	message := &models.Message{ID: uuid.New(), MatchRoomID: room.ID, SenderID: reportedUserID, Content: "send me money"}
+	ownMessage := &models.Message{ID: uuid.New(), MatchRoomID: room.ID, SenderID: reporterID, Content: "no"}
+	mocks.userRepo.On("GetUserByID", reportedUserID).Return(&models.User{ID: reportedUserID}, nil)
+	mocks.matchRepo.On("GetMessage", message.ID).Return(message, nil)
+	mocks.matchRepo.On("GetMessage", ownMessage.ID).Return(ownMessage, nil)
+	mocks.matchRepo.On("GetParticipants", room.ID).Return(room.Participants, nil)
+	mocks.moderationRepo.On("CreateReport", mock.Anything, mock.Anything).Return(&models.ModerationCase{ID: uuid.New(), ReporterCount: 1}, nil)
+
+	report, err := safetyUseCase.Report(reporterID, reportedUserID, usecase.ReportRequest{Category: models.ReportScam, MessageIDs: []uuid.UUID{message.ID}})
+	assert.NoError(t, err)
+	assert.Len(t, report.Messages, 1)
+	assert.Equal(t, "send me money", report.Messages[0].Content)
+
+	// Only messages of the reported user
+	_, err = safetyUseCase.Report(reporterID, reportedUserID, usecase.ReportRequest{Category: models.ReportScam, MessageIDs: []uuid.UUID{ownMessage.ID}})
+	assert.ErrorIs(t, err, usecase.ErrReportMessages)
+
+	_, err = safetyUseCase.Report(reporterID, reportedUserID, usecase.ReportRequest{Category: "rude"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidReport)
+
+	// Below the threshold the profile stays visible
+	mocks.profileRepo.AssertNotCalled(t, "SetHidden", mock.Anything, mock.Anything)
+}
+
+func TestReport_HidesProfileOnRepeatedReports(t *testing.T) {
+	safetyUseCase, mocks := newSafetyUseCase()
+
+	reportedUserID := uuid.New()
+	caseID := uuid.New()
+	mocks.userRepo.On("GetUserByID", reportedUserID).Return(&models.User{ID: reportedUserID}, nil)
+	mocks.moderationRepo.On("CreateReport", mock.Anything, mock.Anything).Return(&models.ModerationCase{ID: caseID, ReporterCount: 3}, nil)
+	mocks.profileRepo.On("SetHidden", reportedUserID, mock.AnythingOfType("*time.Time")).Return(nil)
+	mocks.moderationRepo.On("MarkProfileHidden", caseID, mock.Anything).Return(nil)
+
+	_, err := safetyUseCase.Report(uuid.New(), reportedUserID, usecase.ReportRequest{Category: models.ReportFakeProfile, Details: "stock photos"})
+	assert.NoError(t, err)
+	mocks.profileRepo.AssertExpectations(t)
+	mocks.moderationRepo.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/screening"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testScreener() screening.Screener {
+	return screening.NewScreener(config.ScreeningConfig{NudgeScore: 3, HoldScore: 8})
+}
+
+func TestScreener_Verdicts(t *testing.T) {
+	screener := testScreener()
+
+	tests := []struct {
+		content string
+		verdict models.ScreeningVerdict
+		flags   []string
+	}{
+		{"Hey, how was your weekend?", models.ScreeningDeliver, nil},
+		// Words that merely contain a listed one are left alone
+		{"I'm in Scunthorpe this week, shitake mushrooms for dinner", models.ScreeningDeliver, nil},
+		{"that movie was shit", models.ScreeningNudge, []string{"profanity"}},
+		{"text me on +1 (555) 123-4567", models.ScreeningNudge, []string{"phone_number"}},
+		{"you're a wh0re", models.ScreeningHold, []string{"harassment"}},
+		{"I made a fortune with bitcoin, join my trading platform at quickgains.io", models.ScreeningHold, []string{"scam_keyword", "url"}},
+	}
+	for _, test := range tests {
+		result := screener.Screen(test.content)
+		assert.Equal(t, test.verdict, result.Verdict, test.content)
+		assert.Equal(t, test.flags, result.Flags, test.content)
+	}
+}
+
+func TestCreateMessage_HeldForReview(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), mockModerationUseCase, new(MockQuotaUseCase), testChatConfig())
+
+	message := &models.Message{MatchRoomID: uuid.New(), SenderID: uuid.New(), Content: "send money by western union and I'll visit"}
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, message.SenderID).Return(newMatchRoom(message.MatchRoomID, message.SenderID, uuid.New()), nil)
+	mockMatchRepo.On("CreateMessage", message).Return(nil)
+	mockModerationUseCase.On("OpenCase", message.SenderID, models.CaseSourceTextFilter, mock.AnythingOfType("string")).Return(&models.ModerationCase{}, nil)
+
+	err := matchUseCase.CreateMessage(message)
+	assert.NoError(t, err)
+	assert.NotNil(t, message.HeldAt)
+	assert.Equal(t, models.ScreeningHold, message.Screening.Verdict)
+	assert.Equal(t, []string{"scam_keyword"}, message.Screening.Flags)
+	mockModerationUseCase.AssertExpectations(t)
+}
+
+func TestCreateMessage_NudgeIsDelivered(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), mockModerationUseCase, new(MockQuotaUseCase), testChatConfig())
+
+	message := &models.Message{MatchRoomID: uuid.New(), SenderID: uuid.New(), Content: "what the fuck"}
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, message.SenderID).Return(newMatchRoom(message.MatchRoomID, message.SenderID, uuid.New()), nil)
+	mockMatchRepo.On("CreateMessage", message).Return(nil)
+
+	err := matchUseCase.CreateMessage(message)
+	assert.NoError(t, err)
+	assert.Nil(t, message.HeldAt)
+	assert.Equal(t, models.ScreeningNudge, message.Screening.Verdict)
+	mockModerationUseCase.AssertNotCalled(t, "OpenCase", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_HeldEditIsNotMade(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), mockModerationUseCase, new(MockQuotaUseCase), testChatConfig())
+
+	userID := uuid.New()
+	message := messageAt(uuid.New(), time.Now())
+	message.SenderID = userID
+	message.Content = "hi"
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, userID).Return(newMatchRoom(message.MatchRoomID, userID, uuid.New()), nil)
+	mockMatchRepo.On("GetMessage", message.ID).Return(&message, nil)
+	mockModerationUseCase.On("OpenCase", userID, models.CaseSourceTextFilter, mock.AnythingOfType("string")).Return(&models.ModerationCase{}, nil)
+
+	_, err := matchUseCase.EditMessage(message.MatchRoomID, message.ID, userID, "kill yourself")
+	assert.ErrorIs(t, err, usecase.ErrEditHeld)
+	mockMatchRepo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything)
+	assert.Equal(t, "hi", message.Content)
+}
+
+func TestMarkUnwanted(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), mockModerationUseCase, new(MockQuotaUseCase), testChatConfig())
+
+	senderID := uuid.New()
+	recipientID := uuid.New()
+	message := messageAt(uuid.New(), time.Now())
+	message.SenderID = senderID
+	message.Content = "you up?"
+	room := newMatchRoom(message.MatchRoomID, recipientID, senderID)
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, mock.Anything).Return(room, nil)
+	mockMatchRepo.On("GetMessage", message.ID).Return(&message, nil)
+	mockMatchRepo.On("MarkUnwanted", message.ID, mock.Anything).Return(true, nil).Once()
+	mockMatchRepo.On("MarkUnwanted", message.ID, mock.Anything).Return(false, nil)
+	mockModerationUseCase.On("OpenCase", senderID, models.CaseSourceUnwanted, mock.AnythingOfType("string")).Return(&models.ModerationCase{}, nil)
+
+	require.NoError(t, matchUseCase.MarkUnwanted(message.MatchRoomID, message.ID, recipientID))
+	// Marking it again does not open another case
+	require.NoError(t, matchUseCase.MarkUnwanted(message.MatchRoomID, message.ID, recipientID))
+	mockModerationUseCase.AssertNumberOfCalls(t, "OpenCase", 1)
+
+	err := matchUseCase.MarkUnwanted(message.MatchRoomID, message.ID, senderID)
+	assert.ErrorIs(t, err, usecase.ErrOwnMessage)
+}
+
+func TestMarkUnwanted_FlagsPhoto(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockModerationUseCase := new(MockModerationUseCase)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), mockModerationUseCase, new(MockQuotaUseCase), testChatConfig())
+
+	senderID := uuid.New()
+	recipientID := uuid.New()
+	message := messageAt(uuid.New(), time.Now())
+	message.SenderID = senderID
+	message.Attachment = &models.Attachment{ID: uuid.New(), Kind: models.AttachmentImage}
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, recipientID).Return(newMatchRoom(message.MatchRoomID, recipientID, senderID), nil)
+	mockMatchRepo.On("GetMessage", message.ID).Return(&message, nil)
+	mockMatchRepo.On("MarkUnwanted", message.ID, mock.Anything).Return(true, nil)
+	mockModerationUseCase.On("OpenCase", senderID, models.CaseSourcePhoto, mock.AnythingOfType("string")).Return(&models.ModerationCase{}, nil)
+
+	require.NoError(t, matchUseCase.MarkUnwanted(message.MatchRoomID, message.ID, recipientID))
+	mockModerationUseCase.AssertExpectations(t)
+}
+
+func TestHeldMessage_HiddenFromRecipient(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	matchUseCase := usecase.NewMatchUsecase(mockMatchRepo, new(MockUserRepository), new(MockPresenceUseCase), testScreener(), new(MockModerationUseCase), new(MockQuotaUseCase), testChatConfig())
+
+	senderID := uuid.New()
+	recipientID := uuid.New()
+	heldAt := time.Now()
+	message := messageAt(uuid.New(), time.Now())
+	message.SenderID = senderID
+	message.HeldAt = &heldAt
+	mockMatchRepo.On("GetMatchRoom", message.MatchRoomID, mock.Anything).Return(newMatchRoom(message.MatchRoomID, recipientID, senderID), nil)
+	mockMatchRepo.On("GetMessage", message.ID).Return(&message, nil)
+
+	_, err := matchUseCase.React(message.MatchRoomID, message.ID, recipientID, "❤️")
+	assert.ErrorIs(t, err, usecase.ErrMessageNotFound)
+	_, err = matchUseCase.MarkRead(message.MatchRoomID, recipientID, message.ID)
+	assert.ErrorIs(t, err, usecase.ErrMessageNotFound)
+}
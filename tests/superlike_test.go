@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSwipe_SuperLikePushesToRecipientDeck(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeSuperLike}
+
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaSuperLikes).Return(&models.QuotaStatus{}, nil)
+	mockSwipeRepo.On("CreateSwipe", swipe).Return(&models.SwipeResult{}, nil)
+	mockDeckRepo.On("ConsumeEntry", swipe.UserID, swipe.TargetUserID).Return(nil)
+	mockRecommender.On("RecordSwipe", swipe).Return(nil)
+
+	// The sender jumps the queue of the recipient
+	mockDeckRepo.On("PushFront", swipe.TargetUserID, swipe.UserID).Return(nil)
+
+	result, err := swipeUseCase.Swipe(swipe)
+	assert.NoError(t, err)
+	assert.Nil(t, result.Match)
+
+	mockQuotaUseCase.AssertExpectations(t)
+	mockDeckRepo.AssertExpectations(t)
+}
+
+func TestSwipe_SuperLikeQuotaExceeded(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeSuperLike}
+
+	// The daily swipe taken before the super like quota ran out is given back
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaSuperLikes).Return(&models.QuotaStatus{}, fmt.Errorf("%w: %s", usecase.ErrQuotaExceeded, models.QuotaSuperLikes))
+	mockQuotaUseCase.On("Release", swipe.UserID, models.QuotaDailySwipes).Return(nil)
+
+	_, err := swipeUseCase.Swipe(swipe)
+	assert.ErrorIs(t, err, usecase.ErrQuotaExceeded)
+
+	mockQuotaUseCase.AssertExpectations(t)
+	mockSwipeRepo.AssertNotCalled(t, "CreateSwipe", swipe)
+}
+
+func TestSwipe_LikeAnsweringSuperLikeMatches(t *testing.T) {
+	mockMatchRepo := new(MockMatchRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeLike}
+
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+	// The target super liked the user first
+	match := newMatchRoom(uuid.New(), swipe.UserID, swipe.TargetUserID)
+	mockSwipeRepo.On("CreateSwipe", swipe).Return(&models.SwipeResult{Match: match, SuperLikeMatch: true}, nil)
+	mockDeckRepo.On("ConsumeEntry", swipe.UserID, swipe.TargetUserID).Return(nil)
+	mockRecommender.On("RecordSwipe", swipe).Return(nil)
+
+	result, err := swipeUseCase.Swipe(swipe)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.Match)
+	assert.True(t, result.SuperLikeMatch)
+
+	mockDeckRepo.AssertNotCalled(t, "PushFront", mock.Anything, mock.Anything)
+}
+
+func TestSwipe_InvalidType(t *testing.T) {
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(new(MockSwipeRepository), new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	_, err := swipeUseCase.Swipe(&models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: "maybe"})
+	assert.ErrorIs(t, err, usecase.ErrInvalidSwipeType)
+
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestViewProfiles_SuperLikeBadge(t *testing.T) {
+	mockProfileRepo := new(MockProfileRepository)
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	mockRecommender := new(MockRecommender)
+	profileUseCase := usecase.NewProfileUseCase(mockProfileRepo, new(MockUserRepository), mockSwipeRepo, new(MockMatchRepository), noBlocks(), mockDeckRepo, mockRecommender, mockQuotaUseCase, new(MockPresenceUseCase), testDeckConfig())
+
+	userID := uuid.New()
+	deck := &models.Deck{UserID: userID, GeneratedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	entries, profiles := deckEntries(userID, 0, 1)
+
+	mockQuotaUseCase.On("GetQuota", userID, models.QuotaDailySwipes).Return(freeSwipesLeft(10), nil)
+	mockProfileRepo.On("TouchLastActive", userID, mock.Anything).Return(nil)
+	mockProfileRepo.On("GetProfileByUserID", userID).Return(&models.Profile{UserID: userID}, nil)
+	mockDeckRepo.On("GetDeck", userID).Return(deck, nil)
+	mockProfileRepo.On("RecordImpressions", userID, mock.Anything, mock.Anything).Return(nil)
+	mockDeckRepo.On("GetEntries", userID, math.MinInt32, 10).Return(entries, nil)
+	mockDeckRepo.On("CountEntries", userID).Return(int64(50), nil)
+	mockProfileRepo.On("GetProfilesByUserIDs", mock.Anything).Return(profiles, nil)
+
+	// The profile pushed to the front super liked the viewer
+	mockSwipeRepo.On("GetSuperLikerIDs", userID).Return([]uuid.UUID{profiles[0].UserID}, nil)
+
+	page, err := profileUseCase.ViewProfiles(userID, "")
+	assert.NoError(t, err)
+	assert.True(t, page.Profiles[0].SuperLikedYou)
+	assert.False(t, page.Profiles[1].SuperLikedYou)
+}
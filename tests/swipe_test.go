@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/repository"
 	"github.com/mdzakyabd/dating-app/app/usecase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,109 +19,284 @@ type MockSwipeRepository struct {
 }
 
 // CreateSwipe is a mocked implementation of the CreateSwipe method in the SwipeRepository interface
-func (m *MockSwipeRepository) CreateSwipe(swipe *models.Swipe) error {
+func (m *MockSwipeRepository) CreateSwipe(swipe *models.Swipe) (*models.SwipeResult, error) {
 	args := m.Called(swipe)
-	return args.Error(0)
+	return args.Get(0).(*models.SwipeResult), args.Error(1)
 }
 
 // GetSwipedUsersID is a mocked implementation of the GetSwipedUsersID method in the SwipeRepository interface
-func (m *MockSwipeRepository) GetSwipedUsersID(userID uuid.UUID, date time.Time) ([]uuid.UUID, error) {
-	args := m.Called(userID, date)
+func (m *MockSwipeRepository) GetSwipedUsersID(userID uuid.UUID, passedSince time.Time) ([]uuid.UUID, error) {
+	args := m.Called(userID, passedSince)
 	return args.Get(0).([]uuid.UUID), args.Error(1)
 }
 
+// GetSwipeHistory is a mocked implementation of the GetSwipeHistory method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetSwipeHistory(userID uuid.UUID, types []models.SwipeType, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error) {
+	args := m.Called(userID, types, before, beforeID, limit)
+	return args.Get(0).([]models.Swipe), args.Error(1)
+}
+
 // GetSwipe is a mocked implementation of the GetSwipe method in the SwipeRepository interface
 func (m *MockSwipeRepository) GetSwipe(userID, targetUserID uuid.UUID) (*models.Swipe, error) {
 	args := m.Called(userID, targetUserID)
 	return args.Get(0).(*models.Swipe), args.Error(1)
 }
 
+// GetSwipeByIdempotencyKey is a mocked implementation of the GetSwipeByIdempotencyKey method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetSwipeByIdempotencyKey(userID uuid.UUID, key string) (*models.Swipe, error) {
+	args := m.Called(userID, key)
+	return args.Get(0).(*models.Swipe), args.Error(1)
+}
+
+// GetSuperLikerIDs is a mocked implementation of the GetSuperLikerIDs method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetSuperLikerIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// GetPendingLikes is a mocked implementation of the GetPendingLikes method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetPendingLikes(userID uuid.UUID, filter repository.LikesFilter, before time.Time, beforeID uuid.UUID, limit int) ([]models.Swipe, error) {
+	args := m.Called(userID, filter, before, beforeID, limit)
+	return args.Get(0).([]models.Swipe), args.Error(1)
+}
+
+// CountPendingLikes is a mocked implementation of the CountPendingLikes method in the SwipeRepository interface
+func (m *MockSwipeRepository) CountPendingLikes(userID uuid.UUID, filter repository.LikesFilter) (int64, error) {
+	args := m.Called(userID, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// CountLikesReceived is a mocked implementation of the CountLikesReceived method in the SwipeRepository interface
+func (m *MockSwipeRepository) CountLikesReceived(userID uuid.UUID, start, end time.Time) (int64, error) {
+	args := m.Called(userID, start, end)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// GetLastSwipe is a mocked implementation of the GetLastSwipe method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetLastSwipe(userID uuid.UUID) (*models.Swipe, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*models.Swipe), args.Error(1)
+}
+
+// RewindSwipe is a mocked implementation of the RewindSwipe method in the SwipeRepository interface
+func (m *MockSwipeRepository) RewindSwipe(swipe *models.Swipe, matchID *uuid.UUID) error {
+	args := m.Called(swipe, matchID)
+	return args.Error(0)
+}
+
+// SetRatingChange is a mocked implementation of the SetRatingChange method in the SwipeRepository interface
+func (m *MockSwipeRepository) SetRatingChange(id uuid.UUID, change float64) error {
+	args := m.Called(id, change)
+	return args.Error(0)
+}
+
+// GetSwipesSince is a mocked implementation of the GetSwipesSince method in the SwipeRepository interface
+func (m *MockSwipeRepository) GetSwipesSince(since time.Time) ([]models.Swipe, error) {
+	args := m.Called(since)
+	return args.Get(0).([]models.Swipe), args.Error(1)
+}
+
 func TestSwipe(t *testing.T) {
 	// Create mock repositories
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 
 	// Create SwipeUseCase with mock repositories
-	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
 
 	// Create a mock swipe
 	swipe := &models.Swipe{
 		ID:           uuid.New(),
 		UserID:       uuid.New(),
 		TargetUserID: uuid.New(),
-		Liked:        true, // Simulating a swipe right (like)
+		Type:         models.SwipeLike, // Simulating a swipe right (like)
 	}
 
-	// Set up expectation for CreateSwipe method in mock swipe repository
-	mockSwipeRepo.On("CreateSwipe", swipe).Return(nil)
+	// Set up expectation for the daily swipe quota
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
 
-	// Set up expectation for GetSwipe method in mock swipe repository
-	mockSwipeRepo.On("GetSwipe", swipe.TargetUserID, swipe.UserID).Return(&models.Swipe{}, nil)
+	// Set up expectation for CreateSwipe method in mock swipe repository, which matched the pair
+	match := newMatchRoom(uuid.New(), swipe.UserID, swipe.TargetUserID)
+	mockSwipeRepo.On("CreateSwipe", swipe).Return(&models.SwipeResult{Match: match}, nil)
+
+	// Set up expectations for removing the profile from the deck and the rating update
+	mockDeckRepo.On("ConsumeEntry", swipe.UserID, swipe.TargetUserID).Return(nil)
+	mockRecommender.On("RecordSwipe", swipe).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Swipe).RatingChange = 16
+	}).Return(nil)
+	// The rating change is kept for a rewind to take back
+	mockSwipeRepo.On("SetRatingChange", swipe.ID, 16.0).Return(nil)
 
 	// Call the Swipe method and assert the result
-	err := swipeUseCase.Swipe(swipe)
+	result, err := swipeUseCase.Swipe(swipe)
 	assert.NoError(t, err)
+	assert.Equal(t, match, result.Match)
 
 	// Assert that all expectations were met
 	mockSwipeRepo.AssertExpectations(t)
+	mockRecommender.AssertExpectations(t)
 }
 
 func TestSwipe_ErrorCreatingSwipe(t *testing.T) {
 	// Create mock repositories
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 
 	// Create SwipeUseCase with mock repositories
-	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
 
 	// Create a mock swipe
 	swipe := &models.Swipe{
 		ID:           uuid.New(),
 		UserID:       uuid.New(),
 		TargetUserID: uuid.New(),
-		Liked:        true, // Simulating a swipe right (like)
+		Type:         models.SwipeLike, // Simulating a swipe right (like)
 	}
 
+	// Set up expectation for the daily swipe quota, which is given back when the swipe fails
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+	mockQuotaUseCase.On("Release", swipe.UserID, models.QuotaDailySwipes).Return(nil)
+
 	// Set up expectation for CreateSwipe method in mock swipe repository to return an error
-	mockSwipeRepo.On("CreateSwipe", swipe).Return(errors.New("error creating swipe"))
+	mockSwipeRepo.On("CreateSwipe", swipe).Return((*models.SwipeResult)(nil), errors.New("error creating swipe"))
 
 	// Call the Swipe method and assert the error
-	err := swipeUseCase.Swipe(swipe)
+	_, err := swipeUseCase.Swipe(swipe)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "error creating swipe")
 
 	// Assert that all expectations were met
 	mockSwipeRepo.AssertExpectations(t)
 	mockMatchRepo.AssertExpectations(t)
+	mockQuotaUseCase.AssertExpectations(t)
 }
 
 func TestSwipe_NoMutualLike(t *testing.T) {
 	// Create mock repositories
 	mockMatchRepo := new(MockMatchRepository)
 	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
 
 	// Create SwipeUseCase with mock repositories
-	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
 
 	// Create a mock swipe
 	swipe := &models.Swipe{
 		ID:           uuid.New(),
 		UserID:       uuid.New(),
 		TargetUserID: uuid.New(),
-		Liked:        true, // Simulating a swipe right (like)
+		Type:         models.SwipeLike, // Simulating a swipe right (like)
 	}
 
-	// Set up expectation for CreateSwipe method in mock swipe repository
-	mockSwipeRepo.On("CreateSwipe", swipe).Return(nil)
+	// Set up expectation for the daily swipe quota
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+
+	// Set up expectation for CreateSwipe method in mock swipe repository (no mutual like)
+	mockSwipeRepo.On("CreateSwipe", swipe).Return(&models.SwipeResult{}, nil)
 
-	// Set up expectation for GetSwipe method in mock swipe repository to return an error (no mutual like)
-	mockSwipeRepo.On("GetSwipe", swipe.TargetUserID, swipe.UserID).Return(&models.Swipe{}, errors.New("no mutual like"))
+	// Set up expectations for removing the profile from the deck and the rating update
+	mockDeckRepo.On("ConsumeEntry", swipe.UserID, swipe.TargetUserID).Return(nil)
+	mockRecommender.On("RecordSwipe", swipe).Return(nil)
 
 	// Call the Swipe method and assert no error
-	err := swipeUseCase.Swipe(swipe)
+	result, err := swipeUseCase.Swipe(swipe)
 	assert.NoError(t, err)
+	assert.Nil(t, result.Match)
 
 	// Assert that all expectations were met
 	mockSwipeRepo.AssertExpectations(t)
 	mockMatchRepo.AssertExpectations(t)
+	mockQuotaUseCase.AssertExpectations(t)
+}
+
+func TestSwipe_IdempotentRetry(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockMatchRepo := new(MockMatchRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, mockMatchRepo, mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeLike, IdempotencyKey: "retry-1"}
+	stored := *swipe
+	stored.ID = uuid.New()
+	match := newMatchRoom(uuid.New(), swipe.UserID, swipe.TargetUserID)
+
+	mockSwipeRepo.On("GetSwipeByIdempotencyKey", swipe.UserID, "retry-1").Return(&stored, nil)
+	mockMatchRepo.On("GetMatchBetween", swipe.UserID, swipe.TargetUserID).Return(match, nil)
+
+	result, err := swipeUseCase.Swipe(swipe)
+	assert.NoError(t, err)
+	assert.True(t, result.Replayed)
+	assert.Equal(t, match, result.Match)
+	assert.Equal(t, stored.ID, swipe.ID)
+
+	// The first request already paid for the swipe and acted on it
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	mockSwipeRepo.AssertNotCalled(t, "CreateSwipe", mock.Anything)
+	mockDeckRepo.AssertNotCalled(t, "ConsumeEntry", mock.Anything, mock.Anything)
+	mockRecommender.AssertNotCalled(t, "RecordSwipe", mock.Anything)
+}
+
+func TestSwipe_IdempotentRetryWithoutQuotaLeft(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipePass, IdempotencyKey: "retry-1"}
+	stored := *swipe
+	stored.ID = uuid.New()
+
+	// The retried swipe was the last one the quota allowed
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return((*models.QuotaStatus)(nil), usecase.ErrQuotaExceeded)
+	mockSwipeRepo.On("GetSwipeByIdempotencyKey", swipe.UserID, "retry-1").Return(&stored, nil)
+
+	result, err := swipeUseCase.Swipe(swipe)
+	assert.NoError(t, err)
+	assert.True(t, result.Replayed)
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+}
+
+func TestSwipe_IdempotencyKeyReused(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), new(MockDeckRepository), new(MockProfileRepository), new(MockUserRepository), new(MockRecommender), mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeLike, IdempotencyKey: "retry-1"}
+	// The key was first sent with a pass on someone else
+	stored := models.Swipe{ID: uuid.New(), UserID: swipe.UserID, TargetUserID: uuid.New(), Type: models.SwipePass, IdempotencyKey: "retry-1"}
+	mockSwipeRepo.On("GetSwipeByIdempotencyKey", swipe.UserID, "retry-1").Return(&stored, nil)
+
+	_, err := swipeUseCase.Swipe(swipe)
+	assert.ErrorIs(t, err, usecase.ErrIdempotencyKeyReused)
+
+	mockQuotaUseCase.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	mockSwipeRepo.AssertNotCalled(t, "CreateSwipe", mock.Anything)
+}
+
+func TestSwipe_BookkeepingFailureKeepsSwipe(t *testing.T) {
+	mockSwipeRepo := new(MockSwipeRepository)
+	mockDeckRepo := new(MockDeckRepository)
+	mockRecommender := new(MockRecommender)
+	mockQuotaUseCase := new(MockQuotaUseCase)
+	swipeUseCase := usecase.NewSwipeUseCase(mockSwipeRepo, new(MockMatchRepository), mockDeckRepo, new(MockProfileRepository), new(MockUserRepository), mockRecommender, mockQuotaUseCase, newTestDetector(), new(MockModerationUseCase), testSwipeConfig())
+
+	swipe := &models.Swipe{ID: uuid.New(), UserID: uuid.New(), TargetUserID: uuid.New(), Type: models.SwipeLike}
+	mockQuotaUseCase.On("Consume", swipe.UserID, models.QuotaDailySwipes).Return(&models.QuotaStatus{}, nil)
+	mockSwipeRepo.On("CreateSwipe", swipe).Return(&models.SwipeResult{}, nil)
+	mockDeckRepo.On("ConsumeEntry", swipe.UserID, swipe.TargetUserID).Return(errors.New("deck unavailable"))
+	mockRecommender.On("RecordSwipe", swipe).Return(errors.New("ratings unavailable"))
+
+	result, err := swipeUseCase.Swipe(swipe)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	mockQuotaUseCase.AssertNotCalled(t, "Release", mock.Anything, mock.Anything)
 }
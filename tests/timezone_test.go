@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mdzakyabd/dating-app/app/models"
+	"github.com/mdzakyabd/dating-app/app/usecase"
+	"github.com/mdzakyabd/dating-app/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDayWindow_DSTTransitions(t *testing.T) {
+	newYork := utils.LoadLocation("America/New_York")
+
+	// Clocks spring forward, so the day only has 23 hours
+	start, end := utils.DayWindow(time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), newYork)
+	assert.Equal(t, time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+	// Clocks fall back, so the day has 25 hours
+	start, end = utils.DayWindow(time.Date(2024, 11, 3, 12, 0, 0, 0, newYork), newYork)
+	assert.Equal(t, time.Date(2024, 11, 3, 4, 0, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+
+	// An instant late in the UTC day already belongs to the next day further east
+	tokyo := utils.LoadLocation("Asia/Tokyo")
+	start, _ = utils.DayWindow(time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC), tokyo)
+	assert.Equal(t, time.Date(2024, 6, 2, 0, 0, 0, 0, tokyo), start)
+}
+
+func TestLoadLocation_FallsBackToUTC(t *testing.T) {
+	assert.Equal(t, time.UTC, utils.LoadLocation(""))
+	assert.Equal(t, time.UTC, utils.LoadLocation("Mars/Olympus_Mons"))
+}
+
+func TestGetQuotas_ResetsAtUserMidnight(t *testing.T) {
+	mockQuotaRepo := new(MockQuotaRepository)
+	mockUserRepo := new(MockUserRepository)
+	quotaUseCase := usecase.NewQuotaUseCase(mockQuotaRepo, mockUserRepo, testQuotaConfig())
+
+	userID := uuid.New()
+	loc := utils.LoadLocation("Asia/Jakarta")
+	_, tomorrow := utils.DayWindow(time.Now(), loc)
+
+	mockUserRepo.On("GetUserByID", userID).Return(&models.User{ID: userID, Timezone: "Asia/Jakarta"}, nil)
+	mockQuotaRepo.On("GetCounters", userID, mock.Anything).Return([]models.QuotaCounter{}, nil)
+
+	status, err := quotaUseCase.GetQuota(userID, models.QuotaDailySwipes)
+	assert.NoError(t, err)
+	assert.True(t, status.ResetsAt.Equal(tomorrow))
+	assert.Equal(t, 0, status.ResetsAt.In(loc).Hour())
+}
+
+func TestUpdateUser_InvalidTimezone(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	userUseCase := usecase.NewUserUseCase(mockUserRepo)
+
+	user := &models.User{ID: uuid.New(), Username: "john", Email: "john@example.com", Timezone: "Nowhere/Special"}
+
+	err := userUseCase.UpdateUser(user)
+	assert.True(t, errors.Is(err, usecase.ErrInvalidTimezone))
+
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+}
@@ -53,6 +53,18 @@ func (m *MockUserRepository) FindAllPremiumUsers() ([]models.User, error) {
 	return args.Get(0).([]models.User), args.Error(1)
 }
 
+// SetSuspendedUntil is a mocked implementation of the SetSuspendedUntil method in the UserRepository interface
+func (m *MockUserRepository) SetSuspendedUntil(userID uuid.UUID, until *time.Time) error {
+	args := m.Called(userID, until)
+	return args.Error(0)
+}
+
+// GetEndedSuspensions is a mocked implementation of the GetEndedSuspensions method in the UserRepository interface
+func (m *MockUserRepository) GetEndedSuspensions(now time.Time) ([]models.User, error) {
+	args := m.Called(now)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
 type MockUtils struct {
 	mock.Mock
 }